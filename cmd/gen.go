@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/komkom/jsonc/jsonc/gen"
+)
+
+const genUsage = `gen go [--package main] [--type Config] [file]
+gen example [-o file] dir type`
+
+// genCommand runs the gen subcommands.
+func genCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	if len(args) > 0 {
+		switch args[0] {
+		case `go`:
+			return genGo(args[1:], stdin, stdout, stderr)
+		case `example`:
+			return genExample(args[1:], stdout, stderr)
+		}
+	}
+
+	printUsage(stderr, genUsage)
+	return 2
+}
+
+// genGo writes the Go type definitions of the sample document given in args,
+// or read from the standard input, to stdout.
+func genGo(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := flag.NewFlagSet(`gen go`, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pkg := flags.String(`package`, `main`, `package name of the generated file`)
+	typeName := flags.String(`type`, `Config`, `name of the type of the document`)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() > 1 {
+		printUsage(stderr, genUsage)
+		return 2
+	}
+
+	name := `-`
+	if flags.NArg() == 1 {
+		name = flags.Arg(0)
+	}
+
+	name, data, err := readInput(name, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v: %v\n", name, err)
+		return 1
+	}
+
+	doc, err := jsonc.Parse(data)
+	if err == nil {
+		data, err = gen.GoTypes(doc, gen.Package(*pkg), gen.TypeName(*typeName))
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "%v: %v\n", name, err)
+		return 1
+	}
+
+	_, err = stdout.Write(data)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// genExample writes a commented example jsonc document of the struct type
+// declared in the Go package in a directory, as given in args, to stdout or
+// the file set with -o.
+func genExample(args []string, stdout, stderr io.Writer) int {
+
+	flags := flag.NewFlagSet(`gen example`, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String(`o`, ``, `file to write the example to`)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 2 {
+		printUsage(stderr, genUsage)
+		return 2
+	}
+
+	data, err := gen.Example(flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "%v: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	if *output != `` {
+		err = ioutil.WriteFile(*output, data, 0644)
+	} else {
+		_, err = stdout.Write(data)
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -1,86 +1,254 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
-	"unicode/utf8"
+	"sort"
+	"strings"
 
 	"github.com/komkom/jsonc/jsonc"
 )
 
-var ErrInvalidRune = fmt.Errorf(`invalid-rune`)
+// command is a subcommand of the cli, it returns the exit code.
+type command struct {
+	run   func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+	usage string
+}
 
-func NewRuneReader(reader io.Reader) *RuneReader {
-	return &RuneReader{reader: reader}
+var commands = map[string]command{
+	`validate`: {run: validate, usage: `validate [--schema schema.jsonc] [--dialect d] [--hash] [file ...]`},
+	`schema`:   {run: schemaCommand, usage: schemaUsage},
+	`gen`:      {run: genCommand, usage: genUsage},
+	`merge`:    {run: merge, usage: mergeUsage},
 }
 
-type RuneReader struct {
-	reader io.Reader
-	isEOF  bool
-	buf    [4]byte
-	length int
+// dialects are the values of the -dialect flag.
+var dialects = map[string]jsonc.Dialect{
+	jsonc.JSONC.String(): jsonc.JSONC,
+	jsonc.JSON.String():  jsonc.JSON,
+	jsonc.JSON5.String(): jsonc.JSON5,
 }
 
-func (r *RuneReader) decodeRune() (rune, int, error) {
+// inputOptions returns the options reading the input as given by the -dialect
+// and -hash flags.
+func inputOptions(dialect string, hash bool) ([]jsonc.Option, error) {
+
+	d, ok := dialects[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unknown dialect %q", dialect)
+	}
+
+	opts := []jsonc.Option{jsonc.WithDialect(d)}
+	if hash {
+		opts = append(opts, jsonc.HashComments())
+	}
+	return opts, nil
+}
+
+func main() {
+
+	if len(os.Args) > 1 {
+		if c, ok := commands[os.Args[1]]; ok {
+			os.Exit(c.run(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		}
+	}
+
+	flag.Usage = usage
+
+	var minimize, check, stream, interpolate, references, hash bool
+	flag.BoolVar(&minimize, "m", false, `transform to minified json`)
+	flag.BoolVar(&interpolate, "i", false, `expand ${env:NAME}, ${file:path} and ${NAME:-default} placeholders, with -m or -s`)
+	flag.BoolVar(&references, "r", false, `resolve ${.path} references to values of the document, with -m or -s`)
+	flag.BoolVar(&check, "c", false, `check the input and report all syntax errors`)
+	flag.BoolVar(&stream, "s", false, `read a stream of values and print one json line per value`)
+	flag.BoolVar(&hash, "hash", false, `accept # line comments`)
+	var dialect string
+	flag.StringVar(&dialect, "dialect", `jsonc`, `the syntax of the input: jsonc, json or json5`)
+	flag.Parse()
+
+	opts, err := inputOptions(dialect, hash)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if check {
+		if !report(os.Stderr, os.Stdin, opts...) {
+			os.Exit(1)
+		}
+		return
+	}
 
-	ru, size := utf8.DecodeRune(r.buf[:r.length])
+	if interpolate {
+		opts = append(opts, jsonc.Interpolate(nil))
+	}
+	if references {
+		opts = append(opts, jsonc.References())
+	}
 
-	if ru == utf8.RuneError {
-		return '0', 0, ErrInvalidRune
+	if stream {
+		err := lines(os.Stdout, os.Stdin, opts...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
 	}
 
-	copy(r.buf[:], r.buf[size:r.length])
-	r.length -= size
+	// references need the whole document
+	if references && minimize {
+		if !resolve(os.Stdout, os.Stderr, os.Stdin, opts...) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !transform(os.Stdout, os.Stderr, os.Stdin, minimize, opts...) {
+		os.Exit(1)
+	}
+}
+
+func usage() {
+
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: jsonc [flags] < file\n")
+	flag.PrintDefaults()
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	return ru, size, nil
+	fmt.Fprintf(out, "\ncommands:\n")
+	for _, name := range names {
+		for _, line := range strings.Split(commands[name].usage, "\n") {
+			fmt.Fprintf(out, "  jsonc %v\n", line)
+		}
+	}
 }
 
-func (r *RuneReader) ReadRune() (rune, int, error) {
+// printUsage writes the usage lines of a command.
+func printUsage(w io.Writer, usage string) {
 
-	if r.isEOF {
-		if r.length != 0 {
-			return r.decodeRune()
+	for i, line := range strings.Split(usage, "\n") {
+		if i == 0 {
+			fmt.Fprintf(w, "usage: jsonc %v\n", line)
+			continue
 		}
+		fmt.Fprintf(w, "       jsonc %v\n", line)
+	}
+}
+
+// readInput reads the file name, or the standard input for "-" which it
+// names stdin.
+func readInput(name string, stdin io.Reader) (string, []byte, error) {
 
-		return '0', 0, io.EOF
+	if name == `-` {
+		data, err := ioutil.ReadAll(stdin)
+		return `stdin`, data, err
 	}
 
-	n, err := r.reader.Read(r.buf[r.length:])
-	if err != nil && !errors.Is(err, io.EOF) {
-		return '0', 0, fmt.Errorf(`runeReader.ReadRune reader.Read failed %w`, err)
+	data, err := ioutil.ReadFile(name)
+	return name, data, err
+}
+
+// report writes all syntax errors found in r to w and returns true if there
+// were none.
+func report(w io.Writer, r io.Reader, opts ...jsonc.Option) bool {
+
+	errs, err := jsonc.Validate(r, opts...)
+	if err != nil {
+		fmt.Fprintf(w, "reading input failed, error: %v\n", err)
+		return false
 	}
 
-	if errors.Is(err, io.EOF) {
-		r.isEOF = true
+	for _, e := range errs {
+		fmt.Fprintln(w, e.Error())
 	}
+	return len(errs) == 0
+}
 
-	r.length += n
+// lines writes each top level value of the jsonc stream r as one line of
+// json to w.
+func lines(w io.Writer, r io.Reader, opts ...jsonc.Option) error {
 
-	if r.length == 0 && errors.Is(err, io.EOF) {
-		return '0', 0, err
+	dec, err := jsonc.NewDecoder(r, opts...)
+	if err != nil {
+		return err
 	}
 
-	return r.decodeRune()
+	for {
+		var value json.RawMessage
+		err = dec.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", value)
+		if err != nil {
+			return err
+		}
+	}
 }
 
-func main() {
+// transform streams the jsonc document r to w, as minified json if minimize
+// is set and formatted otherwise. The first error is reported to errw with
+// its location, transform returns false then.
+func transform(w, errw io.Writer, r io.Reader, minimize bool, opts ...jsonc.Option) bool {
 
-	var minimize bool
-	flag.BoolVar(&minimize, "m", false, `transform to minified json`)
-	flag.Parse()
+	f, err := jsonc.New(r, minimize, " ", opts...)
+	if err != nil {
+		fmt.Fprintf(errw, "no input stream, error: %v\n", err)
+		return false
+	}
+
+	io.Copy(w, f)
+
+	err = f.Err()
+	if err != nil && err != io.EOF {
+		fmt.Fprintln(errw, err.Error())
+		return false
+	}
+	return true
+}
 
-	f, err := jsonc.New(NewRuneReader(os.Stdin), minimize, " ")
+// resolve writes the jsonc document r as minified json with its references
+// resolved to w. Errors are reported to errw, resolve returns false then.
+func resolve(w, errw io.Writer, r io.Reader, opts ...jsonc.Option) bool {
+
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
-		fmt.Printf("no input stream, error: %v", err)
-		os.Exit(1)
+		fmt.Fprintf(errw, "reading input failed, error: %v\n", err)
+		return false
 	}
 
-	io.Copy(os.Stdout, f)
+	out, err := jsonc.ToJSON(data, opts...)
 
-	if !f.Done() || (f.Err() != nil && f.Err() != io.EOF) {
-		os.Exit(1)
+	var ierr *jsonc.InterpolationError
+	if errors.As(err, &ierr) {
+		fmt.Fprintln(errw, ierr.Error())
+		return false
+	}
+
+	if err != nil {
+		if report(errw, bytes.NewReader(data), opts...) {
+			fmt.Fprintln(errw, err.Error())
+		}
+		return false
 	}
+
+	w.Write(out)
+	return true
 }
@@ -2,131 +2,328 @@ package main
 
 import (
 	"bytes"
-	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
-	"github.com/pkg/errors"
+	"github.com/komkom/jsonc/jsonc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-type errReader struct {
-	err        error
-	middleRune byte
+func TestReport(t *testing.T) {
+
+	out := &bytes.Buffer{}
+	ok := report(out, strings.NewReader("[1,,2,\n x: y]"))
+	assert.False(t, ok)
+	assert.Equal(t, "line: 1 col: 4 empty no quote state\nline: 2 col: 3 invalid identifier\n", out.String())
+
+	out.Reset()
+	ok = report(out, strings.NewReader(`{x: y}`))
+	assert.True(t, ok)
+	assert.Equal(t, ``, out.String())
+
+	out.Reset()
+	ok = report(out, strings.NewReader(`{'x': 0x10, $y: .5}`), jsonc.WithDialect(jsonc.JSON5))
+	assert.True(t, ok)
+	assert.Equal(t, ``, out.String())
+
+	ok = report(out, strings.NewReader(`{'x': 0x10}`))
+	assert.False(t, ok)
+
+	out.Reset()
+	ok = report(out, strings.NewReader("# shell style\n{x: y}"), jsonc.HashComments())
+	assert.True(t, ok)
+	assert.Equal(t, ``, out.String())
 }
 
-func (r errReader) Read(p []byte) (n int, err error) {
-	if len(p) <= 3 {
-		panic(`errReader buffer invalid`)
-	}
+func TestLines(t *testing.T) {
+
+	out := &bytes.Buffer{}
+	err := lines(out, strings.NewReader("{a: 1} // first\n\x1e{a: 2}\n[x y]"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n{\"a\":2}\n[\"x\",\"y\"]\n", out.String())
 
-	p[0] = byte('a')
-	p[1] = r.middleRune
-	p[2] = byte('c')
+	out.Reset()
+	err = lines(out, strings.NewReader("{a: 1}\n{a: -}"))
+	require.Error(t, err)
+	assert.Equal(t, "{\"a\":1}\n", out.String())
 
-	return 3, r.err
+	out.Reset()
+	resolvers := map[string]jsonc.Resolver{`env`: jsonc.MapResolver{`PORT`: `80`}}
+	err = lines(out, strings.NewReader("{port: ${PORT}}\n{port: ${HTTPS_PORT:-443}}"), jsonc.Interpolate(resolvers))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"port\":80}\n{\"port\":443}\n", out.String())
+
+	out.Reset()
+	err = lines(out, strings.NewReader("{'a': 0xff}\n[+1, Infinity]"), jsonc.WithDialect(jsonc.JSON5))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":255}\n[1,null]\n", out.String())
 }
 
-func TestRuneReader_withErrReader(t *testing.T) {
+func TestResolve(t *testing.T) {
 
-	r := errReader{err: io.EOF, middleRune: byte('b')}
-	rur := &RuneReader{reader: r}
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	ok := resolve(out, errOut, strings.NewReader("{\n  host: db // the host\n  url: \"pg://${.host}\"\n}"), jsonc.References())
+	assert.True(t, ok)
+	assert.Equal(t, `{"host":"db","url":"pg://db"}`, out.String())
+	assert.Equal(t, ``, errOut.String())
 
-	// test first EOF path
-	var runes []rune
-	for {
-		ru, _, err := rur.ReadRune()
-		if errors.Is(err, io.EOF) {
-			break
-		}
+	out.Reset()
+	ok = resolve(out, errOut, strings.NewReader("{\n  url: ${.host}\n}"), jsonc.References())
+	assert.False(t, ok)
+	assert.Equal(t, ``, out.String())
+	assert.Equal(t, "line: 2 col: 8 ${.host}: member \"host\" not found\n", errOut.String())
 
-		require.NoError(t, err)
-		runes = append(runes, ru)
-	}
-	assert.Equal(t, `abc`, string(runes))
+	errOut.Reset()
+	ok = resolve(out, errOut, strings.NewReader(`{url: }`), jsonc.References())
+	assert.False(t, ok)
+	assert.Equal(t, "line: 1 col: 7 empty no quote state\n", errOut.String())
+}
 
-	// test first invalid EOF path
-	r = errReader{err: io.EOF, middleRune: byte('\255')}
-	rur = &RuneReader{reader: r}
+func TestTransform(t *testing.T) {
 
-	runes = nil
-	for {
-		ru, _, err := rur.ReadRune()
-		if err != nil {
-			return
-		}
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	ok := transform(out, errOut, strings.NewReader(`{a: [1, 2]}`), true)
+	assert.True(t, ok)
+	assert.Equal(t, `{"a":[1,2]}`, out.String())
+	assert.Equal(t, ``, errOut.String())
 
-		require.NoError(t, err)
-		runes = append(runes, ru)
-	}
+	out.Reset()
+	ok = transform(out, errOut, strings.NewReader("{\n  a: [1,\n  b: ]\n}"), true)
+	assert.False(t, ok)
+	assert.Equal(t, "line: 3 col: 4 invalid identifier\n", errOut.String())
+
+	errOut.Reset()
+	ok = transform(out, errOut, strings.NewReader(`{a: [1`), false)
+	assert.False(t, ok)
+	assert.Equal(t, "line: 1 col: 7 unexpected end of input\n", errOut.String())
 }
 
-func TestRuneReader_withBufferString(t *testing.T) {
-
-	tests := []struct {
-		data []byte
-		err  string
-	}{
-		{data: []byte(``)},
-		{data: []byte(`test this`)},
-		{
-			data: []byte{byte('\255'), byte('a')},
-			err:  `invalid-rune`,
-		},
-		{
-			data: []byte{byte('a'), byte('\255'), byte('b')},
-			err:  `invalid-rune`,
-		},
-		{
-			data: append([]byte{byte('a'), byte('\255')}, messageWith(128)...),
-			err:  `invalid-rune`,
-		},
-		{
-			data: messageWith(131),
-		},
+func TestValidate(t *testing.T) {
+
+	dir, err := ioutil.TempDir(``, `jsonc`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		return path
 	}
 
-	for _, ts := range tests {
+	schemaFile := write(`schema.jsonc`, `{
+  // the service
+  type: object
+  properties: {port: {type: integer, maximum: 65535}}
+}`)
+	good := write(`good.jsonc`, `{port: 80}`)
+	bad := write(`bad.jsonc`, "{\n  port: 70000\n}")
+	broken := write(`broken.jsonc`, `{port: }`)
 
-		buf := bytes.NewBuffer(ts.data)
-		rur := &RuneReader{reader: buf}
+	out := &bytes.Buffer{}
+	code := validate([]string{`--schema`, schemaFile, good}, nil, out, out)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, ``, out.String())
 
-		var runes []rune
-		var err error
-		var ru rune
-		for {
-			ru, _, err = rur.ReadRune()
-			if errors.Is(err, io.EOF) {
-				err = nil
-				break
-			}
+	out.Reset()
+	code = validate([]string{`--schema`, schemaFile, good, bad, broken}, nil, out, out)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, bad+": line: 2 col: 9 /port: 70000 is greater than the maximum 65535\n"+
+		broken+": line: 1 col: 8 empty no quote state\n", out.String())
 
-			if err != nil {
-				break
-			}
+	out.Reset()
+	code = validate([]string{`--schema`, schemaFile}, strings.NewReader(`{port: x}`), out, out)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "stdin: line: 1 col: 8 /port: expected integer, got string\n", out.String())
 
-			require.NoError(t, err)
+	out.Reset()
+	code = validate([]string{good}, nil, out, out)
+	assert.Equal(t, 0, code)
 
-			runes = append(runes, ru)
-		}
+	out.Reset()
+	code = validate([]string{`--schema`, good + `.missing`, good}, nil, out, out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), `no such file or directory`)
 
-		if ts.err != `` {
-			require.Error(t, err)
-			assert.True(t, strings.Contains(err.Error(), ts.err), err.Error())
-			continue
-		}
-		require.NoError(t, err)
+	// the files are read in the dialect given
+	json5 := write(`port.json5`, "{'port': 0x11170}")
+	out.Reset()
+	code = validate([]string{`--schema`, schemaFile, `--dialect=json5`, json5}, nil, out, out)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, json5+": line: 1 col: 10 /port: 0x11170 is greater than the maximum 65535\n", out.String())
 
-		resp := string(runes)
-		assert.Equal(t, string(ts.data), resp)
-	}
+	out.Reset()
+	code = validate([]string{`--hash`}, strings.NewReader("# the port\n{port: 80}"), out, out)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, ``, out.String())
+
+	out.Reset()
+	code = validate([]string{`--dialect=yaml`}, nil, out, out)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "unknown dialect \"yaml\"\n", out.String())
 }
 
-func messageWith(numberOfBytes int) []byte {
-	var data []byte
-	for i := 0; i < numberOfBytes; i++ {
-		data = append(data, byte('a'))
-	}
-	return data
+func TestSchemaInfer(t *testing.T) {
+
+	dir, err := ioutil.TempDir(``, `jsonc`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, `a.jsonc`)
+	require.NoError(t, ioutil.WriteFile(a, []byte("{\n  // the port\n  port: 80\n  host: a\n}"), 0600))
+	b := filepath.Join(dir, `b.jsonc`)
+	require.NoError(t, ioutil.WriteFile(b, []byte(`{port: 81}`), 0600))
+
+	out := &bytes.Buffer{}
+	code := schemaCommand([]string{`infer`, a, b}, nil, out, out)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema"
+  type: object
+  properties: {
+    host: {
+      type: string
+    }
+    port: {
+      description: "the port"
+      type: integer
+    }
+  }
+  required: [port]
+}
+`, out.String())
+
+	out.Reset()
+	code = schemaCommand([]string{`infer`}, strings.NewReader(`[1, x]`), out, out)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out.String(), `anyOf`)
+
+	out.Reset()
+	code = schemaCommand([]string{`infer`, a}, nil, out, out)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out.String(), `required: [host,port]`)
+
+	out.Reset()
+	code = schemaCommand([]string{`infer`, a, b + `.missing`}, strings.NewReader(``), out, out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), `no such file or directory`)
+
+	out.Reset()
+	code = schemaCommand([]string{`infer`, `-`}, strings.NewReader(`{port: }`), out, out)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "stdin: line: 1 col: 8 empty no quote state\n", out.String())
+
+	out.Reset()
+	code = schemaCommand(nil, nil, out, out)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "usage: jsonc schema infer [file ...]\n", out.String())
+}
+
+func TestGenGo(t *testing.T) {
+
+	out := &bytes.Buffer{}
+	code := genCommand([]string{`go`, `--package`, `config`}, strings.NewReader("{\n  // the port\n  port: 80\n}"), out, out)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "package config\n\ntype Config struct {\n\t// the port\n\tPort int `json:\"port\"`\n}\n", out.String())
+
+	out.Reset()
+	code = genCommand([]string{`go`, `--type`, `Hosts`, `-`}, strings.NewReader(`[a, b]`), out, out)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "package main\n\ntype Hosts []string\n", out.String())
+
+	out.Reset()
+	code = genCommand([]string{`go`}, strings.NewReader(`{port: }`), out, out)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "stdin: line: 1 col: 8 empty no quote state\n", out.String())
+
+	out.Reset()
+	code = genCommand([]string{`go`, `a.jsonc`, `b.jsonc`}, nil, out, out)
+	assert.Equal(t, 2, code)
+
+	out.Reset()
+	code = genCommand([]string{`java`}, nil, out, out)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "usage: jsonc gen go [--package main] [--type Config] [file]\n"+
+		"       jsonc gen example [-o file] dir type\n", out.String())
+}
+
+func TestGenExample(t *testing.T) {
+
+	dir, err := ioutil.TempDir(``, `jsonc`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, `config.go`), []byte(`package config
+
+// Config is the config.
+type Config struct {
+	// Port is the listen port.
+	Port int `+"`json:\"port\" default:\"8080\"`"+`
+	Host *string `+"`json:\"host\"`"+`
+}
+`), 0600))
+
+	out := &bytes.Buffer{}
+	code := genCommand([]string{`example`, dir, `Config`}, nil, out, out)
+	assert.Equal(t, 0, code)
+	example := "// Config is the config.\n{\n  // Port is the listen port.\n  port: 8080\n  // host: \"\"\n}\n"
+	assert.Equal(t, example, out.String())
+
+	out.Reset()
+	file := filepath.Join(dir, `config.example.jsonc`)
+	code = genCommand([]string{`example`, `-o`, file, dir, `Config`}, nil, out, out)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, ``, out.String())
+
+	data, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, example, string(data))
+
+	out.Reset()
+	code = genCommand([]string{`example`, dir, `Server`}, nil, out, out)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, dir+": type Server not found in "+dir+"\n", out.String())
+
+	out.Reset()
+	code = genCommand([]string{`example`, dir}, nil, out, out)
+	assert.Equal(t, 2, code)
+}
+
+func TestMerge(t *testing.T) {
+
+	dir, err := ioutil.TempDir(``, `jsonc`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, `base.jsonc`)
+	prod := filepath.Join(dir, `prod.jsonc`)
+	broken := filepath.Join(dir, `broken.jsonc`)
+	require.NoError(t, ioutil.WriteFile(base, []byte("{\n  // the hosts\n  hosts: [a]\n  debug: true\n}\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(prod, []byte("{\n  hosts: [b] // production\n  debug: null\n}\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(broken, []byte(`{hosts: `), 0600))
+
+	out := &bytes.Buffer{}
+	code := merge([]string{base, prod}, nil, out, out)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "{\n  // the hosts\n  hosts: [b] // production\n}\n", out.String())
+
+	out.Reset()
+	code = merge([]string{`--arrays`, `append`, base, `-`}, strings.NewReader(`{hosts: [c]}`), out, out)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "{\n  // the hosts\n  hosts: [a, c]\n  debug: true\n}\n", out.String())
+
+	out.Reset()
+	code = merge([]string{base, broken}, nil, out, out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), broken+": ")
+
+	out.Reset()
+	code = merge([]string{`--arrays`, `zip`, base, prod}, nil, out, out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), `usage: jsonc merge`)
 }
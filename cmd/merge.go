@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+const mergeUsage = `merge [--arrays replace|append|merge] [--key name] base overlay ...`
+
+var arrayStrategies = map[string]jsonc.ArrayStrategy{
+	jsonc.ReplaceArrays.String():    jsonc.ReplaceArrays,
+	jsonc.AppendArrays.String():     jsonc.AppendArrays,
+	jsonc.MergeArraysByKey.String(): jsonc.MergeArraysByKey,
+}
+
+// merge writes the first file given in args with the others merged into it
+// to stdout.
+func merge(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := flag.NewFlagSet(`merge`, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	arrays := flags.String(`arrays`, `replace`, `how arrays are combined: replace, append or merge objects by key`)
+	key := flags.String(`key`, `name`, `the member identifying the objects of arrays merged by key`)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	strategy, ok := arrayStrategies[*arrays]
+	if !ok || flags.NArg() == 0 {
+		printUsage(stderr, mergeUsage)
+		return 2
+	}
+
+	code := 0
+	var docs [][]byte
+	for _, name := range flags.Args() {
+
+		name, data, err := readInput(name, stdin)
+		if err == nil {
+			_, err = jsonc.Parse(data)
+		}
+
+		if err != nil {
+			fmt.Fprintf(stderr, "%v: %v\n", name, err)
+			code = 1
+			continue
+		}
+		docs = append(docs, data)
+	}
+
+	if code != 0 {
+		return code
+	}
+
+	out, err := jsonc.NewMerger(jsonc.MergeArrays(strategy), jsonc.MergeKey(*key)).Merge(docs[0], docs[1:]...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	stdout.Write(out)
+	return 0
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/komkom/jsonc/jsonc/schema"
+)
+
+const schemaUsage = `schema infer [file ...]`
+
+// schemaCommand runs the schema subcommands.
+func schemaCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	if len(args) == 0 || args[0] != `infer` {
+		printUsage(stderr, schemaUsage)
+		return 2
+	}
+	return infer(args[1:], stdin, stdout, stderr)
+}
+
+// infer writes a draft JSON Schema of the files given in args, or the
+// standard input, to stdout.
+func infer(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := flag.NewFlagSet(`schema infer`, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{`-`}
+	}
+
+	code := 0
+	var docs []*jsonc.Node
+	for _, name := range files {
+
+		name, data, err := readInput(name, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v: %v\n", name, err)
+			code = 1
+			continue
+		}
+
+		doc, err := jsonc.Parse(data)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v: %v\n", name, err)
+			code = 1
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	if code != 0 {
+		return code
+	}
+
+	out, err := jsonc.Marshal(schema.Infer(docs...))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%s\n", out)
+	return 0
+}
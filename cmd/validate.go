@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/komkom/jsonc/jsonc/schema"
+)
+
+// validate checks the files given in args, or the standard input, for syntax
+// errors and against the JSON Schema given by --schema. The files are read as
+// given by --dialect and --hash. All errors are written to stderr prefixed by
+// the file name.
+func validate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := flag.NewFlagSet(`validate`, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	schemaFile := flags.String(`schema`, ``, `JSON Schema the files must satisfy, written as jsonc`)
+	dialect := flags.String(`dialect`, `jsonc`, `the syntax of the files: jsonc, json or json5`)
+	hash := flags.Bool(`hash`, false, `accept # line comments`)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	opts, err := inputOptions(*dialect, *hash)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+
+	var s *schema.Schema
+	if *schemaFile != `` {
+
+		data, err := ioutil.ReadFile(*schemaFile)
+		if err == nil {
+			s, err = schema.Compile(data)
+		}
+
+		if err != nil {
+			fmt.Fprintf(stderr, "%v: %v\n", *schemaFile, err)
+			return 1
+		}
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{`-`}
+	}
+
+	code := 0
+	for _, name := range files {
+
+		name, data, err := readInput(name, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v: %v\n", name, err)
+			code = 1
+			continue
+		}
+
+		if !check(stderr, name, data, s, opts) {
+			code = 1
+		}
+	}
+	return code
+}
+
+// check writes the syntax errors and schema violations of the document data
+// to w and returns true if there were none. opts select the syntax of data.
+func check(w io.Writer, name string, data []byte, s *schema.Schema, opts []jsonc.Option) bool {
+
+	errs, err := jsonc.Validate(bytes.NewReader(data), opts...)
+	if err != nil {
+		fmt.Fprintf(w, "%v: %v\n", name, err)
+		return false
+	}
+
+	for _, e := range errs {
+		fmt.Fprintf(w, "%v: %v\n", name, e.Error())
+	}
+
+	if len(errs) > 0 || s == nil {
+		return len(errs) == 0
+	}
+
+	violations, err := s.ValidateBytes(data, opts...)
+	if err != nil {
+		fmt.Fprintf(w, "%v: %v\n", name, err)
+		return false
+	}
+
+	for _, v := range violations {
+		fmt.Fprintf(w, "%v: %v\n", name, v.Error())
+	}
+	return len(violations) == 0
+}
@@ -0,0 +1,307 @@
+// Package config loads configurations layered from several jsonc files.
+//
+// The files are read in the order of the options which add them, later files
+// override earlier ones. Objects are merged member by member, all other
+// values are replaced. Missing files are skipped, so a typical search path
+// lists the system wide, the user's and the working directory's file:
+//
+//	origins, err := config.Load(&c,
+//		config.FS(defaults, "defaults.jsonc"), // an embed.FS
+//		config.Dir(config.XDGDirs("app")...),
+//		config.Dir("."),
+//	)
+package config
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/pkg/errors"
+)
+
+// Option configures Load.
+type Option func(*loader)
+
+type loader struct {
+	fileName string
+	layers   []layer
+	opts     []jsonc.Option
+}
+
+// layer is a file to load.
+type layer struct {
+	fsys fs.FS
+	path string // the path in fsys, empty for the file name of a directory
+	name string // the name in errors and origins
+}
+
+// FS adds the file at path in fsys, a slash separated path as used by
+// io/fs, as next layer.
+func FS(fsys fs.FS, path string) Option {
+	return func(l *loader) {
+		l.layers = append(l.layers, layer{fsys: fsys, path: path, name: path})
+	}
+}
+
+// Dir adds the config files in the directories dirs as next layers, in the
+// given order. The file name is set by FileName.
+func Dir(dirs ...string) Option {
+	return func(l *loader) {
+		for _, dir := range dirs {
+			l.layers = append(l.layers, layer{fsys: os.DirFS(dir), name: dir})
+		}
+	}
+}
+
+// FileName sets the name of the config files searched in the directories
+// added by Dir, the default is config.jsonc.
+func FileName(name string) Option {
+	return func(l *loader) {
+		l.fileName = name
+	}
+}
+
+// WithOptions sets the options of parsing the files, as jsonc.WithDialect.
+func WithOptions(opts ...jsonc.Option) Option {
+	return func(l *loader) {
+		l.opts = opts
+	}
+}
+
+// XDGDirs returns the config directories of the application app, from the
+// lowest to the highest priority: /etc/app, the directories of
+// $XDG_CONFIG_DIRS, by default /etc/xdg, and $XDG_CONFIG_HOME, by default
+// ~/.config, each joined with app.
+func XDGDirs(app string) []string {
+
+	dirs := []string{filepath.Join(`/etc`, app)}
+
+	configDirs := os.Getenv(`XDG_CONFIG_DIRS`)
+	if configDirs == `` {
+		configDirs = `/etc/xdg`
+	}
+
+	// the first directory of XDG_CONFIG_DIRS is the most important one
+	list := filepath.SplitList(configDirs)
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i] != `` {
+			dirs = append(dirs, filepath.Join(list[i], app))
+		}
+	}
+
+	home := os.Getenv(`XDG_CONFIG_HOME`)
+	if home == `` {
+		if userHome, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(userHome, `.config`)
+		}
+	}
+
+	if home != `` {
+		dirs = append(dirs, filepath.Join(home, app))
+	}
+	return dirs
+}
+
+// Origin is the location a value of a loaded configuration was read from.
+type Origin struct {
+	File string // the name of the file, as given to FS or joined from Dir
+	Pos  jsonc.Pos
+}
+
+// Origins maps the JSON Pointers of the values of a loaded configuration to
+// the file each value came from. Objects merged from several files have the
+// origin of the last one.
+type Origins map[string]Origin
+
+// Lookup returns the origin of the value at the JSON Pointer pointer, or of
+// the closest enclosing value which has one.
+func (o Origins) Lookup(pointer string) (Origin, bool) {
+
+	for {
+		if origin, ok := o[pointer]; ok {
+			return origin, true
+		}
+
+		idx := strings.LastIndexByte(pointer, '/')
+		if idx < 0 {
+			return Origin{}, false
+		}
+		pointer = pointer[:idx]
+	}
+}
+
+// Load reads the files added by opts, merges them and decodes the result
+// into the value pointed to by v as json.Unmarshal does. If no file exists v
+// is left unchanged. Syntax errors are prefixed by the name of their file,
+// values which do not fit v are reported as *jsonc.DecodeError located in
+// the file they came from.
+func Load(v interface{}, opts ...Option) (Origins, error) {
+
+	l := &loader{fileName: `config.jsonc`}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	m := &merger{origins: Origins{}}
+	var merged interface{}
+	var found bool
+
+	for _, ly := range l.layers {
+
+		path, name := ly.path, ly.name
+		if path == `` {
+			path, name = l.fileName, filepath.Join(ly.name, l.fileName)
+		}
+
+		data, err := fs.ReadFile(ly.fsys, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := jsonc.Parse(data, l.opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, name)
+		}
+
+		root := doc.Value()
+		if root == nil {
+			continue
+		}
+
+		merged, err = m.merge(merged, root, ``, name)
+		if err != nil {
+			return nil, errors.Wrap(err, name)
+		}
+		found = true
+	}
+
+	if !found {
+		return m.origins, nil
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return nil, m.locate(err)
+	}
+	return m.origins, nil
+}
+
+type merger struct {
+	origins Origins
+}
+
+// merge merges the value n read from file into dst at the JSON Pointer path
+// and returns the result.
+func (m *merger) merge(dst interface{}, n *jsonc.Node, path, file string) (interface{}, error) {
+
+	if n.Kind != jsonc.ObjectNode {
+		m.forget(path)
+		m.record(n, path, file)
+		return n.Interface()
+	}
+
+	obj, ok := dst.(map[string]interface{})
+	if !ok {
+		m.forget(path)
+		obj = map[string]interface{}{}
+	}
+	m.origins[path] = Origin{File: file, Pos: n.Start}
+
+	for _, member := range n.Members() {
+
+		value := member.Value()
+		if value == nil {
+			continue
+		}
+
+		name := member.Name()
+		v, err := m.merge(obj[name], value, path+`/`+jsonc.EscapePointer(name), file)
+		if err != nil {
+			return nil, err
+		}
+		obj[name] = v
+	}
+	return obj, nil
+}
+
+// forget removes the origins of the value at path and its children.
+func (m *merger) forget(path string) {
+
+	for p := range m.origins {
+		if p == path || strings.HasPrefix(p, path+`/`) {
+			delete(m.origins, p)
+		}
+	}
+}
+
+// record sets the origins of the value n at path and its children.
+func (m *merger) record(n *jsonc.Node, path, file string) {
+
+	m.origins[path] = Origin{File: file, Pos: n.Start}
+	switch n.Kind {
+	case jsonc.ObjectNode:
+		for _, member := range n.Members() {
+			if value := member.Value(); value != nil {
+				m.record(value, path+`/`+jsonc.EscapePointer(member.Name()), file)
+			}
+		}
+
+	case jsonc.ArrayNode:
+		for idx, e := range n.Elements() {
+			m.record(e, path+`/`+strconv.Itoa(idx), file)
+		}
+	}
+}
+
+// locate returns the type errors of decoding as *jsonc.DecodeError prefixed by
+// the file of the value.
+func (m *merger) locate(err error) error {
+
+	var terr *json.UnmarshalTypeError
+	if !errors.As(err, &terr) {
+		return err
+	}
+
+	// Field joins the member names by dots, newer versions of encoding/json
+	// escape them as JSON Pointer tokens already
+	pointer := ``
+	if terr.Field != `` {
+
+		var escaped string
+		for _, name := range strings.Split(terr.Field, `.`) {
+			pointer += `/` + jsonc.EscapePointer(name)
+			escaped += `/` + name
+		}
+
+		if _, ok := m.origins[pointer]; !ok {
+			pointer = escaped
+		}
+	}
+
+	origin, ok := m.origins.Lookup(pointer)
+	if !ok {
+		return err
+	}
+
+	return errors.Wrap(&jsonc.DecodeError{
+		Pos:   origin.Pos,
+		Value: terr.Value,
+		Type:  terr.Type,
+		Field: terr.Field,
+		Err:   err,
+	}, origin.File)
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type server struct {
+	Host    string   `json:"host"`
+	Port    int      `json:"port"`
+	Aliases []string `json:"aliases"`
+}
+
+type settings struct {
+	Name   string            `json:"name"`
+	Server server            `json:"server"`
+	Labels map[string]string `json:"labels"`
+	Debug  bool              `json:"debug"`
+}
+
+func TestLoad(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		`defaults.jsonc`: {Data: []byte(`{
+	// the defaults
+	name: app
+	server: {host: localhost, port: 8080, aliases: [a, b]}
+	labels: {env: dev, team: core}
+}`)},
+		`etc/config.jsonc`: {Data: []byte(`{
+	server: {
+		port: 9090
+		aliases: [c]
+	}
+	labels: {env: prod}
+}`)},
+		`home/config.jsonc`: {Data: []byte(`{debug: true, "a/b": {"c~d": 1}}`)},
+	}
+
+	var s settings
+	origins, err := Load(&s,
+		FS(fsys, `defaults.jsonc`),
+		FS(fsys, `etc/config.jsonc`),
+		FS(fsys, `missing.jsonc`),
+		FS(fsys, `home/config.jsonc`),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, settings{
+		Name:   `app`,
+		Server: server{Host: `localhost`, Port: 9090, Aliases: []string{`c`}},
+		Labels: map[string]string{`env`: `prod`, `team`: `core`},
+		Debug:  true,
+	}, s)
+
+	tests := []struct {
+		pointer string
+		file    string
+		line    int
+	}{
+		{pointer: `/name`, file: `defaults.jsonc`, line: 3},
+		{pointer: `/server/host`, file: `defaults.jsonc`, line: 4},
+		{pointer: `/server/port`, file: `etc/config.jsonc`, line: 3},
+		{pointer: `/server/aliases/0`, file: `etc/config.jsonc`, line: 4},
+		{pointer: `/labels/team`, file: `defaults.jsonc`, line: 5},
+		{pointer: `/labels/env`, file: `etc/config.jsonc`, line: 6},
+		{pointer: `/debug`, file: `home/config.jsonc`, line: 1},
+		{pointer: `/a~1b/c~0d`, file: `home/config.jsonc`, line: 1},
+	}
+
+	for _, ts := range tests {
+		origin, ok := origins[ts.pointer]
+		require.True(t, ok, ts.pointer)
+		assert.Equal(t, ts.file, origin.File, ts.pointer)
+		assert.Equal(t, ts.line, origin.Pos.Line, ts.pointer)
+	}
+
+	// the aliases of the defaults are replaced
+	_, ok := origins[`/server/aliases/1`]
+	assert.False(t, ok)
+
+	origin, ok := origins.Lookup(`/server/aliases/0/x`)
+	assert.True(t, ok)
+	assert.Equal(t, `etc/config.jsonc`, origin.File)
+
+	_, ok = Origins{}.Lookup(`/name`)
+	assert.False(t, ok)
+}
+
+func TestLoadDir(t *testing.T) {
+
+	dir, err := ioutil.TempDir(``, `config`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	system := filepath.Join(dir, `system`)
+	user := filepath.Join(dir, `user`)
+	for _, d := range []string{system, user} {
+		require.NoError(t, os.Mkdir(d, 0755))
+	}
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(system, `app.jsonc`), []byte(`{name: system, debug: true}`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(user, `app.jsonc`), []byte(`{name: user}`), 0644))
+
+	var s settings
+	origins, err := Load(&s, Dir(system, filepath.Join(dir, `missing`), user), FileName(`app.jsonc`))
+	require.NoError(t, err)
+
+	assert.Equal(t, `user`, s.Name)
+	assert.True(t, s.Debug)
+	assert.Equal(t, filepath.Join(user, `app.jsonc`), origins[`/name`].File)
+	assert.Equal(t, filepath.Join(system, `app.jsonc`), origins[`/debug`].File)
+
+	// nothing found leaves the value unchanged
+	s = settings{Name: `unchanged`}
+	origins, err = Load(&s, Dir(filepath.Join(dir, `missing`)))
+	require.NoError(t, err)
+	assert.Equal(t, settings{Name: `unchanged`}, s)
+	assert.Empty(t, origins)
+}
+
+func TestLoadErrors(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		`base.jsonc`:    {Data: []byte(`{server: {port: 1}}`)},
+		`broken.jsonc`:  {Data: []byte(`{server: {port: 1}`)},
+		`invalid.jsonc`: {Data: []byte("{\n\tserver: {\n\t\tport: eighty\n\t}\n}")},
+		`strict.json`:   {Data: []byte(`{name: app}`)},
+	}
+
+	var s settings
+	_, err := Load(&s, FS(fsys, `base.jsonc`), FS(fsys, `broken.jsonc`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `broken.jsonc: `)
+
+	_, err = Load(&s, FS(fsys, `base.jsonc`), FS(fsys, `invalid.jsonc`))
+	require.Error(t, err)
+	assert.Regexp(t, `^invalid.jsonc: `, err.Error())
+
+	var derr *jsonc.DecodeError
+	require.True(t, errors.As(err, &derr))
+	assert.Equal(t, 3, derr.Pos.Line)
+	assert.Equal(t, `server.port`, derr.Field)
+
+	// member names holding / and ~ are escaped in the pointer of the origin
+	var dirs struct {
+		Paths struct {
+			Root int `json:"/srv~1"`
+		} `json:"paths"`
+	}
+	fsys[`dirs.jsonc`] = &fstest.MapFile{Data: []byte("{\n  paths: {\n    \"/srv~1\": root\n  }\n}")}
+	_, err = Load(&dirs, FS(fsys, `base.jsonc`), FS(fsys, `dirs.jsonc`))
+	require.True(t, errors.As(err, &derr))
+	assert.Regexp(t, `^dirs.jsonc: `, err.Error())
+	assert.Equal(t, 3, derr.Pos.Line)
+
+	// older versions of encoding/json do not escape the names in Field
+	m := &merger{origins: Origins{`/paths/~1srv~01`: {File: `dirs.jsonc`, Pos: jsonc.Pos{Line: 3}}}}
+	err = m.locate(&json.UnmarshalTypeError{Value: `string`, Type: reflect.TypeOf(0), Field: `paths./srv~1`})
+	require.True(t, errors.As(err, &derr))
+	assert.Equal(t, 3, derr.Pos.Line)
+
+	_, err = Load(&s, FS(fsys, `strict.json`))
+	require.NoError(t, err)
+	assert.Equal(t, `app`, s.Name)
+
+	_, err = Load(&s, FS(fsys, `strict.json`), WithOptions(jsonc.WithDialect(jsonc.JSON)))
+	require.Error(t, err)
+	assert.Regexp(t, `^strict.json: `, err.Error())
+}
+
+func TestXDGDirs(t *testing.T) {
+
+	for _, name := range []string{`XDG_CONFIG_DIRS`, `XDG_CONFIG_HOME`} {
+		value, ok := os.LookupEnv(name)
+		if ok {
+			defer os.Setenv(name, value)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+
+	os.Setenv(`XDG_CONFIG_DIRS`, `/first`+string(filepath.ListSeparator)+`/second`)
+	os.Setenv(`XDG_CONFIG_HOME`, `/home/me/.config`)
+	assert.Equal(t, []string{`/etc/app`, `/second/app`, `/first/app`, `/home/me/.config/app`}, XDGDirs(`app`))
+
+	os.Unsetenv(`XDG_CONFIG_DIRS`)
+	dirs := XDGDirs(`app`)
+	assert.Equal(t, []string{`/etc/app`, `/etc/xdg/app`, `/home/me/.config/app`}, dirs)
+}
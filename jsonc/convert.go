@@ -0,0 +1,162 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Unmarshal parses the jsonc data and stores the result in the value pointed
+// to by v, like json.Unmarshal. data has to hold exactly one value, v is left
+// unchanged if it does not. Errors are located in data as by Decoder.Decode.
+func Unmarshal(data []byte, v interface{}, opts ...Option) error {
+
+	d, err := newDecoder(bytes.NewReader(data), false, opts)
+	if errors.Is(err, io.EOF) {
+		return errEmpty
+	}
+
+	if err != nil {
+		return err
+	}
+
+	raw, start, err := d.next()
+	if errors.Is(err, io.EOF) {
+		return errEmpty
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// reading on reports the syntax errors behind the value before v is set
+	_, err = d.dec.Token()
+	if errors.Is(err, io.EOF) {
+		return d.decode(raw, start, v)
+	}
+
+	if err == nil {
+		pos, _ := d.filter.srcmap.lookup(int(d.dec.InputOffset()) - 1)
+		err = newError(pos, 0, ``, "invalid data after top-level value")
+	}
+	return d.streamErr(err)
+}
+
+// Valid reports whether data is a valid jsonc document holding one value.
+func Valid(data []byte, opts ...Option) bool {
+	_, err := convert(data, false, ``, opts)
+	return err == nil
+}
+
+// ToJSON transforms the jsonc data into minified json.
+func ToJSON(data []byte, opts ...Option) ([]byte, error) {
+	return convert(data, false, ``, opts)
+}
+
+// Compact appends the jsonc src transformed into minified json to dst, like
+// json.Compact. dst is left unchanged on errors.
+func Compact(dst *bytes.Buffer, src []byte, opts ...Option) error {
+
+	out, err := convert(src, false, ``, opts)
+	if err != nil {
+		return err
+	}
+
+	dst.Write(out)
+	return nil
+}
+
+// Indent appends the jsonc src transformed into indented json to dst, like
+// json.Indent. Comments are dropped, dst is left unchanged on errors.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string, opts ...Option) error {
+
+	out, err := convert(src, false, ``, opts)
+	if err != nil {
+		return err
+	}
+	return json.Indent(dst, out, prefix, indent)
+}
+
+// Format formats the jsonc data keeping comments, blank lines and multiline
+// strings. Nested lines are indented by the string set with Space.
+func Format(data []byte, opts ...Option) ([]byte, error) {
+
+	c := newConfig(opts)
+	if len(data) == 0 {
+		return []byte{}, nil
+	}
+
+	f, err := c.filter(bytes.NewReader(data).ReadRune, true, c.space)
+	if err != nil {
+		return nil, err
+	}
+	return readAll(f)
+}
+
+// convert transforms data holding one value with a Filter.
+func convert(data []byte, format bool, space string, opts []Option) ([]byte, error) {
+	return newConfig(opts).convert(data, format, space)
+}
+
+func (c config) convert(data []byte, format bool, space string) ([]byte, error) {
+
+	resolve := (c.references || c.refs != nil) && !format
+	out, srcmap, err := c.filterAll(data, format, space, resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolve {
+		return c.resolveReferences(out, func(offset int) Pos {
+			pos, _ := srcmap.lookup(offset)
+			return pos
+		})
+	}
+	return out, nil
+}
+
+// filterAll returns the output of a Filter reading data holding one value and,
+// if mapped is set, the source map of the output.
+func (c config) filterAll(data []byte, format bool, space string, mapped bool) ([]byte, *sourceMap, error) {
+
+	f, err := c.filter(bytes.NewReader(data).ReadRune, format, space)
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errEmpty
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if mapped {
+		f.srcmap = &sourceMap{}
+	}
+
+	out, err := readAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !f.rootState.init {
+		return nil, nil, f.unexpectedEOF()
+	}
+	return out, f.srcmap, nil
+}
+
+// readAll reads the whole output of f.
+func readAll(f *Filter) ([]byte, error) {
+
+	out, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.Done() {
+		return nil, f.unexpectedEOF()
+	}
+	return out, nil
+}
+
+var errEmpty = newError(Pos{Line: 1, Column: 1}, 0, ``, "unexpected end of input")
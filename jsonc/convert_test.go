@@ -0,0 +1,119 @@
+package jsonc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+
+	var v struct {
+		A    int      `json:"a"`
+		List []string `json:"list"`
+	}
+
+	err := Unmarshal([]byte("{\n a: 1 // one\n list: [x, `y`]\n}\n"), &v)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v.A)
+	assert.Equal(t, []string{`x`, `y`}, v.List)
+
+	tests := []struct {
+		jsonc string
+		err   string
+	}{
+		{jsonc: ``, err: `line: 1 col: 1 unexpected end of input`},
+		{jsonc: `// only a comment`, err: `line: 1 col: 1 unexpected end of input`},
+		{jsonc: `{a: 1`, err: `line: 1 col: 6 unexpected end of input`},
+		{jsonc: `{a: x}`, err: `line: 1 col: 5 cannot unmarshal string into field a of type int`},
+		{jsonc: `{a: 1} {a: 2}`, err: `invalid first character: {`},
+		{jsonc: `{a: 1} x`, err: `invalid first character: x`},
+	}
+
+	for _, ts := range tests {
+		err := Unmarshal([]byte(ts.jsonc), &v)
+		require.Error(t, err, ts.jsonc)
+		assert.Contains(t, err.Error(), ts.err, ts.jsonc)
+	}
+
+	// trailing data is rejected before v is set, with the error of the decoder
+	var m map[string]int
+	err = Unmarshal([]byte(`{a: 7} x`), &m)
+	assert.Nil(t, m)
+
+	dec, derr := NewDecoder(bytes.NewReader([]byte(`{a: 7} x`)))
+	require.NoError(t, derr)
+	require.NoError(t, dec.Decode(&m))
+	assert.EqualError(t, err, dec.Decode(&m).Error())
+}
+
+func TestValid(t *testing.T) {
+
+	assert.True(t, Valid([]byte(`{a: 1} // comment`)))
+	assert.True(t, Valid([]byte(`[1, 2]`)))
+	assert.False(t, Valid([]byte(``)))
+	assert.False(t, Valid([]byte(`/* nothing */`)))
+	assert.False(t, Valid([]byte(`{a: 1`)))
+	assert.False(t, Valid([]byte(`[1,,2]`)))
+	assert.False(t, Valid([]byte(`{a: 1}`), WithDialect(JSON)))
+}
+
+func TestToJSON(t *testing.T) {
+
+	out, err := ToJSON([]byte("{\n a: 1, // one\n b: `x\ny`\n}"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1,"b":"x\ny"}`, string(out))
+
+	out, err = ToJSON([]byte(`{"a": [-1, -0.5, 1e-5, -2E-3]}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":[-1,-0.5,1e-5,-2E-3]}`, string(out))
+
+	for _, doc := range []string{`{a: x-y}`, `{a: -x}`, `{a: 1-2}`} {
+		_, err = ToJSON([]byte(doc))
+		assert.Error(t, err, doc)
+	}
+
+	_, err = ToJSON([]byte(`{a: -}`))
+	var jerr Error
+	require.True(t, errors.As(err, &jerr))
+	assert.Equal(t, 5, jerr.Column())
+}
+
+func TestCompactAndIndent(t *testing.T) {
+
+	dst := bytes.NewBufferString(`>`)
+	require.NoError(t, Compact(dst, []byte(`{a: [1, 2]} // c`)))
+	assert.Equal(t, `>{"a":[1,2]}`, dst.String())
+
+	assert.Error(t, Compact(dst, []byte(`{a: [1, 2}`)))
+	assert.Equal(t, `>{"a":[1,2]}`, dst.String())
+
+	dst.Reset()
+	require.NoError(t, Indent(dst, []byte(`{a: [1, 2]} // c`), ``, "\t"))
+	assert.Equal(t, "{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t]\n}", dst.String())
+}
+
+func TestFormat(t *testing.T) {
+
+	out, err := Format([]byte("{a:1, // c\nb:{c: `x`}}"))
+	require.NoError(t, err)
+	assert.Equal(t, "{a: 1, // c\n  b: {c: `x`}}", string(out))
+
+	out, err = Format([]byte("{\na:1}"), Space("\t"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\n\ta: 1}", string(out))
+
+	out, err = Format([]byte(`// only a comment`))
+	require.NoError(t, err)
+	assert.Equal(t, `// only a comment`, string(out))
+
+	out, err = Format(nil)
+	require.NoError(t, err)
+	assert.Equal(t, ``, string(out))
+
+	_, err = Format([]byte(`{a: `))
+	assert.Error(t, err)
+}
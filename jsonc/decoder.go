@@ -1,34 +1,314 @@
 package jsonc
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
-	"io/ioutil"
+	"math"
 
 	"github.com/pkg/errors"
 )
 
+// Decoder reads and decodes jsonc values from an input stream. Like
+// json.Decoder it reads one value at a time: repeated calls to Decode return
+// the concatenated top level values of the stream, Token and More allow to
+// walk large documents element by element.
 type Decoder struct {
 	filter *Filter
+	dec    *json.Decoder
+	config config
 }
 
-type Options = func(dec Decoder)
+// NewDecoder returns a Decoder reading from r configured by opts. Readers
+// which do not implement io.RuneReader are buffered. On an empty input the
+// first Decode or Token returns io.EOF.
+func NewDecoder(r io.Reader, opts ...Option) (*Decoder, error) {
+	return newDecoder(runeReader(r), true, opts)
+}
+
+func newDecoder(r io.RuneReader, multi bool, opts []Option) (*Decoder, error) {
+
+	c := newConfig(opts)
+	f, err := c.filter(r.ReadRune, false, ``)
+	if errors.Is(err, io.EOF) {
 
-func NewDecoder(r io.RuneReader, opts ...Options) (*Decoder, error) {
+		// the decoder of an empty input reports io.EOF when it is read
+		f, err = c.filter(nil, false, ``)
+		if err != nil {
+			return nil, err
+		}
+		f.err = io.EOF
+	}
 
-	ring, err := NewRing(256, 64, r.ReadRune)
 	if err != nil {
 		return nil, err
 	}
+	f.srcmap = &sourceMap{}
+	f.multi = multi
 
-	return &Decoder{filter: NewFilter(ring, 256, false, ``)}, nil
+	return &Decoder{filter: f, dec: json.NewDecoder(f), config: c}, nil
 }
 
+// Decode stores the next jsonc value in the value pointed to by v. At the end
+// of the input Decode returns io.EOF. Errors of encoding/json are returned as
+// *DecodeError located in the jsonc source.
 func (d *Decoder) Decode(v interface{}) error {
 
-	data, err := ioutil.ReadAll(d.filter)
+	d.filter.srcmap.trim(int(d.dec.InputOffset()))
+
+	raw, start, err := d.next()
+	if err != nil {
+		return err
+	}
+	return d.decode(raw, start, v)
+}
+
+// next reads the json of the next value and its offset in the filter output.
+func (d *Decoder) next() (json.RawMessage, int, error) {
+
+	var raw json.RawMessage
+	err := d.dec.Decode(&raw)
+	if err != nil {
+		return nil, 0, d.streamErr(err)
+	}
+	return raw, int(d.dec.InputOffset()) - len(raw), nil
+}
+
+// decode stores the json value raw read at start into v.
+func (d *Decoder) decode(raw json.RawMessage, start int, v interface{}) error {
+
+	var err error
+	data := []byte(raw)
+	if d.config.references || d.config.refs != nil {
+
+		data, err = d.config.resolveReferences(raw, func(offset int) Pos {
+			pos, _ := d.filter.srcmap.lookup(start + offset)
+			return pos
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if d.config.useNumber {
+		dec.UseNumber()
+	}
+	if d.config.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	err = dec.Decode(v)
+	if err != nil && !bytes.Equal(data, raw) {
+		// the offsets of resolved references are not in the source map
+		return err
+	}
+
+	if err != nil {
+		return d.filter.srcmap.translate(err, raw, start)
+	}
+	return nil
+}
+
+// DecodePath reads the next jsonc value of the stream and stores its subtree
+// at path in the value pointed to by v. The path is written as for Get. Only
+// the subtree is decoded, the rest of the value is skipped. A missing member
+// or element is reported as Error located at the object or array which lacks
+// it, the stream then continues behind the value.
+func (d *Decoder) DecodePath(path string, v interface{}) error {
+
+	var open int // containers entered on the path
+	for _, token := range splitPath(path) {
+
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		pos, _ := d.filter.srcmap.lookup(int(d.dec.InputOffset()) - 1)
+
+		found := true
+		switch t {
+		case json.Delim('{'):
+			open++
+			found, err = d.member(token)
+
+		case json.Delim('['):
+			open++
+			idx, ierr := arrayIndex(token, math.MaxInt32, false) // the length is unknown while streaming
+			if ierr != nil {
+				err = d.skip(open)
+				if err != nil {
+					return err
+				}
+				return newError(pos, 0, ``, "%v", ierr)
+			}
+			found, err = d.element(idx)
+
+		default:
+			err = d.skip(open)
+			if err != nil {
+				return err
+			}
+			return newError(pos, 0, ``, "%v has no member %q", rawKind(tokenJSON(t)), token)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			err = d.skip(open)
+			if err != nil {
+				return err
+			}
+
+			if t == json.Delim('[') {
+				return newError(pos, 0, ``, "array index %v out of range", token)
+			}
+			return newError(pos, 0, ``, "member %q not found", token)
+		}
+	}
+
+	err := d.Decode(v)
+	if err != nil {
+		return err
+	}
+	return d.skip(open)
+}
+
+// member reads the members of an object up to the value of the member name.
+func (d *Decoder) member(name string) (bool, error) {
+
+	for d.More() {
+
+		key, err := d.Token()
+		if err != nil {
+			return false, err
+		}
+
+		if key == name {
+			return true, nil
+		}
+
+		err = d.skipValue()
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// element reads the elements of an array up to the element at index.
+func (d *Decoder) element(index int) (bool, error) {
+
+	for ; d.More(); index-- {
+
+		if index == 0 {
+			return true, nil
+		}
+
+		err := d.skipValue()
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// skipValue reads the next value without decoding it.
+func (d *Decoder) skipValue() error {
+
+	d.filter.srcmap.trim(int(d.dec.InputOffset()))
+
+	var raw json.RawMessage
+	err := d.dec.Decode(&raw)
+	if err != nil {
+		return d.streamErr(err)
+	}
+	return nil
+}
+
+// skip reads the tokens up to the end of the open containers.
+func (d *Decoder) skip(open int) error {
+
+	for open > 0 {
+
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			open++
+		case json.Delim('}'), json.Delim(']'):
+			open--
+		}
+	}
+	return nil
+}
+
+// tokenJSON returns the json of a scalar token.
+func tokenJSON(t json.Token) json.RawMessage {
+
+	raw, err := json.Marshal(t)
+	if err != nil || t == nil {
+		return json.RawMessage(`null`)
+	}
+	return raw
+}
+
+// Token returns the next json token of the input stream as described for
+// json.Decoder.Token. At the end of the input Token returns nil, io.EOF.
+func (d *Decoder) Token() (json.Token, error) {
+
+	d.filter.srcmap.trim(int(d.dec.InputOffset()))
+
+	t, err := d.dec.Token()
 	if err != nil {
+		return t, d.streamErr(err)
+	}
+	return t, nil
+}
+
+// More reports whether there is another element in the current array or
+// object being parsed.
+func (d *Decoder) More() bool {
+	return d.dec.More()
+}
+
+// InputOffset returns the byte offset in the jsonc input of the current
+// decoder position. It lies between the end of the last decoded token and the
+// beginning of the next one, comments and spaces may lie in between.
+func (d *Decoder) InputOffset() int64 {
+
+	pos, ok := d.filter.srcmap.lookup(int(d.dec.InputOffset()))
+	if !ok {
+		return 0
+	}
+	return int64(pos.Offset)
+}
+
+// Buffered returns a reader of the json which was already produced from the
+// jsonc input but not yet decoded.
+func (d *Decoder) Buffered() io.Reader {
+	return io.MultiReader(d.dec.Buffered(), bytes.NewReader(d.filter.outbuf))
+}
+
+// streamErr converts the errors of reading the filter output.
+func (d *Decoder) streamErr(err error) error {
+
+	if errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) && !d.filter.Done() {
+		return d.filter.unexpectedEOF()
+	}
+
+	var jerr Error
+	if errors.As(err, &jerr) {
 		return errors.Wrap(err, `jsonc filter failed`)
 	}
-	return json.Unmarshal(data, v)
+	return d.filter.srcmap.translate(err, nil, 0)
 }
@@ -1,7 +1,12 @@
 package jsonc
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 
@@ -83,3 +88,240 @@ func TestJsoncDecoder(t *testing.T) {
 		assert.Equal(t, ts.json, string(data))
 	}
 }
+
+func TestDecodeErrorLocation(t *testing.T) {
+
+	type config struct {
+		Name     string `json:"name"`
+		Database struct {
+			Port    int   `json:"port"`
+			Ports   []int `json:"ports"`
+			Enabled bool  `json:"enabled"`
+		} `json:"database"`
+	}
+
+	tests := []struct {
+		jsonc string
+		field string
+		line  int
+		col   int
+		msg   string
+	}{
+		{
+			jsonc: "{\n // comment\n name: jsonc\n database: {\n  port: `8080`\n }\n}",
+			field: `database.port`,
+			line:  5,
+			col:   9,
+			msg:   "line: 5 col: 9 cannot unmarshal string into field database.port of type int",
+		},
+		{
+			jsonc: "{\n database: {\n  ports: [1, 2, /* three */ three]\n }\n}",
+			field: `database.ports.2`,
+			line:  3,
+			col:   29,
+		},
+		{
+			jsonc: "{\n database: {enabled: {x: 1}}\n}",
+			field: `database.enabled`,
+			line:  2,
+			col:   22,
+		},
+		{
+			jsonc: "{\n name: [\"a\"] database: {}\n}",
+			field: `name`,
+			line:  2,
+			col:   8,
+		},
+		{
+			jsonc: "{\n database: {port: \"a \\\" b\"}\n}",
+			field: `database.port`,
+			line:  2,
+			col:   19,
+		},
+	}
+
+	for _, ts := range tests {
+
+		dec, err := NewDecoder(strings.NewReader(ts.jsonc))
+		require.NoError(t, err)
+
+		err = dec.Decode(&config{})
+		require.Error(t, err)
+
+		var derr *DecodeError
+		require.True(t, errors.As(err, &derr), err.Error())
+		assert.Equal(t, ts.field, derr.Field, ts.jsonc)
+		assert.Equal(t, ts.line, derr.Pos.Line, ts.jsonc)
+		assert.Equal(t, ts.col, derr.Pos.Column, ts.jsonc)
+
+		if ts.msg != `` {
+			assert.Equal(t, ts.msg, err.Error())
+		}
+
+		var terr *json.UnmarshalTypeError
+		assert.True(t, errors.As(err, &terr))
+	}
+}
+
+func TestDecoderTokens(t *testing.T) {
+
+	type entry struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	src := `// servers
+{
+  servers: [
+    {name: alpha, port: 1} // first
+    {name: beta port: 2}
+  ]
+}`
+
+	dec, err := NewDecoder(strings.NewReader(src))
+	require.NoError(t, err)
+
+	var tokens []json.Token
+	for _, expected := range []json.Token{json.Delim('{'), `servers`, json.Delim('[')} {
+		tok, err := dec.Token()
+		require.NoError(t, err)
+		assert.Equal(t, expected, tok)
+		tokens = append(tokens, tok)
+	}
+	buffered, err := ioutil.ReadAll(dec.Buffered())
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"alpha","port":1},{"name":"beta","port":2}]}`, string(buffered))
+
+	assert.True(t, dec.InputOffset() > int64(strings.Index(src, `[`)))
+	assert.True(t, dec.InputOffset() <= int64(strings.Index(src, `{name`)))
+
+	var entries []entry
+	for dec.More() {
+		var e entry
+		require.NoError(t, dec.Decode(&e))
+		entries = append(entries, e)
+	}
+	assert.Equal(t, []entry{{Name: `alpha`, Port: 1}, {Name: `beta`, Port: 2}}, entries)
+	assert.Equal(t, int64(strings.Index(src, `2}`)+2), dec.InputOffset())
+
+	for _, expected := range []json.Token{json.Delim(']'), json.Delim('}')} {
+		tok, err := dec.Token()
+		require.NoError(t, err)
+		assert.Equal(t, expected, tok)
+	}
+
+	_, err = dec.Token()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoderStreamsLargeArrays(t *testing.T) {
+
+	const count = 20000
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("[\n"))
+		for i := 0; i < count; i++ {
+			fmt.Fprintf(pw, "  {id: %v, name: `n%v`} // element %v\n", i, i, i)
+		}
+		pw.Write([]byte("]"))
+		pw.Close()
+	}()
+
+	dec, err := NewDecoder(bufio.NewReader(pr))
+	require.NoError(t, err)
+
+	tok, err := dec.Token()
+	require.NoError(t, err)
+	assert.Equal(t, json.Delim('['), tok)
+
+	var n int
+	for dec.More() {
+		var e struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+		require.NoError(t, dec.Decode(&e))
+		require.Equal(t, n, e.ID)
+		n++
+
+		// the source map does not grow with the document
+		require.True(t, len(dec.filter.srcmap.out) < 128)
+	}
+	assert.Equal(t, count, n)
+
+	tok, err = dec.Token()
+	require.NoError(t, err)
+	assert.Equal(t, json.Delim(']'), tok)
+
+	err = dec.Decode(&struct{}{})
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoderUnexpectedEOF(t *testing.T) {
+
+	dec, err := NewDecoder(strings.NewReader("{\n a: [1, 2"))
+	require.NoError(t, err)
+
+	err = dec.Decode(&struct{}{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `line: 2 col: 10 unexpected end of input`)
+}
+
+func TestDecoderEmptyInput(t *testing.T) {
+
+	for _, src := range []string{``, " \n", `// nothing`} {
+
+		dec, err := NewDecoder(strings.NewReader(src))
+		require.NoError(t, err, src)
+		assert.False(t, dec.More(), src)
+
+		_, err = dec.Token()
+		assert.Equal(t, io.EOF, err, src)
+
+		var v interface{}
+		assert.Equal(t, io.EOF, dec.Decode(&v), src)
+	}
+}
+
+func TestDecoderMultipleValues(t *testing.T) {
+
+	src := "// log records\n{level: info, msg: `started`}\n{level: warn msg: `slow`} /* next */ {level: info, msg: done}\n" +
+		"\x1e[1, 2]\n\x1e\"end\"\n"
+
+	dec, err := NewDecoder(strings.NewReader(src))
+	require.NoError(t, err)
+
+	var values []string
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		values = append(values, string(data))
+	}
+
+	assert.Equal(t, []string{
+		`{"level":"info","msg":"started"}`,
+		`{"level":"warn","msg":"slow"}`,
+		`{"level":"info","msg":"done"}`,
+		`[1,2]`,
+		`"end"`,
+	}, values)
+
+	dec, err = NewDecoder(strings.NewReader("{a: 1}\n{a: x y}"))
+	require.NoError(t, err)
+
+	var v map[string]interface{}
+	require.NoError(t, dec.Decode(&v))
+	assert.Equal(t, map[string]interface{}{`a`: 1.0}, v)
+
+	err = dec.Decode(&v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `line: 2 col: 8`)
+}
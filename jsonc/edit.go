@@ -0,0 +1,989 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// Update returns doc changed to hold the value v with a minimal edit of its
+// text. Scalars which changed are replaced in place keeping their quoting
+// where possible, members and elements missing in doc are inserted with the
+// indentation and comma style of the document, members missing in v are
+// deleted together with the comments on the lines directly above them.
+// Everything else stays byte identical.
+//
+// v is encoded by Marshal, comments of new members are taken from the jsonc
+// struct tags. Members of doc which the struct types of v have no field for
+// are kept. Documents which are strict json stay strict json.
+func Update(doc []byte, v interface{}, opts ...Option) ([]byte, error) {
+
+	root, err := Parse(doc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	e := newEditor(doc, root, opts)
+
+	data, err := Marshal(v, Space(e.unit), WithDialect(e.dialect))
+	if err != nil {
+		return nil, err
+	}
+
+	old := root.Value()
+	if old == nil {
+		out := append([]byte{}, doc...)
+		if len(out) > 0 && out[len(out)-1] != '\n' {
+			out = append(out, '\n')
+		}
+		return append(out, data...), nil
+	}
+
+	update, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.update(root, old, update.Value(), data, reflect.TypeOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return e.bytes()
+}
+
+// editor changes the syntax tree of a document. Positions of the edited tree
+// are outdated, only the text of its leaves counts. New subtrees are added
+// as leaves holding their whole text.
+type editor struct {
+	src     []byte
+	root    *Node
+	unit    string
+	dialect Dialect
+	commas  bool
+	opts    []Option
+}
+
+func newEditor(src []byte, root *Node, opts []Option) *editor {
+
+	e := &editor{
+		src:     src,
+		root:    root,
+		unit:    indentUnit(src),
+		dialect: newConfig(opts).dialect,
+		opts:    opts,
+	}
+
+	if e.dialect != JSON && len(src) > 0 && Valid(src, WithDialect(JSON)) {
+		e.dialect = JSON
+	}
+
+	e.commas = e.dialect == JSON || hasComma(root)
+	return e
+}
+
+// bytes prints the edited document and checks that it is still valid.
+func (e *editor) bytes() ([]byte, error) {
+
+	buf := &bytes.Buffer{}
+	err := Print(buf, e.root)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = Parse(buf.Bytes(), e.opts...)
+	if err != nil {
+		return nil, fmt.Errorf("edited document is invalid: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// update changes the value old with the parent node to the value of the
+// same place in the document src. t is the Go type value was encoded from,
+// nil if it is unknown.
+func (e *editor) update(parent, old, value *Node, src []byte, t reflect.Type) error {
+
+	switch {
+	case old.Kind == ObjectNode && value.Kind == ObjectNode:
+		return e.updateObject(old, value, src, t)
+
+	case old.Kind == ArrayNode && value.Kind == ArrayNode:
+		return e.updateArray(old, value, src, t)
+	}
+	return e.set(parent, old, value, src)
+}
+
+// set replaces the value old with the parent node by the value of the
+// document src unless both hold the same json value.
+func (e *editor) set(parent, old, value *Node, src []byte) error {
+
+	same, err := sameValue(old, value)
+	if err != nil {
+		return err
+	}
+
+	if same {
+		return nil
+	}
+
+	text := scalarText(old, value, e.dialect)
+	if text == `` {
+		text = reindent(value, lineIndent(src, value.Start.Offset), lineIndent(e.src, old.Start.Offset))
+	}
+	e.replace(parent, old, &Node{Kind: value.Kind, Start: old.Start, Text: text, Quote: value.Quote})
+	return nil
+}
+
+func (e *editor) updateObject(old, value *Node, src []byte, t reflect.Type) error {
+
+	list := items(old)
+
+	// the last member of a name is the one decoded
+	names := map[string]int{}
+	for k, it := range list {
+		names[old.Children[it.entry].Name()] = k
+	}
+
+	newList := items(value)
+	wanted := map[string]bool{}
+	inserts := map[int][]int{}
+	anchor := -1
+
+	for nk, it := range newList {
+
+		m := value.Children[it.entry]
+		wanted[m.Name()] = true
+
+		k, ok := names[m.Name()]
+		if !ok {
+			inserts[anchor] = append(inserts[anchor], nk)
+			continue
+		}
+		anchor = k
+
+		om := old.Children[list[k].entry]
+		err := e.update(om, om.Value(), m.Value(), src, memberType(t, m.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	fields := structFields(t)
+
+	var removes []int
+	for k, it := range list {
+
+		name := old.Children[it.entry].Name()
+		if !wanted[name] && (fields == nil || fields.known(name)) {
+			removes = append(removes, k)
+		}
+	}
+
+	return e.edit(old, removes, inserts, value, src)
+}
+
+func (e *editor) updateArray(old, value *Node, src []byte, t reflect.Type) error {
+
+	list := items(old)
+	elements := value.Elements()
+
+	t = editType(t)
+	if t != nil && t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		t = nil
+	}
+
+	for k := 0; k < len(list) && k < len(elements); k++ {
+
+		var et reflect.Type
+		if t != nil {
+			et = t.Elem()
+		}
+
+		err := e.update(old, old.Children[list[k].entry], elements[k], src, et)
+		if err != nil {
+			return err
+		}
+	}
+
+	var removes []int
+	for k := len(elements); k < len(list); k++ {
+		removes = append(removes, k)
+	}
+
+	inserts := map[int][]int{}
+	for k := len(list); k < len(elements); k++ {
+		inserts[len(list)-1] = append(inserts[len(list)-1], k)
+	}
+
+	return e.edit(old, removes, inserts, value, src)
+}
+
+// edit removes the items removes of the container c and inserts the items of
+// the container value behind the items of c given as keys of inserts, -1
+// inserts in front.
+func (e *editor) edit(c *Node, removes []int, inserts map[int][]int, value *Node, src []byte) error {
+
+	if len(removes) == 0 && len(inserts) == 0 {
+		return nil
+	}
+
+	var texts [][]string
+	var anchors []int
+	for anchor := -1; anchor < len(items(c)); anchor++ {
+		if len(inserts[anchor]) == 0 {
+			continue
+		}
+
+		newList := items(value)
+		var t []string
+		for _, nk := range inserts[anchor] {
+			t = append(t, e.itemText(c, value, newList[nk], src))
+		}
+		texts = append(texts, t)
+		anchors = append(anchors, anchor)
+	}
+
+	ed := newContainerEdit(c, e)
+	for _, k := range removes {
+		ed.remove(k)
+	}
+
+	for i, anchor := range anchors {
+		ed.insert(anchor, texts[i])
+	}
+	ed.apply()
+	return nil
+}
+
+// insert inserts value read from src as entry of the container c behind the
+// entry anchor, -1 inserts in front. name is the name of a new member.
+func (e *editor) insert(c *Node, anchor int, name string, value *Node, src []byte) error {
+
+	open, close := `[`, `]`
+	entry := value
+
+	if c.Kind == ObjectNode {
+		open, close = `{`, `}`
+
+		key := &encodeState{dialect: e.dialect}
+		key.key(name)
+
+		entry = &Node{Kind: MemberNode, Start: value.Start, Children: []*Node{
+			{Kind: KeyNode, Text: key.String()},
+			{Kind: PunctNode, Text: `:`},
+			{Kind: SpaceNode, Text: ` `},
+			value,
+		}}
+	}
+
+	container := &Node{Kind: c.Kind, Children: []*Node{
+		{Kind: PunctNode, Text: open},
+		entry,
+		{Kind: PunctNode, Text: close},
+	}}
+	return e.edit(c, nil, map[int][]int{anchor: {0}}, container, src)
+}
+
+// remove removes the entry k of the container c with its comments.
+func (e *editor) remove(c *Node, k int) error {
+	return e.edit(c, []int{k}, nil, nil, nil)
+}
+
+// itemText returns the text of the item it of the container value in src
+// with its comments, indented for the container c.
+func (e *editor) itemText(c, value *Node, it item, src []byte) string {
+
+	entry := value.Children[it.entry]
+	from := lineIndent(src, entry.Start.Offset)
+	to := e.itemIndent(c)
+
+	if !isMultiLine(c) && len(items(c)) > 0 {
+		return reindent(entry, from, lineIndent(e.src, c.Start.Offset))
+	}
+
+	buf := &strings.Builder{}
+	for i := attachedStart(value, it); i <= it.entry; i++ {
+
+		n := value.Children[i]
+		if n.Kind == SpaceNode && buf.Len() == 0 {
+			continue
+		}
+		buf.WriteString(reindent(n, from, to))
+	}
+	return buf.String()
+}
+
+// itemIndent returns the indentation of the items of the container c.
+func (e *editor) itemIndent(c *Node) string {
+
+	for _, it := range items(c) {
+		for i := it.start; i < it.entry; i++ {
+			if n := c.Children[i]; n.Kind == SpaceNode && strings.Contains(n.Text, "\n") {
+				return lineIndent(e.src, c.Children[it.entry].Start.Offset)
+			}
+		}
+	}
+	return lineIndent(e.src, c.Start.Offset) + e.unit
+}
+
+// replace replaces the child old of parent by n.
+func (e *editor) replace(parent, old, n *Node) {
+	for i, c := range parent.Children {
+		if c == old {
+			parent.Children[i] = n
+			return
+		}
+	}
+}
+
+// item is an entry of an object or array, a member or a value, with its
+// spaces, comments and comma. children[start:entry] precede the entry and
+// start on the line of the previous entry, children[entry+1:end] follow it on
+// its line.
+type item struct {
+	start int
+	entry int
+	end   int
+	comma int // index of the comma behind the entry or -1
+}
+
+// items splits the children of the object or array c into its entries.
+func items(c *Node) []item {
+
+	splitSpaces(c)
+
+	last := len(c.Children) - 1
+	isEntry := func(i int) bool {
+		n := c.Children[i]
+		if c.Kind == ObjectNode {
+			return n.Kind == MemberNode
+		}
+		return n.IsValue()
+	}
+
+	var list []item
+	i := header(c)
+	for {
+		j := i
+		for j < last && !isEntry(j) {
+			j++
+		}
+
+		if j >= last {
+			return list
+		}
+
+		it := item{start: i, entry: j, comma: -1}
+		for m := j + 1; m < last && !isEntry(m); m++ {
+			if n := c.Children[m]; n.Kind == PunctNode && n.Text == `,` {
+				it.comma = m
+				break
+			}
+		}
+
+		it.end = sameLine(c, j+1)
+		if it.comma >= it.end {
+			it.end = it.comma + 1
+		}
+
+		list = append(list, it)
+		i = it.end
+	}
+}
+
+// header returns the index behind the opening brace of c and the comments on
+// its line.
+func header(c *Node) int {
+	return sameLine(c, 1)
+}
+
+// sameLine returns the index of the first child from i on which starts a new
+// line or is an entry.
+func sameLine(c *Node, i int) int {
+
+	for ; i < len(c.Children)-1; i++ {
+
+		n := c.Children[i]
+		switch {
+		case n.Kind == SpaceNode && !strings.Contains(n.Text, "\n"):
+		case n.Kind == PunctNode && n.Text == `,`:
+		case n.IsComment():
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// splitSpaces splits the spaces of c at their first line break, so each line
+// starts with a new child.
+func splitSpaces(c *Node) {
+
+	var children []*Node
+	for _, n := range c.Children {
+
+		idx := strings.IndexByte(n.Text, '\n')
+		if n.Kind != SpaceNode || idx <= 0 {
+			children = append(children, n)
+			continue
+		}
+
+		mid := advance(n.Start, n.Text[:idx])
+		children = append(children,
+			&Node{Kind: SpaceNode, Start: n.Start, End: mid, Text: n.Text[:idx]},
+			&Node{Kind: SpaceNode, Start: mid, End: n.End, Text: n.Text[idx:]},
+		)
+	}
+
+	if len(children) != len(c.Children) {
+		c.Children = children
+	}
+}
+
+// attachedStart returns the index of the space before the comments directly
+// above the entry of it. A blank line detaches comments.
+func attachedStart(c *Node, it item) int {
+
+	first := it.entry
+	for j := it.entry - 1; j >= it.start; j-- {
+
+		n := c.Children[j]
+		if n.Kind == SpaceNode {
+			if strings.Count(n.Text, "\n") > 1 {
+				break
+			}
+			continue
+		}
+
+		if !n.IsComment() {
+			break
+		}
+		first = j
+	}
+
+	for first > it.start && c.Children[first-1].Kind == SpaceNode {
+		first--
+	}
+	return first
+}
+
+// isMultiLine reports whether the entries of c are on their own lines.
+func isMultiLine(c *Node) bool {
+
+	for _, n := range c.Children {
+		if n.Kind == SpaceNode && strings.Contains(n.Text, "\n") {
+			return true
+		}
+	}
+	return false
+}
+
+// containerEdit collects the removals and insertions of the entries of a
+// container and applies them at once.
+type containerEdit struct {
+	c        *Node
+	e        *editor
+	list     []item
+	removed  []bool
+	before   map[int][]*Node
+	touched  map[*Node]bool
+	seps     map[*Node]bool // the separators inserted in front of entries
+	trailing bool
+	commas   bool
+}
+
+func newContainerEdit(c *Node, e *editor) *containerEdit {
+
+	ed := &containerEdit{
+		c:       c,
+		e:       e,
+		list:    items(c),
+		removed: make([]bool, len(c.Children)),
+		before:  map[int][]*Node{},
+		touched: map[*Node]bool{},
+		seps:    map[*Node]bool{},
+	}
+
+	// containers with one entry follow the document, on a single line they
+	// are separated by commas
+	ed.commas = len(ed.list) < 2 && (e.commas || !isMultiLine(c))
+	for k, it := range ed.list {
+		if it.comma >= 0 {
+			ed.commas = true
+			ed.trailing = k == len(ed.list)-1
+		}
+	}
+	return ed
+}
+
+func (ed *containerEdit) drop(from, to int) {
+	for i := from; i < to; i++ {
+		ed.removed[i] = true
+	}
+}
+
+// remove removes the entry k with its comments.
+func (ed *containerEdit) remove(k int) {
+
+	c, it := ed.c, ed.list[k]
+
+	// an entry on its own line is removed with the line
+	lineEnd := it.end == len(c.Children)-1 || strings.HasPrefix(c.Children[it.end].Text, "\n")
+	for i := it.start; i < it.entry && lineEnd; i++ {
+		if n := c.Children[i]; n.Kind == SpaceNode && strings.Contains(n.Text, "\n") {
+			ed.drop(attachedStart(c, it), it.end)
+			return
+		}
+	}
+
+	// entries sharing a line
+	if k < len(ed.list)-1 {
+		to := it.end
+		next := ed.list[k+1]
+		for to < next.entry && c.Children[to].Kind == SpaceNode {
+			to++
+		}
+		ed.drop(it.entry, to)
+		return
+	}
+
+	// the spaces in front go with it, also those left by removed entries,
+	// the spaces behind the opening brace stay
+	from := it.entry
+	for from > 2 && (c.Children[from-1].Kind == SpaceNode || ed.removed[from-1]) {
+		from--
+	}
+	ed.drop(from, it.entry+1)
+	if it.comma >= 0 {
+		ed.removed[it.comma] = true
+	}
+}
+
+// insert inserts the entries texts behind the entry anchor, -1 inserts in
+// front.
+func (ed *containerEdit) insert(anchor int, texts []string) {
+
+	c := ed.c
+	kind := MemberNode
+	if c.Kind == ArrayNode {
+		kind = StringNode
+	}
+
+	entry := func(text string) *Node {
+		n := &Node{Kind: kind, Text: text}
+		ed.touched[n] = true
+		return n
+	}
+
+	at := header(c)
+	if anchor >= 0 {
+		at = ed.list[anchor].end
+		ed.touched[c.Children[ed.list[anchor].entry]] = true
+	}
+
+	multiLine := isMultiLine(c)
+	if len(ed.list) == 0 && !multiLine {
+
+		// an empty object or a new multiline entry opens the container
+		open := c.Kind == ObjectNode
+		for _, t := range texts {
+			open = open || strings.Contains(t, "\n")
+		}
+
+		if !open {
+			var nodes []*Node
+			for idx, t := range texts {
+				if idx > 0 {
+					nodes = append(nodes, &Node{Kind: SpaceNode, Text: ` `})
+				}
+				nodes = append(nodes, entry(t))
+			}
+			ed.drop(1, len(c.Children)-1)
+			ed.before[len(c.Children)-1] = nodes
+			return
+		}
+
+		indent := ed.e.itemIndent(c)
+		var nodes []*Node
+		for _, t := range texts {
+			nodes = append(nodes, &Node{Kind: SpaceNode, Text: "\n" + indent}, entry(t))
+		}
+		nodes = append(nodes, &Node{Kind: SpaceNode, Text: "\n" + lineIndent(ed.e.src, c.Start.Offset)})
+		ed.drop(1, len(c.Children)-1)
+		ed.before[len(c.Children)-1] = nodes
+		return
+	}
+
+	if len(ed.list) == 0 {
+		// in front of the line break before the closing brace
+		at = len(c.Children) - 1
+		if n := c.Children[at-1]; n.Kind == SpaceNode && strings.HasPrefix(n.Text, "\n") {
+			at--
+		}
+	}
+
+	var nodes []*Node
+	for _, t := range texts {
+
+		if !multiLine {
+			sep := ed.separator()
+			if anchor < 0 {
+				// in front of the first entry
+				n := &Node{Kind: SpaceNode, Text: sep}
+				ed.seps[n] = true
+				nodes = append(nodes, entry(t), n)
+				continue
+			}
+
+			// directly behind the entry, the spaces up to the next one or the
+			// closing brace stay
+			at = ed.list[anchor].entry + 1
+			nodes = append(nodes, &Node{Kind: SpaceNode, Text: sep}, entry(t))
+			continue
+		}
+
+		nodes = append(nodes, &Node{Kind: SpaceNode, Text: "\n" + ed.e.itemIndent(c)}, entry(t))
+	}
+	ed.before[at] = append(ed.before[at], nodes...)
+}
+
+// separator returns the spaces between the comma of an entry and the next
+// entry on a line, a space if the container does not tell.
+func (ed *containerEdit) separator() string {
+
+	c := ed.c
+	for k := 0; k+1 < len(ed.list); k++ {
+
+		from := ed.list[k].entry + 1
+		if comma := ed.list[k].comma; comma >= 0 {
+			from = comma + 1
+		}
+
+		sep := ``
+		for i := from; i < ed.list[k+1].entry; i++ {
+			if n := c.Children[i]; n.Kind == SpaceNode {
+				sep += n.Text
+			}
+		}
+
+		if sep != `` || ed.commas {
+			return sep
+		}
+	}
+	return ` `
+}
+
+// apply rebuilds the children of the container and fixes the commas of the
+// entries next to the changes.
+func (ed *containerEdit) apply() {
+
+	c := ed.c
+
+	// the entry before a removed one may lose its successor
+	for k, it := range ed.list {
+		if !ed.removed[it.entry] {
+			continue
+		}
+
+		for j := k - 1; j >= 0; j-- {
+			if e := ed.list[j].entry; !ed.removed[e] {
+				ed.touched[c.Children[e]] = true
+				break
+			}
+		}
+	}
+
+	var children []*Node
+	for i, n := range c.Children {
+		children = append(children, ed.before[i]...)
+		if !ed.removed[i] {
+			children = append(children, n)
+		}
+	}
+	c.Children = children
+
+	// separators in front of entries which were all removed trail
+	for i := len(c.Children) - 2; i > 0 && c.Children[i].Kind == SpaceNode; i-- {
+		if ed.seps[c.Children[i]] {
+			c.Children = append(c.Children[:i:i], c.Children[i+1:]...)
+		}
+	}
+
+	// a container emptied down to its spaces closes on its line
+	empty := len(c.Children) > 2
+	for _, n := range c.Children[1 : len(c.Children)-1] {
+		empty = empty && n.Kind == SpaceNode
+	}
+	if empty {
+		c.Children = []*Node{c.Children[0], c.Children[len(c.Children)-1]}
+	}
+
+	if !ed.commas {
+		return
+	}
+
+	list := items(c)
+	for k := len(list) - 1; k >= 0; k-- {
+
+		it := list[k]
+		if !ed.touched[c.Children[it.entry]] && k < len(list)-1 {
+			continue
+		}
+		want := k < len(list)-1 || ed.trailing
+
+		switch {
+		case want && it.comma < 0:
+			children := append([]*Node{}, c.Children[:it.entry+1]...)
+			children = append(children, &Node{Kind: PunctNode, Text: `,`})
+			c.Children = append(children, c.Children[it.entry+1:]...)
+
+		case !want && it.comma >= 0:
+			// with the spaces in front of it
+			from := it.comma
+			for from > it.entry+1 && c.Children[from-1].Kind == SpaceNode {
+				from--
+			}
+			c.Children = append(c.Children[:from:from], c.Children[it.comma+1:]...)
+		}
+	}
+}
+
+// editType returns t without pointers, nil if the json of its values is not
+// given by its Go type.
+func editType(t reflect.Type) reflect.Type {
+
+	for t != nil && t.Kind() == reflect.Ptr && t != nodeType {
+		t = t.Elem()
+	}
+
+	if t == nil || t == nodeType || t.Kind() == reflect.Interface {
+		return nil
+	}
+
+	pt := reflect.PtrTo(t)
+	if pt.Implements(marshalerType) || pt.Implements(textMarshalerType) {
+		return nil
+	}
+	return t
+}
+
+// jsonFields are the fields of a struct type by their json name.
+type jsonFields map[string]Field
+
+// structFields returns the fields of the struct type t, nil for other types.
+func structFields(t reflect.Type) jsonFields {
+
+	t = editType(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := jsonFields{}
+	for _, f := range Fields(t) {
+		fields[f.Name] = f
+	}
+	return fields
+}
+
+// known reports whether a member name is decoded into one of the fields,
+// json names match case insensitively.
+func (fields jsonFields) known(name string) bool {
+
+	for n := range fields {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// memberType returns the type of the member name of values of type t, nil if
+// it is unknown.
+func memberType(t reflect.Type, name string) reflect.Type {
+
+	if fields := structFields(t); fields != nil {
+
+		f, ok := fields[name]
+		if !ok {
+			return nil
+		}
+		return f.Type
+	}
+
+	t = editType(t)
+	if t != nil && t.Kind() == reflect.Map {
+		return t.Elem()
+	}
+	return nil
+}
+
+// hasComma reports whether the tree n separates entries by commas.
+func hasComma(n *Node) bool {
+
+	if n.Kind == PunctNode && n.Text == `,` {
+		return true
+	}
+
+	for _, c := range n.Children {
+		if hasComma(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// reindent returns the text of n with the indentation from of its lines
+// replaced by to. Only line breaks outside of strings are touched.
+func reindent(n *Node, from, to string) string {
+
+	buf := &strings.Builder{}
+	var walk func(n *Node)
+	walk = func(n *Node) {
+
+		if n.Children != nil {
+			for _, c := range n.Children {
+				walk(c)
+			}
+			return
+		}
+
+		if n.Kind != SpaceNode {
+			buf.WriteString(n.Text)
+			return
+		}
+		buf.WriteString(strings.ReplaceAll(n.Text, "\n"+from, "\n"+to))
+	}
+	walk(n)
+	return buf.String()
+}
+
+// lineIndent returns the spaces and tabs the line holding offset starts with.
+func lineIndent(src []byte, offset int) string {
+
+	if offset > len(src) {
+		offset = len(src)
+	}
+
+	start := bytes.LastIndexByte(src[:offset], '\n') + 1
+	end := start
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[start:end])
+}
+
+// indentUnit returns the indentation of the first indented line of src, two
+// spaces if there is none.
+func indentUnit(src []byte) string {
+
+	for _, line := range bytes.Split(src, []byte("\n")) {
+
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return `  `
+}
+
+// sameValue reports whether the scalars or containers a and b hold the same
+// json value. Numbers are compared by value.
+func sameValue(a, b *Node) (bool, error) {
+
+	if a.IsValue() != b.IsValue() {
+		return false, nil
+	}
+
+	av, err := a.Interface()
+	if err != nil {
+		return false, err
+	}
+
+	bv, err := b.Interface()
+	if err != nil {
+		return false, err
+	}
+
+	return equalValues(av, bv), nil
+}
+
+// equalValues compares values decoded with json.Number, numbers are equal
+// by value.
+func equalValues(a, b interface{}) bool {
+
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		af, _, aerr := big.ParseFloat(string(a), 10, 256, big.ToNearestEven)
+		bf, _, berr := big.ParseFloat(string(b), 10, 256, big.ToNearestEven)
+		if aerr != nil || berr != nil {
+			return a == b
+		}
+		return af.Cmp(bf) == 0
+
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !equalValues(v, w) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if !equalValues(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// scalarText returns the text of the string value in the quoting style of
+// old, or an empty string if the style does not fit.
+func scalarText(old, value *Node, dialect Dialect) string {
+
+	if old.Kind != StringNode || value.Kind != StringNode {
+		return ``
+	}
+
+	v, err := value.Interface()
+	if err != nil {
+		return ``
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return ``
+	}
+
+	switch {
+	case old.Quote == DoubleQuote || dialect == JSON:
+		return string(appendQuoted(nil, s))
+
+	case old.Quote == NoQuote && isBareValue(s):
+		return s
+
+	case old.Quote == Backtick && isMultiline(s):
+		return "`" + s + "`"
+	}
+	return ``
+}
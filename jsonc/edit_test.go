@@ -0,0 +1,264 @@
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type updateServer struct {
+	Name string `json:"name"`
+	Port int    `json:"port,omitempty"`
+}
+
+type updateConfig struct {
+	Name    string         `json:"name"`
+	Port    int            `json:"port,omitempty" jsonc:",comment=listen port"`
+	Debug   bool           `json:"debug,omitempty"`
+	Tags    []string       `json:"tags"`
+	Servers []updateServer `json:"servers,omitempty"`
+	Motd    string         `json:"motd,omitempty"`
+}
+
+const updateDoc = `// service config
+{
+	// the name
+	name: "alpha", // inline
+
+	debug: true
+
+	/* tags of the service */
+	tags: [a, b],
+	servers: [
+		{name: one, port: 1}
+		{name: two}
+	]
+}
+`
+
+func TestUpdate(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		change func(c *updateConfig)
+		doc    string
+	}{
+		{
+			name:   `unchanged`,
+			change: func(c *updateConfig) {},
+			doc:    updateDoc,
+		},
+		{
+			name: `scalars in place`,
+			change: func(c *updateConfig) {
+				c.Name = `beta gamma`
+				c.Tags[1] = `c`
+				c.Servers[0].Port = 2
+			},
+			doc: `// service config
+{
+	// the name
+	name: "beta gamma", // inline
+
+	debug: true
+
+	/* tags of the service */
+	tags: [a, c],
+	servers: [
+		{name: one, port: 2}
+		{name: two}
+	]
+}
+`,
+		},
+		{
+			name: `insert`,
+			change: func(c *updateConfig) {
+				c.Port = 8080
+				c.Tags = append(c.Tags, `x y`)
+				c.Servers = append(c.Servers, updateServer{Name: `three`})
+				c.Servers[1].Port = 3
+				c.Motd = "hello\nworld"
+			},
+			doc: "// service config\n{\n\t// the name\n\tname: \"alpha\", // inline\n\t// listen port\n\tport: 8080,\n\n\tdebug: true\n\n\t/* tags of the service */\n\ttags: [a, b, \"x y\"],\n\tservers: [\n\t\t{name: one, port: 1}\n\t\t{name: two, port: 3}\n\t\t{\n\t\t\tname: three\n\t\t}\n\t],\n\tmotd: `hello\nworld`\n}\n",
+		},
+		{
+			name: `remove`,
+			change: func(c *updateConfig) {
+				c.Debug = false
+				c.Tags = c.Tags[:1]
+				c.Servers = nil
+			},
+			doc: `// service config
+{
+	// the name
+	name: "alpha", // inline
+
+	/* tags of the service */
+	tags: [a]
+}
+`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		var c updateConfig
+		require.NoError(t, Unmarshal([]byte(updateDoc), &c), ts.name)
+		ts.change(&c)
+
+		out, err := Update([]byte(updateDoc), c)
+		require.NoError(t, err, ts.name)
+		assert.Equal(t, ts.doc, string(out), ts.name)
+
+		var back updateConfig
+		require.NoError(t, Unmarshal(out, &back), ts.name)
+		assert.Equal(t, c, back, ts.name)
+	}
+}
+
+func TestUpdateStyles(t *testing.T) {
+
+	tests := []struct {
+		doc   string
+		value interface{}
+		out   string
+	}{
+		{
+			doc:   `{"a": 1, "b": [1, 2]}`,
+			value: map[string]interface{}{`a`: 1, `b`: []int{1}, `c`: `x`},
+			out:   `{"a": 1, "b": [1], "c": "x"}`,
+		},
+		{
+			doc:   "{\n  \"a\": 1,\n  \"b\": 2\n}",
+			value: map[string]interface{}{`a`: 1},
+			out:   "{\n  \"a\": 1\n}",
+		},
+		{
+			doc:   "{\n  a: 1\n  b: 2\n}",
+			value: map[string]interface{}{`b`: 2, `c`: map[string]int{`d`: 1}},
+			out:   "{\n  b: 2\n  c: {\n    d: 1\n  }\n}",
+		},
+		{
+			doc:   "{\n  a: 1,\n  b: 2,\n}",
+			value: map[string]interface{}{`a`: 1, `b`: 2, `c`: 3},
+			out:   "{\n  a: 1,\n  b: 2,\n  c: 3,\n}",
+		},
+		{
+			doc:   `{db: {host: h}}`,
+			value: map[string]interface{}{`db`: map[string]int{`a`: 1}},
+			out:   `{db: {a: 1}}`,
+		},
+		{
+			doc:   `{o: { c: 1 }}`,
+			value: map[string]interface{}{`o`: map[string]string{`a`: `x`, `b`: `y z`}},
+			out:   `{o: { a: x, b: "y z" }}`,
+		},
+		{
+			doc:   `{a: 1}`,
+			value: map[string]interface{}{`a`: 1, `b`: 4},
+			out:   `{a: 1, b: 4}`,
+		},
+		{
+			doc:   `{a: 1 b: 2}`,
+			value: map[string]interface{}{`a`: 1, `b`: 2, `c`: 3},
+			out:   `{a: 1 b: 2 c: 3}`,
+		},
+		{
+			doc:   "{\n  a: {}\n  b: []\n}",
+			value: map[string]interface{}{`a`: map[string]int{`x`: 1}, `b`: []int{1, 2}},
+			out:   "{\n  a: {\n    x: 1\n  }\n  b: [1, 2]\n}",
+		},
+		{
+			doc:   "{\n  // first\n  a: 1\n\n  // detached\n\n  // attached\n  b: 2\n}",
+			value: map[string]interface{}{`a`: 1},
+			out:   "{\n  // first\n  a: 1\n\n  // detached\n}",
+		},
+		{
+			doc:   "{\n  a: 1.0\n  b: x\n  c: `old`\n}",
+			value: map[string]interface{}{`a`: 1, `b`: `y`, `c`: "new\ntext"},
+			out:   "{\n  a: 1.0\n  b: y\n  c: `new\ntext`\n}",
+		},
+		{
+			doc:   "{\n  a: [1]\n}",
+			value: map[string]interface{}{`a`: `x`},
+			out:   "{\n  a: x\n}",
+		},
+		{
+			doc:   `{a: 1, b: 2, c: 3}`,
+			value: map[string]interface{}{`b`: 2},
+			out:   `{b: 2}`,
+		},
+		{
+			doc:   `{a: 1, c: 3}`,
+			value: map[string]interface{}{`a`: 1, `b`: 2, `c`: 3},
+			out:   `{a: 1, b: 2, c: 3}`,
+		},
+		{
+			doc:   "{\n  a: 1, b: 2\n}",
+			value: map[string]interface{}{`b`: 2, `c`: 3},
+			out:   "{\n  b: 2,\n  c: 3\n}",
+		},
+		{
+			doc:   "{ // head\n  b: 2\n}",
+			value: map[string]interface{}{`a`: 1, `b`: 2},
+			out:   "{ // head\n  a: 1\n  b: 2\n}",
+		},
+		{
+			doc:   `[1, 2, 3]`,
+			value: []int{1},
+			out:   `[1]`,
+		},
+		{
+			doc:   `[ 8001 , 8002 ]`,
+			value: []int{8001},
+			out:   `[ 8001 ]`,
+		},
+		{
+			doc:   `{ports: [ 8001, 8001, 8002 ]}`,
+			value: map[string][]int{`ports`: {8001, 8001, 8002, 9000}},
+			out:   `{ports: [ 8001, 8001, 8002, 9000 ]}`,
+		},
+		{
+			doc:   `[1,2]`,
+			value: []int{1, 2, 3},
+			out:   `[1,2,3]`,
+		},
+		{
+			doc:   `{ a: 1, b: 2 }`,
+			value: map[string]int{`a`: 1, `c`: 3},
+			out:   `{ a: 1, c: 3 }`,
+		},
+		{
+			doc:   `// nothing yet`,
+			value: map[string]interface{}{`a`: 1},
+			out:   "// nothing yet\n{\n  a: 1\n}",
+		},
+	}
+
+	for _, ts := range tests {
+		out, err := Update([]byte(ts.doc), ts.value)
+		require.NoError(t, err, ts.doc)
+		assert.Equal(t, ts.out, string(out), ts.doc)
+	}
+}
+
+func TestUpdateUnknownMembers(t *testing.T) {
+
+	doc := "{\n  // kept\n  extra: {x: 1}\n  name: a\n  servers: [{name: s, weight: 2}]\n  Port: 1\n}"
+
+	var c updateConfig
+	require.NoError(t, Unmarshal([]byte(doc), &c))
+	c.Name = `b`
+	c.Port = 0
+
+	out, err := Update([]byte(doc), &c)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  // kept\n  extra: {x: 1}\n  name: b\n  tags: null\n  servers: [{name: s, weight: 2}]\n}", string(out))
+
+	// maps know all members
+	out, err = Update([]byte(`{a: 1, b: 2}`), map[string]interface{}{`a`: 1})
+	require.NoError(t, err)
+	assert.Equal(t, `{a: 1}`, string(out))
+}
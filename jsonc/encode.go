@@ -0,0 +1,751 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Marshal returns the jsonc encoding of v. Values are encoded like
+// json.Marshal does with these differences:
+//
+// Objects list one member per line without commas, keys and strings made of
+// letters and digits only are written without quotes. Strings spanning
+// several lines are written as backtick multiline strings. Arrays are written
+// on one line unless an element spans several lines. The result is stable
+// under Format.
+//
+// Members are named by the json tag, as encoding/json does. The jsonc struct
+// tag adds a line comment above the member, the comment takes the rest of the
+// tag:
+//
+//	Port int `json:"port" jsonc:",comment=listen port"`
+//
+// Scalars of fields with the ,string option of the json tag are written as
+// strings, as encoding/json does.
+//
+// A *Node is encoded as the value it holds, without its comments.
+//
+// Nested lines are indented by the string set with Space. With the JSON
+// dialect Marshal writes indented json: all keys and strings are quoted,
+// members and elements are separated by commas and comments are left out.
+func Marshal(v interface{}, opts ...Option) ([]byte, error) {
+
+	e := newEncodeState(newConfig(opts))
+	err := e.value(reflect.ValueOf(v), 0)
+	if err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// Encoder writes jsonc values to an output stream.
+type Encoder struct {
+	w      io.Writer
+	config config
+}
+
+// NewEncoder returns an Encoder writing to w. Of the options only Space and
+// WithDialect apply.
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	return &Encoder{w: w, config: newConfig(opts)}
+}
+
+// Encode writes the jsonc encoding of v followed by a line break, see Marshal.
+func (enc *Encoder) Encode(v interface{}) error {
+
+	e := newEncodeState(enc.config)
+	err := e.value(reflect.ValueOf(v), 0)
+	if err != nil {
+		return err
+	}
+	e.WriteByte('\n')
+
+	_, err = enc.w.Write(e.Bytes())
+	return err
+}
+
+// maxEncodeDepth stops the encoding of cyclic values.
+const maxEncodeDepth = 1000
+
+var (
+	nodeType          = reflect.TypeOf((*Node)(nil))
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+type encodeState struct {
+	bytes.Buffer
+	space   string
+	dialect Dialect
+}
+
+func newEncodeState(c config) *encodeState {
+	return &encodeState{space: c.space, dialect: c.dialect}
+}
+
+// member is an object member to encode.
+type member struct {
+	name    string
+	comment string
+	value   reflect.Value
+	quoted  bool // the ,string option
+}
+
+func (e *encodeState) value(v reflect.Value, depth int) error {
+
+	if !v.IsValid() {
+		e.WriteString(`null`)
+		return nil
+	}
+
+	if depth > maxEncodeDepth {
+		return fmt.Errorf("jsonc: encoding exceeds the maximum depth of %v", maxEncodeDepth)
+	}
+
+	if v.Type() == nodeType && v.CanInterface() {
+		return e.node(v.Interface().(*Node), depth)
+	}
+
+	if v.Kind() != reflect.Ptr && v.CanAddr() && v.Addr().CanInterface() {
+		pt := reflect.PtrTo(v.Type())
+		if pt.Implements(marshalerType) || pt.Implements(textMarshalerType) {
+			v = v.Addr()
+		}
+	}
+
+	if v.CanInterface() && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+
+		switch m := v.Interface().(type) {
+		case json.Marshaler:
+			return e.marshaler(m)
+
+		case encoding.TextMarshaler:
+			text, err := m.MarshalText()
+			if err != nil {
+				return &json.MarshalerError{Type: v.Type(), Err: err}
+			}
+			e.string(string(text))
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		e.WriteString(strconv.FormatBool(v.Bool()))
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.WriteString(strconv.FormatInt(v.Int(), 10))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.WriteString(strconv.FormatUint(v.Uint(), 10))
+
+	case reflect.Float32, reflect.Float64:
+		var f interface{} = v.Float()
+		if v.Kind() == reflect.Float32 {
+			f = float32(v.Float())
+		}
+
+		b, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		e.Write(b)
+
+	case reflect.String:
+		e.string(v.String())
+
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			e.WriteString(`null`)
+			return nil
+		}
+		return e.value(v.Elem(), depth)
+
+	case reflect.Struct:
+		return e.members(structMembers(v), depth)
+
+	case reflect.Map:
+		if v.IsNil() {
+			e.WriteString(`null`)
+			return nil
+		}
+
+		ms, err := mapMembers(v)
+		if err != nil {
+			return err
+		}
+		return e.members(ms, depth)
+
+	case reflect.Slice:
+		if v.IsNil() {
+			e.WriteString(`null`)
+			return nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := json.Marshal(v.Bytes())
+			if err != nil {
+				return err
+			}
+			e.Write(b)
+			return nil
+		}
+		return e.array(v, depth)
+
+	case reflect.Array:
+		return e.array(v, depth)
+
+	default:
+		return &json.UnsupportedTypeError{Type: v.Type()}
+	}
+	return nil
+}
+
+// node writes the value of the syntax tree n, its comments are dropped.
+func (e *encodeState) node(n *Node, depth int) error {
+
+	if n == nil {
+		e.WriteString(`null`)
+		return nil
+	}
+
+	switch n.Kind {
+	case DocumentNode, MemberNode:
+		return e.node(n.Value(), depth)
+
+	case ObjectNode:
+		var ms []member
+		for _, m := range n.Members() {
+			ms = append(ms, member{name: m.Name(), value: reflect.ValueOf(m.Value())})
+		}
+		return e.members(ms, depth)
+
+	case ArrayNode:
+		return e.array(reflect.ValueOf(n.Elements()), depth)
+
+	case StringNode:
+		data, err := n.JSON()
+		if err != nil {
+			return err
+		}
+
+		var s string
+		err = json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+		e.string(s)
+		return nil
+
+	case NumberNode, BoolNode, NullNode:
+		e.WriteString(n.Text)
+		return nil
+	}
+	return fmt.Errorf("%v is not a value", n.Kind)
+}
+
+// marshaler writes the json of m as formatted by Format.
+func (e *encodeState) marshaler(m json.Marshaler) error {
+
+	b, err := m.MarshalJSON()
+	if err != nil {
+		return &json.MarshalerError{Type: reflect.TypeOf(m), Err: err}
+	}
+
+	compact := &bytes.Buffer{}
+	err = json.Compact(compact, b)
+	if err != nil {
+		return &json.MarshalerError{Type: reflect.TypeOf(m), Err: err}
+	}
+
+	b, err = Format(compact.Bytes(), Space(e.space), WithDialect(e.dialect))
+	if err != nil {
+		return &json.MarshalerError{Type: reflect.TypeOf(m), Err: err}
+	}
+	e.Write(b)
+	return nil
+}
+
+func (e *encodeState) members(ms []member, depth int) error {
+
+	if len(ms) == 0 {
+		e.WriteString(`{}`)
+		return nil
+	}
+
+	e.WriteString("{\n")
+	for idx, m := range ms {
+
+		if m.comment != `` && e.dialect != JSON {
+			for _, line := range strings.Split(m.comment, "\n") {
+				e.indent(depth + 1)
+				e.WriteString(strings.TrimRight(`// `+line, ` `))
+				e.WriteByte('\n')
+			}
+		}
+
+		e.indent(depth + 1)
+		e.key(m.name)
+		e.WriteString(`: `)
+
+		var err error
+		if m.quoted {
+			err = e.quoted(m.value, depth+1)
+		} else {
+			err = e.value(m.value, depth+1)
+		}
+		if err != nil {
+			return err
+		}
+
+		if e.dialect == JSON && idx < len(ms)-1 {
+			e.WriteByte(',')
+		}
+		e.WriteByte('\n')
+	}
+
+	e.indent(depth)
+	e.WriteByte('}')
+	return nil
+}
+
+// quoted writes the json of the scalar v as string.
+func (e *encodeState) quoted(v reflect.Value, depth int) error {
+
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		e.WriteString(`null`)
+		return nil
+	}
+
+	scalar := &encodeState{dialect: JSON}
+	err := scalar.value(v, depth)
+	if err != nil {
+		return err
+	}
+	e.string(scalar.String())
+	return nil
+}
+
+// array writes arrays on one line, or one element per line if an element
+// spans several lines.
+func (e *encodeState) array(v reflect.Value, depth int) error {
+
+	if v.Len() == 0 {
+		e.WriteString(`[]`)
+		return nil
+	}
+
+	inline := true
+	elems := make([][]byte, v.Len())
+	for i := range elems {
+
+		elem := &encodeState{space: e.space, dialect: e.dialect}
+		err := elem.value(v.Index(i), depth+1)
+		if err != nil {
+			return err
+		}
+
+		b := elem.Bytes()
+		if bytes.IndexByte(b, '\n') >= 0 {
+			inline = false
+		}
+		elems[i] = b
+	}
+
+	if inline {
+		e.WriteByte('[')
+		e.Write(bytes.Join(elems, []byte(`,`)))
+		e.WriteByte(']')
+		return nil
+	}
+
+	e.WriteString("[\n")
+	for idx, b := range elems {
+		e.indent(depth + 1)
+		e.Write(b)
+
+		if e.dialect == JSON && idx < len(elems)-1 {
+			e.WriteByte(',')
+		}
+		e.WriteByte('\n')
+	}
+
+	e.indent(depth)
+	e.WriteByte(']')
+	return nil
+}
+
+func (e *encodeState) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		e.WriteString(e.space)
+	}
+}
+
+func (e *encodeState) key(name string) {
+
+	if e.dialect != JSON && isBareKey(name) {
+		e.WriteString(name)
+		return
+	}
+	e.Write(appendQuoted(nil, name))
+}
+
+func (e *encodeState) string(s string) {
+
+	switch {
+	case e.dialect == JSON:
+		e.Write(appendQuoted(nil, s))
+
+	case isBareValue(s):
+		e.WriteString(s)
+
+	case strings.Contains(s, "\n") && isMultiline(s):
+		e.WriteByte('`')
+		e.WriteString(s)
+		e.WriteByte('`')
+
+	default:
+		e.Write(appendQuoted(nil, s))
+	}
+}
+
+// isBareKey reports whether name can be written as a key without quotes.
+func isBareKey(name string) bool {
+
+	if name == `` {
+		return false
+	}
+
+	for _, ru := range name {
+		if !unicode.IsLetter(ru) && !unicode.IsDigit(ru) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBareValue reports whether s can be written as a string value without
+// quotes, which does not turn it into a number, boolean or null. Infinity and
+// NaN are numbers of JSON5.
+func isBareValue(s string) bool {
+
+	if s == `` || IsNumber(s) || s == `true` || s == `false` || s == `null` || s == `Infinity` || s == `NaN` {
+		return false
+	}
+
+	for i, ru := range s {
+		if i == 0 && !unicode.IsLetter(ru) {
+			return false
+		}
+
+		if !unicode.IsLetter(ru) && !unicode.IsDigit(ru) && ru != '.' && ru != '+' {
+			return false
+		}
+	}
+	return true
+}
+
+// isMultiline reports whether s is read back unchanged from a multiline
+// string. The Filter drops control characters and turns spaces into ' '.
+func isMultiline(s string) bool {
+
+	for _, ru := range s {
+		switch {
+		case ru == '\n':
+		case ru == '`' || ru == '\\' || ru == utf8.RuneError:
+			return false
+		case unicode.IsControl(ru):
+			return false
+		case unicode.IsSpace(ru) && ru != ' ':
+			return false
+		}
+	}
+	return true
+}
+
+// appendQuoted appends s as a json string. Besides the characters json
+// requires it escapes all spaces other than ' ' which the Filter would
+// replace.
+func appendQuoted(buf []byte, s string) []byte {
+
+	buf = append(buf, '"')
+	for _, ru := range s {
+		switch {
+		case ru == '"' || ru == '\\':
+			buf = append(buf, '\\', byte(ru))
+		case ru == '\n':
+			buf = append(buf, '\\', 'n')
+		case ru == '\r':
+			buf = append(buf, '\\', 'r')
+		case ru == '\t':
+			buf = append(buf, '\\', 't')
+		case unicode.IsControl(ru) || (unicode.IsSpace(ru) && ru != ' '):
+			buf = append(buf, fmt.Sprintf(`\u%04x`, ru)...)
+		default:
+			buf = append(buf, string(ru)...)
+		}
+	}
+	return append(buf, '"')
+}
+
+// Field is a struct field as encoded by Marshal.
+type Field struct {
+	Name      string // the member name
+	Index     []int  // the index sequence for reflect.Type.FieldByIndex
+	Type      reflect.Type
+	OmitEmpty bool
+	Quoted    bool   // the ,string option applies to the type
+	Comment   string // the comment of the jsonc tag
+	tagged    bool
+}
+
+// structMembers returns the members of the struct v.
+func structMembers(v reflect.Value) []member {
+
+	var ms []member
+	for _, f := range Fields(v.Type()) {
+
+		fv, ok := fieldByIndex(v, f.Index)
+		if !ok || (f.OmitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		ms = append(ms, member{name: f.Name, comment: f.Comment, value: fv, quoted: f.Quoted})
+	}
+	return ms
+}
+
+// fieldByIndex returns the field of v at index, it fails for fields of nil
+// embedded pointers.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// Fields returns the fields of the struct type t in the order Marshal writes
+// them. As in encoding/json the fields of embedded structs without a name are
+// promoted, of fields with the same name the least nested one wins.
+func Fields(t reflect.Type) []Field {
+
+	var fields []Field
+	var walk func(t reflect.Type, index []int)
+
+	walk = func(t reflect.Type, index []int) {
+
+		for i := 0; i < t.NumField(); i++ {
+
+			sf := t.Field(i)
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if sf.PkgPath != `` && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+				continue
+			}
+
+			tag := ParseTag(sf.Tag)
+			if tag.Skip {
+				continue
+			}
+
+			f := Field{
+				Name:      tag.Name,
+				Index:     append(append([]int{}, index...), i),
+				Type:      sf.Type,
+				OmitEmpty: tag.OmitEmpty,
+				Quoted:    tag.String && isScalarType(sf.Type),
+				Comment:   tag.Comment,
+				tagged:    tag.Name != ``,
+			}
+
+			if sf.Anonymous && !f.tagged && ft.Kind() == reflect.Struct {
+				walk(ft, f.Index)
+				continue
+			}
+
+			if sf.PkgPath != `` {
+				continue
+			}
+
+			if f.Name == `` {
+				f.Name = sf.Name
+			}
+			fields = append(fields, f)
+		}
+	}
+	walk(t, nil)
+
+	// keep the dominant field of each name in the order of declaration
+	var result []Field
+	for i, f := range fields {
+
+		dominant := true
+		for j, o := range fields {
+			if i == j || o.Name != f.Name {
+				continue
+			}
+
+			if len(o.Index) < len(f.Index) ||
+				(len(o.Index) == len(f.Index) && (o.tagged || !f.tagged)) {
+				dominant = false
+				break
+			}
+		}
+
+		if dominant {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// FieldTag holds the json and jsonc struct tags of a field.
+type FieldTag struct {
+	Name      string // the name of the json tag
+	Skip      bool   // the json tag is "-"
+	OmitEmpty bool
+	String    bool   // the ,string option
+	Comment   string // the comment of the jsonc tag
+}
+
+// ParseTag reads the json and jsonc tags of a struct field as Marshal does.
+// The jsonc tag adds options only, the name is taken from the json tag as
+// decoding knows no other.
+func ParseTag(tag reflect.StructTag) FieldTag {
+
+	jsonTag := tag.Get(`json`)
+	if jsonTag == `-` {
+		return FieldTag{Skip: true}
+	}
+
+	var t FieldTag
+	name, opts := splitTag(jsonTag)
+	t.Name = name
+	t.OmitEmpty = strings.Contains(`,`+opts+`,`, `,omitempty,`)
+	t.String = strings.Contains(`,`+opts+`,`, `,string,`)
+
+	_, opts = splitTag(tag.Get(`jsonc`))
+	for opts != `` {
+
+		// the comment takes the rest of the tag
+		if strings.HasPrefix(opts, `comment=`) {
+			t.Comment = strings.TrimPrefix(opts, `comment=`)
+			break
+		}
+
+		var opt string
+		opt, opts = splitTag(opts)
+		if opt == `omitempty` {
+			t.OmitEmpty = true
+		}
+	}
+	return t
+}
+
+func splitTag(tag string) (name string, opts string) {
+
+	if idx := strings.IndexByte(tag, ','); idx >= 0 {
+		return tag[:idx], tag[idx+1:]
+	}
+	return tag, ``
+}
+
+// isScalarType reports whether the ,string option applies to values of t.
+func isScalarType(t reflect.Type) bool {
+
+	if t.Name() == `` && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// mapMembers returns the members of the map v sorted by key.
+func mapMembers(v reflect.Value) ([]member, error) {
+
+	var ms []member
+	iter := v.MapRange()
+	for iter.Next() {
+
+		name, err := mapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, member{name: name, value: iter.Value()})
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].name < ms[j].name
+	})
+	return ms, nil
+}
+
+func mapKey(k reflect.Value) (string, error) {
+
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return ``, nil
+		}
+
+		text, err := tm.MarshalText()
+		if err != nil {
+			return ``, &json.MarshalerError{Type: k.Type(), Err: err}
+		}
+		return string(text), nil
+	}
+
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return ``, &json.UnsupportedTypeError{Type: k.Type()}
+}
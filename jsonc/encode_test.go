@@ -0,0 +1,228 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type encodeServer struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+type encodeBase struct {
+	Version int `json:"version"`
+}
+
+type encodeConfig struct {
+	encodeBase
+	Host    string                  `json:"host" jsonc:",comment=host name, without port"`
+	Port    int                     `json:"port" jsonc:",comment=listen port"`
+	Debug   bool                    `json:"debug,omitempty"`
+	Motd    string                  `json:"motd" jsonc:",comment=message of the day\nshown on login"`
+	Ports   []int                   `json:"ports"`
+	Servers []encodeServer          `json:"servers"`
+	Labels  map[string]string       `json:"labels"`
+	Extra   map[string]interface{}  `json:"extra,omitempty"`
+	Raw     json.RawMessage         `json:"raw"`
+	Next    *encodeConfig           `json:"next"`
+	Secret  string                  `json:"-"`
+	Empty   struct{}                `json:"empty"`
+	Matrix  [][]float64             `json:"matrix"`
+	Nested  map[string]encodeServer `json:"nested,omitempty"`
+}
+
+func testEncodeConfig() encodeConfig {
+	return encodeConfig{
+		encodeBase: encodeBase{Version: 2},
+		Host:       `example.com`,
+		Port:       8080,
+		Motd:       "Welcome \"home\"\n  enjoy",
+		Ports:      []int{80, 443},
+		Servers:    []encodeServer{{Name: `alpha`, IP: `10.0.0.1`}, {Name: `beta two`}},
+		Labels:     map[string]string{`b`: `true`, `a`: `x1`, `with space`: ``},
+		Raw:        json.RawMessage(`{"a":[1, 2]}`),
+		Secret:     `hidden`,
+		Matrix:     [][]float64{{1.5, 2}, {}},
+	}
+}
+
+const testEncodeConfigJSONC = "{\n" +
+	"  version: 2\n" +
+	"  // host name, without port\n" +
+	"  host: example.com\n" +
+	"  // listen port\n" +
+	"  port: 8080\n" +
+	"  // message of the day\n" +
+	"  // shown on login\n" +
+	"  motd: `Welcome \"home\"\n  enjoy`\n" +
+	"  ports: [80,443]\n" +
+	"  servers: [\n" +
+	"    {\n" +
+	"      name: alpha\n" +
+	"      ip: \"10.0.0.1\"\n" +
+	"    }\n" +
+	"    {\n" +
+	"      name: \"beta two\"\n" +
+	"      ip: \"\"\n" +
+	"    }\n" +
+	"  ]\n" +
+	"  labels: {\n" +
+	"    a: x1\n" +
+	"    b: \"true\"\n" +
+	"    \"with space\": \"\"\n" +
+	"  }\n" +
+	"  raw: {\"a\": [1,2]}\n" +
+	"  next: null\n" +
+	"  empty: {}\n" +
+	"  matrix: [[1.5,2],[]]\n" +
+	"}"
+
+func TestMarshal(t *testing.T) {
+
+	out, err := Marshal(testEncodeConfig())
+	require.NoError(t, err)
+	assert.Equal(t, testEncodeConfigJSONC, string(out))
+
+	// the output is stable under the formatter
+	formatted, err := Format(out)
+	require.NoError(t, err)
+	assert.Equal(t, string(out), string(formatted))
+
+	// and decodes to the same value
+	var c encodeConfig
+	require.NoError(t, Unmarshal(out, &c))
+
+	expected := testEncodeConfig()
+	expected.Secret = ``
+	expected.Raw = json.RawMessage(`{"a":[1,2]}`)
+	assert.Equal(t, expected, c)
+}
+
+func TestMarshalValues(t *testing.T) {
+
+	tests := []struct {
+		value interface{}
+		jsonc string
+	}{
+		{value: nil, jsonc: `null`},
+		{value: `null`, jsonc: `"null"`},
+		{value: `12`, jsonc: `"12"`},
+		{value: `v1.2`, jsonc: `v1.2`},
+		{value: "tab\there", jsonc: `"tab\there"`},
+		{value: "a`b\nc", jsonc: `"a` + "`" + `b\nc"`},
+		{value: []byte(`hi`), jsonc: `"aGk="`},
+		{value: float32(0.1), jsonc: `0.1`},
+		{value: map[int]bool{2: true, 1: false}, jsonc: "{\n  1: false\n  2: true\n}"},
+		{value: []interface{}{`a`, 1, nil, map[string]int{}}, jsonc: `[a,1,null,{}]`},
+	}
+
+	for _, ts := range tests {
+		out, err := Marshal(ts.value)
+		require.NoError(t, err)
+		assert.Equal(t, ts.jsonc, string(out))
+	}
+
+	_, err := Marshal(map[string]interface{}{`f`: func() {}})
+	assert.Error(t, err)
+}
+
+func TestEncoder(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, Space("\t"))
+
+	require.NoError(t, enc.Encode(encodeServer{Name: `alpha`}))
+	require.NoError(t, enc.Encode([]int{1}))
+	assert.Equal(t, "{\n\tname: alpha\n\tip: \"\"\n}\n[1]\n", buf.String())
+
+	dec, err := NewDecoder(buf)
+	require.NoError(t, err)
+
+	var s encodeServer
+	require.NoError(t, dec.Decode(&s))
+	assert.Equal(t, `alpha`, s.Name)
+}
+
+func TestMarshalStringOption(t *testing.T) {
+
+	type service struct {
+		Port  int      `json:"port,string"`
+		Ratio *float64 `json:"ratio,string"`
+		Debug bool     `json:"debug,string"`
+		Name  string   `json:"name,string"`
+		Tags  []string `json:"tags,string"`
+		None  *int     `json:"none,string"`
+	}
+
+	ratio := 0.5
+	v := service{Port: 8080, Ratio: &ratio, Debug: true, Name: `a b`, Tags: []string{`x`}}
+
+	out, err := Marshal(v)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  port: \"8080\"\n  ratio: \"0.5\"\n  debug: \"true\"\n  name: \"\\\"a b\\\"\"\n  tags: [x]\n  none: null\n}", string(out))
+
+	expected, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	converted, err := ToJSON(out)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(converted))
+
+	var back service
+	require.NoError(t, Unmarshal(out, &back))
+	assert.Equal(t, v, back)
+}
+
+func TestMarshalJSONCTagName(t *testing.T) {
+
+	type service struct {
+		Renamed int `jsonc:"other,comment=kept"`
+		Port    int `json:"port" jsonc:"listen"`
+	}
+
+	out, err := Marshal(service{Renamed: 1, Port: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  // kept\n  Renamed: 1\n  port: 2\n}", string(out))
+
+	var back service
+	require.NoError(t, Unmarshal(out, &back))
+	assert.Equal(t, service{Renamed: 1, Port: 2}, back)
+}
+
+func TestFields(t *testing.T) {
+
+	type inner struct {
+		Port int `json:"port,string"`
+		Host string
+	}
+
+	type outer struct {
+		*inner
+		Host string `json:"host,omitempty" jsonc:"x,comment=the host, or empty"`
+		Skip int    `json:"-"`
+		hide int
+	}
+
+	fields := Fields(reflect.TypeOf(outer{}))
+	require.Len(t, fields, 3)
+
+	assert.Equal(t, `port`, fields[0].Name)
+	assert.Equal(t, []int{0, 0}, fields[0].Index)
+	assert.True(t, fields[0].Quoted)
+
+	assert.Equal(t, `Host`, fields[1].Name)
+	assert.Equal(t, []int{0, 1}, fields[1].Index)
+
+	assert.Equal(t, `host`, fields[2].Name)
+	assert.True(t, fields[2].OmitEmpty)
+	assert.Equal(t, `the host, or empty`, fields[2].Comment)
+
+	assert.Equal(t, FieldTag{Skip: true}, ParseTag(`json:"-" jsonc:",comment=x"`))
+	assert.Equal(t, FieldTag{OmitEmpty: true}, ParseTag(`jsonc:"name,omitempty"`))
+}
@@ -2,19 +2,55 @@ package jsonc
 
 import "fmt"
 
+// Error is a syntax error found in a jsonc document.
 type Error struct {
 	err      string
 	position int
+	pos      Pos
+	token    string
 }
 
 func (e Error) Error() string {
+	if e.pos.Line > 0 {
+		return fmt.Sprintf("line: %v col: %v %v", e.pos.Line, e.pos.Column, e.err)
+	}
 	return e.err
 }
 
+// Position returns the rune index of the error.
 func (e Error) Position() int {
 	return e.position
 }
 
+// Pos returns the location of the error.
+func (e Error) Pos() Pos {
+	return e.pos
+}
+
+// Line returns the line of the error starting at 1, or 0 if unknown.
+func (e Error) Line() int {
+	return e.pos.Line
+}
+
+// Column returns the column of the error in runes starting at 1, or 0 if
+// unknown.
+func (e Error) Column() int {
+	return e.pos.Column
+}
+
+// Offset returns the byte offset of the error.
+func (e Error) Offset() int {
+	return e.pos.Offset
+}
+
+// Token returns the offending token.
+func (e Error) Token() string {
+	return e.token
+}
+
+// Errorf returns an Error at the rune index position. It carries no line
+// information, errors found by a Filter are created with the location of the
+// offending token.
 func Errorf(formatter string, position int, args ...interface{}) Error {
 
 	if position > 0 {
@@ -25,5 +61,14 @@ func Errorf(formatter string, position int, args ...interface{}) Error {
 		return Error{err: fmt.Sprintf("pos: %v "+formatter, jargs...), position: position}
 	}
 
-	return Error{err: fmt.Sprintf(formatter, args), position: position}
+	return Error{err: fmt.Sprintf(formatter, args...), position: position}
+}
+
+func newError(pos Pos, position int, token string, formatter string, args ...interface{}) Error {
+	return Error{
+		err:      fmt.Sprintf(formatter, args...),
+		position: position,
+		pos:      pos,
+		token:    token,
+	}
 }
@@ -0,0 +1,50 @@
+package jsonc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorLocation(t *testing.T) {
+
+	tests := []struct {
+		jsonc  string
+		line   int
+		column int
+		offset int
+		token  string
+		msg    string
+	}{
+		{jsonc: `x`, line: 1, column: 1, offset: 0, token: `x`, msg: `invalid first character`},
+		{jsonc: "{\n  a: \"b\nc\"}", line: 2, column: 8, offset: 9, token: "\n", msg: `line break in string value`},
+		{jsonc: "{\n a: `x\\y`}", line: 2, column: 7, offset: 8, token: `\`, msg: `character \ found in multiline string`},
+		{jsonc: "[\n 1,\n 2,,\n]", line: 3, column: 4, offset: 9, token: `,`, msg: `empty no quote state`},
+		{jsonc: "{\n  ä: 1ab\n}", line: 2, column: 6, offset: 8, token: `1ab`, msg: `invalid identifier`},
+		{jsonc: `{a b}`, line: 1, column: 4, offset: 3, token: `b`, msg: `error parsing object rune`},
+		{jsonc: `{-: b}`, line: 1, column: 2, offset: 1, token: `-`, msg: `invalid key`},
+		{jsonc: `["a""b"]`, line: 1, column: 5, offset: 4, token: `"`, msg: `invalid character after value`},
+	}
+
+	for _, ts := range tests {
+
+		f, err := New(strings.NewReader(ts.jsonc), true, ``)
+		require.NoError(t, err)
+
+		_, err = (&bytes.Buffer{}).ReadFrom(f)
+		require.Error(t, err, ts.jsonc)
+
+		var jerr Error
+		require.True(t, errors.As(err, &jerr), ts.jsonc)
+
+		assert.Equal(t, ts.line, jerr.Line(), ts.jsonc)
+		assert.Equal(t, ts.column, jerr.Column(), ts.jsonc)
+		assert.Equal(t, ts.offset, jerr.Offset(), ts.jsonc)
+		assert.Equal(t, ts.token, jerr.Token(), ts.jsonc)
+		assert.Contains(t, jerr.Error(), ts.msg, ts.jsonc)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -43,33 +44,102 @@ type Filter struct {
 
 	outbuf  []byte
 	lastOut rune
+	read    int
+
+	tokens tokenHandler
+	srcmap *sourceMap
+
+	// multi accepts a stream of top level values, in the minified output
+	// they are separated by line breaks.
+	multi bool
+
+	// recover collects syntax errors in errs and resynchronizes instead of
+	// stopping at the first error.
+	recover    bool
+	errs       []Error
+	lastResync int
+
+	dialect  Dialect
+	maxDepth int
+	hash     bool // # starts line comments
+
+	// interp expands the placeholders of values. The output of a quoted
+	// string is held back from hold on until it is expanded at its end.
+	// Formatting keeps the placeholders, bare ones are accepted though.
+	placeholders bool
+	interp       *interpolator
+	holding      bool
+	hold         int
+	dollars      []Pos // the locations of the $ signs of the value
+	valueStart   Pos
 }
 
-func NewFilter(ring *Ring, outMinSize int, format bool, space string) *Filter {
-	return &Filter{
+// tokenHandler receives the begin and end locations of the tokens read by a
+// Filter. Scalars are reported as StringNode, containers as ObjectNode and
+// ArrayNode including their braces.
+type tokenHandler interface {
+	begin(kind NodeKind, pos Pos) error
+	end(kind NodeKind, pos Pos) error
+}
+
+// NewFilter creates a Filter reading from ring. Of the options only MaxDepth,
+// WithDialect, HashComments, Interpolate and References apply, the buffer
+// sizes are given by ring and outMinSize, which is at least 1. Formatted
+// output is indented by space per nesting level.
+func NewFilter(ring *Ring, outMinSize int, format bool, space string, opts ...Option) *Filter {
+
+	if outMinSize < 1 {
+		outMinSize = 1
+	}
+
+	f := &Filter{
 		ring:       ring,
 		outMinSize: outMinSize,
 		rootState:  &RootState{},
 		format:     format,
 		space:      space,
 		lastOut:    utf8.RuneError,
+		lastResync: -1,
+	}
+	f.configure(newConfig(opts))
+	return f
+}
+
+func (f *Filter) configure(c config) {
+	f.dialect = c.dialect
+	f.maxDepth = c.maxDepth
+	f.hash = c.hash && f.dialect != JSON
+
+	f.placeholders = (c.interpolate || c.references) && f.dialect != JSON
+	if c.interpolate && !f.format {
+		f.interp = newInterpolator(c.resolvers, c.references)
 	}
 }
 
 func (f *Filter) Clear() {
 	f.rootState.init = false
 	f.outbuf = nil
+	f.read = 0
 	f.stack = nil
 	f.done = false
 	f.err = nil
 	f.lastOut = utf8.RuneError
+	f.errs = nil
+	f.lastResync = -1
+	f.holding = false
 }
 
 func (f *Filter) Done() bool {
 	return f.done
 }
 
+// Err returns the error which stopped the filter, io.EOF at the end of the
+// input. An input ending within a value is an unexpected end of input.
 func (f *Filter) Err() error {
+
+	if f.err == io.EOF && !f.done {
+		return f.unexpectedEOF()
+	}
 	return f.err
 }
 
@@ -99,46 +169,26 @@ func (r *RootState) Next(ru rune, f *Filter) error {
 		return ErrDontAdvance
 	}
 
-	if !r.init {
-		switch ru {
-		case '{':
-			r.init = true
-			f.pushOut(ru)
-			f.pushState(&ObjectState{})
-			return nil
-
-		case '[':
-			r.init = true
-			f.pushOut(ru)
-			f.pushState(&ArrayState{})
-			return nil
-
-		case '"':
-			r.init = true
-			f.pushOut(ru)
-			f.pushState(&ValueState{})
-			return nil
-
-		case '`':
-			r.init = true
-			if f.format {
-				f.pushOut(ru)
-			} else {
-				f.pushOut('"')
+	if !r.init || f.multi {
+		switch {
+		case ru == '{' || ru == '[' || ru == '"' || ru == '`' || (ru == '\'' && f.dialect == JSON5):
+			if r.init && !f.format {
+				f.pushOut('\n')
 			}
-			f.pushState(&ValueMultilineState{})
-			return nil
+			r.init = true
+			return f.pushValue(ru)
 		}
 	}
 
 	if !unicode.IsSpace(ru) {
-		return Errorf("invalid first character: %v", -1, string(ru))
+		return f.errorf(string(ru), "invalid first character: %v", string(ru))
 	}
 	return nil
 }
 
 type KeyState struct {
 	escaped bool
+	json5   *json5String
 }
 
 func (o *KeyState) Type() TokenType {
@@ -147,11 +197,21 @@ func (o *KeyState) Type() TokenType {
 
 func (k *KeyState) Next(ru rune, f *Filter) error {
 
+	if k.json5 != nil {
+		closed, err := k.json5.next(ru, f)
+		if err != nil || !closed {
+			return err
+		}
+
+		f.popState()
+		return f.end(KeyNode, f.ring.EndPos())
+	}
+
 	if !k.escaped && ru == '"' {
 
 		f.pushOut(ru)
 		f.popState()
-		return nil
+		return f.end(KeyNode, f.ring.EndPos())
 	}
 
 	k.escaped = false
@@ -165,6 +225,7 @@ func (k *KeyState) Next(ru rune, f *Filter) error {
 
 type ValueState struct {
 	escaped bool
+	json5   *json5String
 }
 
 func (v *ValueState) Type() TokenType {
@@ -173,16 +234,27 @@ func (v *ValueState) Type() TokenType {
 
 func (v *ValueState) Next(ru rune, f *Filter) error {
 
+	if v.json5 != nil {
+		closed, err := v.json5.next(ru, f)
+		if err != nil || !closed {
+			return err
+		}
+
+		f.popState()
+		return f.end(StringNode, f.ring.EndPos())
+	}
+
 	if !v.escaped && ru == '\n' {
-		return fmt.Errorf(`line break in string value`)
+		return f.errorf(string(ru), `line break in string value`)
 	}
 
 	if !v.escaped && ru == '"' {
 		f.pushOut(ru)
 		f.popState()
-		return nil
+		return f.end(StringNode, f.ring.EndPos())
 	}
 
+	f.dollar(ru)
 	v.escaped = false
 	if ru == '\\' {
 		v.escaped = true
@@ -192,6 +264,88 @@ func (v *ValueState) Next(ru rune, f *Filter) error {
 	return nil
 }
 
+// json5String translates a JSON5 string, which may be quoted by ' and hold
+// escapes unknown to json, to a json string.
+type json5String struct {
+	quote   rune
+	escaped bool
+	cr      bool // a line continuation ended by \r, skip a following \n
+	hex     int  // the hex digits still expected by an escape
+}
+
+// next writes ru and reports whether it closed the string.
+func (s *json5String) next(ru rune, f *Filter) (bool, error) {
+
+	if s.cr {
+		s.cr = false
+		if ru == '\n' {
+			return false, nil
+		}
+	}
+
+	if s.hex > 0 {
+		if !isHexDigit(ru) {
+			return false, f.errorf(string(ru), `invalid escape`)
+		}
+		s.hex--
+		f.pushOut(ru)
+		return false, nil
+	}
+
+	if s.escaped {
+		s.escaped = false
+		switch {
+		case ru == '\n' || ru == '\u2028' || ru == '\u2029':
+		case ru == '\r':
+			s.cr = true
+		case ru == '\'':
+			f.pushOut(ru)
+		case ru == 'v':
+			f.pushRunes([]rune(`\u000b`))
+		case ru == '0':
+			f.pushRunes([]rune(`\u0000`))
+		case ru == 'x':
+			f.pushRunes([]rune(`\u00`))
+			s.hex = 2
+		case ru == 'u':
+			f.pushRunes([]rune(`\u`))
+			s.hex = 4
+		case strings.ContainsRune(`"\/bfnrt`, ru):
+			f.pushOut('\\')
+			f.pushOut(ru)
+		case ru < ' ':
+			f.pushRunes([]rune(fmt.Sprintf(`\u%04x`, ru)))
+		default:
+			f.pushOut(ru)
+		}
+		return false, nil
+	}
+
+	switch ru {
+	case s.quote:
+		f.pushOut('"')
+		return true, nil
+
+	case '\n':
+		return false, f.errorf(string(ru), `line break in string value`)
+
+	case '\\':
+		s.escaped = true
+		return false, nil
+
+	case '"':
+		f.pushOut('\\')
+	}
+
+	f.dollar(ru)
+	f.pushOut(ru)
+	return false, nil
+}
+
+func isHexDigit(ru rune) bool {
+	return (ru >= '0' && ru <= '9') || (ru >= 'a' && ru <= 'f') || (ru >= 'A' && ru <= 'F')
+}
+
 type KeyNoQuoteState struct {
 	notFirst bool
 }
@@ -203,8 +357,8 @@ func (o *KeyNoQuoteState) Type() TokenType {
 func (k *KeyNoQuoteState) Next(ru rune, f *Filter) error {
 
 	if !k.notFirst {
-		if !unicode.IsLetter(ru) && !unicode.IsDigit(ru) {
-			return Errorf("invalid key", f.ring.Position())
+		if !f.keyRune(ru) {
+			return f.errorf(string(ru), "invalid key")
 		}
 	}
 
@@ -215,25 +369,38 @@ func (k *KeyNoQuoteState) Next(ru rune, f *Filter) error {
 	}
 	k.notFirst = true
 
-	if ru == ':' || ru == '/' || unicode.IsSpace(ru) {
+	if ru == ':' || ru == '/' || (ru == '#' && f.hash) || unicode.IsSpace(ru) {
 
 		if !f.format {
 			f.pushOut('"')
 		}
 		f.popState()
+
+		err := f.end(KeyNode, f.ring.Pos())
+		if err != nil {
+			return err
+		}
 		return ErrDontAdvance
 	}
 
-	if !unicode.IsLetter(ru) && !unicode.IsDigit(ru) {
-		return Errorf("invalid key", f.ring.Position())
+	if !f.keyRune(ru) {
+		return f.errorf(string(ru), "invalid key")
 	}
 
 	f.pushOut(ru)
 	return nil
 }
 
+// keyRune reports whether ru may be part of an unquoted key.
+func (f *Filter) keyRune(ru rune) bool {
+	return unicode.IsLetter(ru) || unicode.IsDigit(ru) || (f.dialect == JSON5 && (ru == '$' || ru == '_'))
+}
+
 type ValueNoQuoteState struct {
-	cval []rune
+	cval        []rune
+	start       Pos
+	placeholder bool // inside ${ } while interpolating
+	minus       bool // a - sign outside of placeholders
 }
 
 func (o *ValueNoQuoteState) Type() TokenType {
@@ -246,49 +413,112 @@ func (v *ValueNoQuoteState) Next(ru rune, f *Filter) error {
 
 		f.popState()
 		if len(v.cval) == 0 {
-			return Errorf("empty no quote state", f.ring.Position())
+			return f.errorf(string(ru), "empty no quote state")
 		}
 
 		// check if quotes are not needed
 		s := string(v.cval)
-		if IsNumber(s) ||
+		expanded := false
+		if f.interp != nil && strings.ContainsRune(s, '$') {
+
+			var err error
+			s, err = f.interp.expand(s, f.dollars, v.start)
+			if err != nil {
+				return err
+			}
+			v.cval = []rune(s)
+			expanded = true
+		}
+
+		number, isJSON5 := ``, false
+		if f.dialect == JSON5 && !expanded {
+			number, isJSON5 = json5Number(s)
+		}
+
+		switch {
+		case isJSON5:
+			f.pushRunes([]rune(number))
+
+		case IsNumber(s) ||
 			s == `true` ||
 			s == `false` ||
-			s == `null` {
+			s == `null`:
 
 			f.pushRunes(v.cval)
-			return ErrDontAdvance
-		}
 
-		if !unicode.IsLetter(([]rune(s))[0]) {
-			return Errorf("invalid identifier", f.ring.Position())
-		}
+		case expanded || (f.placeholders && !f.format && strings.Contains(s, `${`)):
+			f.outbuf = appendQuoted(f.outbuf, s)
+			f.lastOut = '"'
 
-		if f.format {
+		case (!unicode.IsLetter(([]rune(s))[0]) || v.minus) && !(f.placeholders && strings.HasPrefix(s, `${`)):
+			return newError(v.start, f.ring.Position()-len(v.cval), s, "invalid identifier")
+
+		case f.dialect == JSON:
+			return newError(v.start, f.ring.Position()-len(v.cval), s, "unquoted strings are not allowed in json")
+
+		case f.format:
 			f.pushRunes(v.cval)
-			return ErrDontAdvance
-		}
 
-		// quote the value
-		f.pushOut('"')
-		f.pushRunes(v.cval)
-		f.pushOut('"')
+		default:
+			// quote the value
+			f.pushOut('"')
+			f.pushRunes(v.cval)
+			f.pushOut('"')
+		}
 
+		err := f.end(StringNode, f.ring.Pos())
+		if err != nil {
+			return err
+		}
 		return ErrDontAdvance
 	}
 
-	if unicode.IsSpace(ru) || ru == ',' || ru == '}' || ru == ']' || ru == '/' {
+	// placeholders may hold any character but line breaks
+	if f.placeholders {
+
+		switch {
+		case v.placeholder && ru != '\n':
+			v.placeholder = ru != '}'
+			v.cval = append(v.cval, ru)
+			return nil
+
+		case ru == '{' && len(v.cval) > 0 && v.cval[len(v.cval)-1] == '$':
+			v.placeholder = true
+			v.cval = append(v.cval, ru)
+			return nil
+
+		case ru == '$':
+			if len(v.cval) == 0 {
+				v.start = f.ring.Pos()
+			}
+			f.dollar(ru)
+			v.cval = append(v.cval, ru)
+			return nil
+		}
+	}
+
+	if unicode.IsSpace(ru) || ru == ',' || ru == '}' || ru == ']' || ru == '/' || (ru == '#' && f.hash) {
 		return renderValue()
 	}
 
-	if !unicode.IsLetter(ru) && !unicode.IsDigit(ru) && ru != '.' && ru != '+' {
-		return Errorf("invalid identifier", f.ring.Position())
+	// signs of numbers and their exponents
+	if ru == '-' {
+		if n := len(v.cval); n > 0 && v.cval[n-1] != 'e' && v.cval[n-1] != 'E' {
+			return f.errorf(string(ru), "invalid identifier")
+		}
+		v.minus = true
+
+	} else if !unicode.IsLetter(ru) && !unicode.IsDigit(ru) && ru != '.' && ru != '+' {
+		return f.errorf(string(ru), "invalid identifier")
 	}
 
 	if ru == '\\' {
-		return Errorf("invalid identifier", f.ring.Position())
+		return f.errorf(string(ru), "invalid identifier")
 	}
 
+	if len(v.cval) == 0 {
+		v.start = f.ring.Pos()
+	}
 	v.cval = append(v.cval, ru)
 	return nil
 }
@@ -326,11 +556,11 @@ func (v *ValueMultilineState) Next(ru rune, f *Filter) error {
 		if ru == '`' {
 			f.pushOut(ru)
 			f.popState()
-			return nil
+			return f.end(StringNode, f.ring.EndPos())
 		}
 
 		if ru == '\\' {
-			return fmt.Errorf("character \\ found in multiline string")
+			return f.errorf(string(ru), "character \\ found in multiline string")
 
 		}
 
@@ -341,13 +571,14 @@ func (v *ValueMultilineState) Next(ru rune, f *Filter) error {
 	if ru == '`' {
 		f.pushOut('"')
 		f.popState()
-		return nil
+		return f.end(StringNode, f.ring.EndPos())
 	}
 
 	if ru == '\\' {
-		return fmt.Errorf("character \\ found in multiline string")
+		return f.errorf(string(ru), "character \\ found in multiline string")
 	}
 
+	f.dollar(ru)
 	if rep, ok := needsReplacement(ru); ok {
 		f.pushOut('\\')
 		f.pushOut(rep)
@@ -387,14 +618,14 @@ func (o *ObjectState) pop(f *Filter) error {
 
 	if f.format {
 		f.pushOutMult(o.lineBreaks, 2, '\n')
-		if o.lineBreaks > 0 {
+		if o.lineBreaks > 0 || f.lastOut == '\n' {
 			f.pushSpaces(f.indent() - 1)
 		}
 		o.lineBreaks = 0
 	}
 	f.pushOut('}')
 	f.popState()
-	return nil
+	return f.end(ObjectNode, f.ring.EndPos())
 }
 
 func (o *ObjectState) Next(ru rune, f *Filter) error {
@@ -414,7 +645,8 @@ func (o *ObjectState) Next(ru rune, f *Filter) error {
 		if f.format {
 			o.fromComment = true
 			f.pushOutMult(o.lineBreaks, 2, '\n')
-			if o.lineBreaks > 0 {
+			if o.lineBreaks > 0 || f.lastOut == '\n' {
+				f.pushSpaces(f.indent())
 				f.lastOut = utf8.RuneError
 			}
 			o.lineBreaks = 0
@@ -433,6 +665,9 @@ func (o *ObjectState) Next(ru rune, f *Filter) error {
 	case ObjIntNext:
 
 		if ru == '}' {
+			if f.dialect == JSON {
+				return f.errorf(string(ru), "trailing commas are not allowed in json")
+			}
 			return o.pop(f)
 		}
 
@@ -458,13 +693,22 @@ func (o *ObjectState) Next(ru rune, f *Filter) error {
 			o.lineBreaks = 0
 		}
 
+		err := f.begin(KeyNode, f.ring.Pos())
+		if err != nil {
+			return err
+		}
+
 		o.internalState = ObjInternalKey
-		if ru == '"' {
-			f.pushOut(ru)
-			f.pushState(&KeyState{})
+		if ru == '"' || (ru == '\'' && f.dialect == JSON5) {
+			f.pushOut('"')
+			f.pushState(f.keyState(ru))
 			return nil
 		}
 
+		if f.dialect == JSON {
+			return f.errorf(string(ru), "unquoted keys are not allowed in json")
+		}
+
 		f.pushState(&KeyNoQuoteState{})
 		return ErrDontAdvance
 
@@ -479,39 +723,12 @@ func (o *ObjectState) Next(ru rune, f *Filter) error {
 			return nil
 		}
 
-		return Errorf("error parsing object rune: %v", f.ring.Position(), string(ru))
+		return f.errorf(string(ru), "error parsing object rune: %v", string(ru))
 
 	case ObjInternalDelimiter:
 
 		o.internalState = ObjInternalValue
-		switch ru {
-		case '[':
-			f.pushOut(ru)
-			f.pushState(&ArrayState{})
-			return nil
-
-		case '{':
-			f.pushOut(ru)
-			f.pushState(&ObjectState{})
-			return nil
-
-		case '"':
-			f.pushOut(ru)
-			f.pushState(&ValueState{})
-			return nil
-
-		case '`':
-			if f.format {
-				f.pushOut(ru)
-			} else {
-				f.pushOut('"')
-			}
-			f.pushState(&ValueMultilineState{})
-			return nil
-		default:
-			f.pushState(&ValueNoQuoteState{})
-			return ErrDontAdvance
-		}
+		return f.pushValue(ru)
 
 	case ObjInternalValue:
 
@@ -528,6 +745,10 @@ func (o *ObjectState) Next(ru rune, f *Filter) error {
 			return nil
 		}
 
+		if f.dialect == JSON {
+			return f.errorf(string(ru), "missing comma")
+		}
+
 		if f.format {
 			if o.lineBreaks == 0 && !o.fromComment {
 				f.pushOut(' ')
@@ -538,7 +759,7 @@ func (o *ObjectState) Next(ru rune, f *Filter) error {
 		}
 		return ErrDontAdvance
 	default:
-		return Errorf("invalid internal object state: %v", f.ring.Position(), string(ru))
+		return f.errorf(string(ru), "invalid internal object state: %v", string(ru))
 	}
 }
 
@@ -556,6 +777,7 @@ type ArrayState struct {
 	lineBreaks      int
 	spaceOrControls int
 	fromComment     bool
+	comma           bool
 }
 
 func (ArrayState) Type() TokenType {
@@ -578,7 +800,8 @@ func (a *ArrayState) Next(ru rune, f *Filter) error {
 
 		if f.format {
 			f.pushOutMult(a.lineBreaks, 2, '\n')
-			if a.lineBreaks > 0 {
+			if a.lineBreaks > 0 || f.lastOut == '\n' {
+				f.pushSpaces(f.indent())
 				f.lastOut = utf8.RuneError
 			}
 			a.fromComment = true
@@ -606,12 +829,17 @@ func (a *ArrayState) Next(ru rune, f *Filter) error {
 			return ErrDontAdvance
 		case ',':
 			a.internalState = ArrayIntValue
+			a.comma = true
 			if f.format {
 				f.pushOut(',')
 			}
 			return nil
 		}
 
+		if f.dialect == JSON {
+			return f.errorf(string(ru), "missing comma")
+		}
+
 		if spaceOrControls > 0 {
 			if f.format && (a.lineBreaks == 0 && !a.fromComment) {
 				f.pushOut(' ')
@@ -620,11 +848,15 @@ func (a *ArrayState) Next(ru rune, f *Filter) error {
 			return ErrDontAdvance
 		}
 
-		return Errorf("invalid character after value", f.ring.Position())
+		return f.errorf(string(ru), "invalid character after value")
 
 	case ArrayIntStart, ArrayIntValue:
 
 		if ru == ']' {
+			if a.comma && f.dialect == JSON {
+				return f.errorf(string(ru), "trailing commas are not allowed in json")
+			}
+
 			if f.format {
 				f.pushOutMult(a.lineBreaks, 2, '\n')
 				if a.lineBreaks > 0 || a.fromComment {
@@ -635,7 +867,7 @@ func (a *ArrayState) Next(ru rune, f *Filter) error {
 			}
 			f.popState()
 			f.pushOut(ru)
-			return nil
+			return f.end(ArrayNode, f.ring.EndPos())
 		}
 
 		if a.internalState != ArrayIntStart && !f.format {
@@ -652,44 +884,132 @@ func (a *ArrayState) Next(ru rune, f *Filter) error {
 		}
 
 		a.internalState = ArrayIntAfterValue
-		switch ru {
-		case '[':
-			f.pushOut(ru)
-			f.pushState(&ArrayState{})
-			return nil
+		a.comma = false
+		return f.pushValue(ru)
+	default:
+		return f.errorf(string(ru), "invalid internal array state: %v", string(ru))
+	}
+}
 
-		case '{':
-			f.pushOut(ru)
-			f.pushState(&ObjectState{})
-			return nil
+// pushValue pushes the state of the value starting with ru.
+func (f *Filter) pushValue(ru rune) error {
+
+	kind := StringNode
+	switch ru {
+	case '[':
+		kind = ArrayNode
+	case '{':
+		kind = ObjectNode
+	case '`':
+		if f.dialect == JSON {
+			return f.errorf(string(ru), "multiline strings are not allowed in json")
+		}
+	}
 
-		case '"':
-			f.pushOut(ru)
-			f.pushState(&ValueState{})
-			return nil
+	if kind != StringNode && f.maxDepth > 0 && f.indent() >= f.maxDepth {
+		return f.errorf(string(ru), "maximum nesting depth of %v exceeded", f.maxDepth)
+	}
 
-		case '`':
-			if f.format {
-				f.pushOut(ru)
-			} else {
-				f.pushOut('"')
-			}
-			f.pushState(&ValueMultilineState{})
-			return nil
-		default:
-			f.pushState(&ValueNoQuoteState{})
-			return ErrDontAdvance
+	err := f.begin(kind, f.ring.Pos())
+	if err != nil {
+		return err
+	}
+
+	if f.interp != nil && kind == StringNode {
+		f.dollars = f.dollars[:0]
+		f.valueStart = f.ring.Pos()
+		if ru == '"' || ru == '`' || (ru == '\'' && f.dialect == JSON5) {
+			f.holding, f.hold = true, len(f.outbuf)
 		}
-	default:
-		return Errorf("invalid internal array state: %v", f.ring.Position(), string(ru))
 	}
+
+	switch ru {
+	case '[':
+		f.pushOut(ru)
+		f.pushState(&ArrayState{})
+		return nil
+
+	case '{':
+		f.pushOut(ru)
+		f.pushState(&ObjectState{})
+		return nil
+
+	case '"':
+		f.pushOut(ru)
+		f.pushState(f.valueState(ru))
+		return nil
+
+	case '\'':
+		if f.dialect != JSON5 {
+			break
+		}
+
+		f.pushOut('"')
+		f.pushState(f.valueState(ru))
+		return nil
+
+	case '`':
+		if f.format {
+			f.pushOut(ru)
+		} else {
+			f.pushOut('"')
+		}
+		f.pushState(&ValueMultilineState{})
+		return nil
+	}
+
+	f.pushState(&ValueNoQuoteState{})
+	return ErrDontAdvance
+}
+
+// valueState returns the state of a string value quoted by quote.
+func (f *Filter) valueState(quote rune) State {
+
+	if f.dialect == JSON5 {
+		return &ValueState{json5: &json5String{quote: quote}}
+	}
+	return &ValueState{}
+}
+
+// keyState returns the state of a key quoted by quote.
+func (f *Filter) keyState(quote rune) State {
+
+	if f.dialect == JSON5 {
+		return &KeyState{json5: &json5String{quote: quote}}
+	}
+	return &KeyState{}
 }
 
 func dispatchComment(f *Filter, postHook func() error) (shouldDispatch bool, err error) {
 
 	ru := f.ring.Peek()
+	start := f.ring.Pos()
+
+	if ru == '#' && f.hash {
+
+		if postHook != nil {
+			err = postHook()
+			if err != nil {
+				return
+			}
+		}
+
+		err = f.begin(LineCommentNode, start)
+		if err != nil {
+			return
+		}
+
+		shouldDispatch = true
+		err = openLineComment(f, `#`)
+		return
+	}
 
 	if ru == '/' {
+		if f.dialect == JSON {
+			err = f.errorf(string(ru), "comments are not allowed in json")
+			return
+		}
+
 		err = f.ring.Advance()
 		if err != nil {
 			return
@@ -705,13 +1025,13 @@ func dispatchComment(f *Filter, postHook func() error) (shouldDispatch bool, err
 				}
 			}
 
-			shouldDispatch = true
-			err = f.ring.Advance()
-			if f.format {
-				f.pushSpace()
-				f.pushRunes([]rune("//"))
+			err = f.begin(LineCommentNode, start)
+			if err != nil {
+				return
 			}
-			f.pushState(&CommentState{})
+
+			shouldDispatch = true
+			err = openLineComment(f, `//`)
 			return
 		}
 
@@ -720,6 +1040,11 @@ func dispatchComment(f *Filter, postHook func() error) (shouldDispatch bool, err
 				postHook()
 			}
 
+			err = f.begin(BlockCommentNode, start)
+			if err != nil {
+				return
+			}
+
 			shouldDispatch = true
 			if f.format {
 				f.pushSpace()
@@ -737,6 +1062,27 @@ func dispatchComment(f *Filter, postHook func() error) (shouldDispatch bool, err
 	return
 }
 
+// openLineComment enters the line comment opened by the current rune. A
+// comment opened at the end of the input ends there.
+func openLineComment(f *Filter, opening string) error {
+
+	err := f.ring.Advance()
+	if f.format {
+		f.pushSpace()
+		f.pushRunes([]rune(opening))
+	}
+
+	if errors.Is(err, io.EOF) {
+		if herr := f.end(LineCommentNode, f.ring.EndPos()); herr != nil {
+			return herr
+		}
+		return err
+	}
+
+	f.pushState(&CommentState{})
+	return err
+}
+
 type CommentState struct{}
 
 func (c *CommentState) Type() TokenType {
@@ -747,6 +1093,11 @@ func (*CommentState) Next(ru rune, f *Filter) error {
 
 	if ru == '\n' {
 		f.popState()
+		err := f.end(LineCommentNode, f.ring.Pos())
+		if err != nil {
+			return err
+		}
+
 		if f.format {
 			f.pushOut('\n')
 		}
@@ -761,6 +1112,9 @@ func (*CommentState) Next(ru rune, f *Filter) error {
 	if err != nil {
 		if errors.Is(err, io.EOF) {
 			f.popState()
+			if herr := f.end(LineCommentNode, f.ring.EndPos()); herr != nil {
+				return herr
+			}
 		}
 		return err
 	}
@@ -793,7 +1147,7 @@ func (c *CommentMultiLineState) Next(ru rune, f *Filter) error {
 			}
 
 			f.popState()
-			return nil
+			return f.end(BlockCommentNode, f.ring.EndPos())
 		}
 
 		f.ring.Pop()
@@ -813,7 +1167,7 @@ func (c *CommentMultiLineState) Next(ru rune, f *Filter) error {
 
 func (f *Filter) Read(p []byte) (n int, err error) {
 
-	if f.err != nil {
+	if f.err != nil && len(f.outbuf) == 0 {
 		return 0, f.err
 	}
 
@@ -822,17 +1176,12 @@ func (f *Filter) Read(p []byte) (n int, err error) {
 		n = len(p)
 	}
 
-	for n > len(f.outbuf) {
-
-		err = f.fill()
-		if err != nil {
-			break
-		}
+	for f.err == nil && n > f.ready() {
+		f.err = f.fill()
 	}
-	f.err = err
 
-	if len(f.outbuf) < n {
-		n = len(f.outbuf)
+	if f.ready() < n {
+		n = f.ready()
 	}
 
 	for i := 0; i < n; i++ {
@@ -840,18 +1189,24 @@ func (f *Filter) Read(p []byte) (n int, err error) {
 	}
 
 	f.outbuf = f.outbuf[n:]
+	f.read += n
+	f.hold -= n
 
-	if errors.Is(err, io.EOF) && f.peekState().Type() == Root {
+	if errors.Is(f.err, io.EOF) && f.peekState().Type() == Root {
 		f.done = true
 	}
 
-	return n, err
+	// the error is returned after all output was read
+	if len(f.outbuf) > 0 {
+		return n, nil
+	}
+	return n, f.err
 }
 
 func (f *Filter) fill() error {
 
 	state := f.peekState()
-	for f.outMinSize > len(f.outbuf) {
+	for f.outMinSize > f.ready() {
 
 		ru := f.ring.Peek()
 
@@ -873,7 +1228,18 @@ func (f *Filter) fill() error {
 
 		err := state.Next(ru, f)
 		if err != nil && !errors.Is(err, ErrDontAdvance) {
-			return err
+
+			var serr Error
+			if !f.recover || !errors.As(err, &serr) {
+				return err
+			}
+
+			err = f.resync(serr)
+			if err != nil {
+				return err
+			}
+			state = f.peekState()
+			continue
 		}
 
 		if !errors.Is(err, ErrDontAdvance) {
@@ -888,6 +1254,64 @@ func (f *Filter) fill() error {
 	return nil
 }
 
+// errorf returns an Error for the token at the current rune.
+func (f *Filter) errorf(token string, formatter string, args ...interface{}) Error {
+	return newError(f.ring.Pos(), f.ring.Position(), token, formatter, args...)
+}
+
+func (f *Filter) begin(kind NodeKind, pos Pos) error {
+
+	if f.srcmap != nil {
+		f.srcmap.add(f.offset(), pos)
+	}
+
+	if f.tokens == nil {
+		return nil
+	}
+	return f.tokens.begin(kind, pos)
+}
+
+func (f *Filter) end(kind NodeKind, pos Pos) error {
+
+	if f.holding && kind == StringNode {
+		f.holding = false
+		err := f.interpolate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.srcmap != nil {
+		f.srcmap.add(f.offset(), pos)
+	}
+
+	if f.tokens == nil {
+		return nil
+	}
+	return f.tokens.end(kind, pos)
+}
+
+// ready returns the number of output bytes which may be read, the output of a
+// string held back for interpolation is not.
+func (f *Filter) ready() int {
+	if f.holding {
+		return f.hold
+	}
+	return len(f.outbuf)
+}
+
+// dollar records the location of the $ signs of values to interpolate.
+func (f *Filter) dollar(ru rune) {
+	if ru == '$' && f.interp != nil {
+		f.dollars = append(f.dollars, f.ring.Pos())
+	}
+}
+
+// offset returns the number of bytes written by the filter.
+func (f *Filter) offset() int {
+	return f.read + len(f.outbuf)
+}
+
 func (f *Filter) peekState() State {
 	if len(f.stack) > 0 {
 		return f.stack[len(f.stack)-1]
@@ -923,15 +1347,18 @@ func (f *Filter) pushSpace() {
 	}
 }
 
+// pushSpaces indents by c levels of space.
 func (f *Filter) pushSpaces(c int) {
 	for i := 0; i < c; i++ {
-		f.pushOut(' ')
+		for _, ru := range f.space {
+			f.pushOut(ru)
+		}
 	}
 }
 
 func (f *Filter) pushOut(r rune) {
 	f.lastOut = r
-	f.outbuf = append(f.outbuf, byte(r))
+	f.outbuf = append(f.outbuf, string(r)...)
 }
 
 func (f *Filter) pushRunes(runes []rune) {
@@ -170,6 +170,23 @@ func TestComplexFmt(t *testing.T) {
 	testFormattingFile(t, `test-complex.txt`)
 }
 
+func TestFormatSpace(t *testing.T) {
+
+	for space, expected := range map[string]string{
+		` `:  "{a: {\n  b: [1\n   2]}}",
+		`  `: "{a: {\n    b: [1\n      2]}}",
+		"\t": "{a: {\n\t\tb: [1\n\t\t\t2]}}",
+	} {
+
+		f, err := New(strings.NewReader("{a: {\nb: [1\n2]}}"), false, space)
+		require.NoError(t, err)
+
+		out, err := ioutil.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(out), space)
+	}
+}
+
 func testFormattingFile(t *testing.T, path string) {
 
 	f, err := os.Open(path)
@@ -246,3 +263,40 @@ func TestMultilineValue(t *testing.T) {
 	assert.Contains(t, ret, `"quote":"\""`)
 	assert.Contains(t, ret, `"lineFeed":"\n"`)
 }
+
+func TestFilterSmallReads(t *testing.T) {
+
+	src := `{x:x, y:[1,2,3], z:{a:"b"}} // comment`
+
+	f, err := New(strings.NewReader(src), true, ``)
+	require.NoError(t, err)
+
+	var out []byte
+	p := make([]byte, 7)
+	for {
+		n, err := f.Read(p)
+		out = append(out, p[:n]...)
+		if err != nil {
+			require.True(t, errors.Is(err, io.EOF))
+			break
+		}
+	}
+
+	assert.Equal(t, `{"x":"x","y":[1,2,3],"z":{"a":"b"}}`, string(out))
+	assert.True(t, f.Done())
+}
+
+func TestFilterErr(t *testing.T) {
+
+	f, err := New(strings.NewReader(`{a: 1}`), true, ``)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, io.EOF, f.Err())
+
+	f, err = New(strings.NewReader(`{a: [1`), true, ``)
+	require.NoError(t, err)
+	ioutil.ReadAll(f)
+	assert.False(t, f.Done())
+	assert.EqualError(t, f.Err(), `line: 1 col: 7 unexpected end of input`)
+}
@@ -0,0 +1,405 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+// Example returns a commented example jsonc document of the struct type
+// typeName declared in the Go package in dir. The package is read from
+// source, test files are left out.
+//
+// Members are named as encoding/json names the fields. The doc comment of a
+// field, or the comment of its jsonc tag, is written above its member, the
+// doc comment of the type above the document. Values are taken from the
+// default tag of the fields, written as jsonc for all but strings:
+//
+//	Port  int      `json:"port" default:"8080"`
+//	Hosts []string `json:"hosts" default:"[alpha, beta]"`
+//
+// Fields without a default get the zero value, structs are expanded. A
+// time.Duration default may be written as "5s". Optional fields, pointers
+// and fields tagged omitempty, are commented out.
+func Example(dir, typeName string) ([]byte, error) {
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), `_test.go`)
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &example{types: map[string]*ast.TypeSpec{}, docs: map[string]string{}, visiting: map[string]bool{}}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range gd.Specs {
+
+					ts := spec.(*ast.TypeSpec)
+					e.types[ts.Name.Name] = ts
+
+					doc := ts.Doc
+					if doc == nil && len(gd.Specs) == 1 {
+						doc = gd.Doc
+					}
+					e.docs[ts.Name.Name] = strings.TrimSpace(doc.Text())
+				}
+			}
+		}
+	}
+
+	ts, ok := e.types[typeName]
+	if !ok {
+		return nil, fmt.Errorf("type %v not found in %v", typeName, dir)
+	}
+
+	if _, ok := ts.Type.(*ast.StructType); !ok {
+		return nil, fmt.Errorf("type %v is not a struct", typeName)
+	}
+
+	writeDoc(&e.buf, ``, e.docs[typeName])
+	err = e.value(&ast.Ident{Name: typeName}, ``, ``)
+	if err != nil {
+		return nil, err
+	}
+	e.buf.WriteByte('\n')
+
+	// the example must read back
+	_, err = jsonc.Parse(e.buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type example struct {
+	buf      bytes.Buffer
+	types    map[string]*ast.TypeSpec
+	docs     map[string]string
+	visiting map[string]bool // the struct types being written
+}
+
+// member is a member of the example.
+type member struct {
+	name       string
+	doc        string
+	typ        ast.Expr
+	optional   bool
+	defaultTag string
+	hasDefault bool
+}
+
+// members returns the members of the struct type st, the fields of embedded
+// structs without a name are promoted.
+func (e *example) members(st *ast.StructType, optional bool) []member {
+
+	var ms []member
+	for _, f := range st.Fields.List {
+
+		tag := reflect.StructTag(``)
+		if f.Tag != nil {
+			if s, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = reflect.StructTag(s)
+			}
+		}
+
+		ft := jsonc.ParseTag(tag)
+		if ft.Skip {
+			continue
+		}
+		name := ft.Name
+
+		m := member{typ: f.Type, doc: strings.TrimSpace(f.Doc.Text())}
+		m.optional = optional || ft.OmitEmpty
+		if _, ok := f.Type.(*ast.StarExpr); ok {
+			m.optional = true
+		}
+		m.defaultTag, m.hasDefault = tag.Lookup(`default`)
+		if ft.Comment != `` {
+			m.doc = ft.Comment
+		}
+
+		names := f.Names
+		if len(names) == 0 {
+			if st, ok := e.embedded(f.Type); ok && name == `` {
+				_, pointer := f.Type.(*ast.StarExpr)
+				ms = append(ms, e.members(st, optional || pointer)...)
+				continue
+			}
+			names = []*ast.Ident{typeIdent(f.Type)}
+		}
+
+		for _, ident := range names {
+
+			if ident == nil || !ident.IsExported() {
+				continue
+			}
+
+			m.name = name
+			if name == `` {
+				m.name = ident.Name
+			}
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// embedded returns the struct type of an embedded field.
+func (e *example) embedded(typ ast.Expr) (*ast.StructType, bool) {
+
+	ident := typeIdent(typ)
+	if ident == nil {
+		return nil, false
+	}
+
+	ts, ok := e.types[ident.Name]
+	if !ok || e.visiting[ident.Name] {
+		return nil, false
+	}
+
+	st, ok := ts.Type.(*ast.StructType)
+	return st, ok
+}
+
+// typeIdent returns the name of the type of an embedded field.
+func typeIdent(typ ast.Expr) *ast.Ident {
+
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return t
+	case *ast.SelectorExpr:
+		return t.Sel
+	}
+	return nil
+}
+
+// value writes the example value of the type typ. Lines after the first
+// start with the comment prefix of commented out members and indent.
+func (e *example) value(typ ast.Expr, prefix, indent string) error {
+
+	switch t := typ.(type) {
+	case *ast.StarExpr:
+		return e.value(t.X, prefix, indent)
+
+	case *ast.StructType:
+		return e.object(t, prefix, indent)
+
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == `byte` && t.Len == nil {
+			e.buf.WriteString(`""`)
+			return nil
+		}
+		e.buf.WriteString(`[]`)
+		return nil
+
+	case *ast.MapType:
+		e.buf.WriteString(`{}`)
+		return nil
+
+	case *ast.SelectorExpr:
+		e.buf.WriteString(zeroValue(t))
+		return nil
+
+	case *ast.Ident:
+		ts, ok := e.types[t.Name]
+		if !ok {
+			e.buf.WriteString(zeroValue(t))
+			return nil
+		}
+
+		if e.visiting[t.Name] {
+			e.buf.WriteString(`null`)
+			return nil
+		}
+
+		e.visiting[t.Name] = true
+		defer delete(e.visiting, t.Name)
+		return e.value(ts.Type, prefix, indent)
+	}
+
+	e.buf.WriteString(`null`)
+	return nil
+}
+
+// object writes the members of the struct type st.
+func (e *example) object(st *ast.StructType, prefix, indent string) error {
+
+	ms := e.members(st, false)
+	if len(ms) == 0 {
+		e.buf.WriteString(`{}`)
+		return nil
+	}
+
+	e.buf.WriteString("{\n")
+	inner := indent + `  `
+	for idx, m := range ms {
+
+		if idx > 0 && m.doc != `` {
+			e.buf.WriteString(strings.TrimRight(prefix+inner, ` `) + "\n")
+		}
+		writeDoc(&e.buf, prefix+inner, m.doc)
+
+		// the lines of commented out members start with the comment
+		p, in := prefix, inner
+		if m.optional && prefix == `` {
+			p, in = inner+`// `, ``
+		}
+
+		e.buf.WriteString(p + in + key(m.name) + `: `)
+
+		var err error
+		if m.hasDefault {
+			err = e.defaultValue(m, p+in)
+		} else {
+			err = e.value(m.typ, p, in)
+		}
+
+		if err != nil {
+			return err
+		}
+		e.buf.WriteByte('\n')
+	}
+
+	e.buf.WriteString(prefix + indent + `}`)
+	return nil
+}
+
+// defaultValue writes the default of the member m, lines after the first
+// start with lineStart.
+func (e *example) defaultValue(m member, lineStart string) error {
+
+	var text []byte
+	var err error
+	if e.isString(m.typ) {
+		text, err = jsonc.Marshal(m.defaultTag)
+	} else if d, derr := time.ParseDuration(m.defaultTag); derr == nil && isDuration(m.typ) {
+		text = []byte(strconv.FormatInt(int64(d), 10))
+	} else {
+		// scalars are not valid documents on their own, format them in an
+		// array
+		text, err = jsonc.Format([]byte(`[` + m.defaultTag + `]`))
+		text = bytes.TrimSpace(text)
+		text = bytes.TrimSuffix(bytes.TrimPrefix(text, []byte(`[`)), []byte(`]`))
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid default of %v: %v", m.name, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(text)), "\n")
+	for i, line := range lines {
+		if i > 0 {
+			e.buf.WriteString("\n" + lineStart)
+		}
+		e.buf.WriteString(line)
+	}
+	return nil
+}
+
+// isString reports whether the underlying type of typ is string.
+func (e *example) isString(typ ast.Expr) bool {
+
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	if ident.Name == `string` {
+		return true
+	}
+
+	ts, ok := e.types[ident.Name]
+	return ok && e.isString(ts.Type)
+}
+
+func isDuration(typ ast.Expr) bool {
+
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+
+	sel, ok := typ.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == `time` && sel.Sel.Name == `Duration`
+}
+
+// zeroValue returns the zero value of a predeclared or imported type.
+func zeroValue(typ ast.Expr) string {
+
+	switch t := typ.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case `string`:
+			return `""`
+		case `bool`:
+			return `false`
+		case `int`, `int8`, `int16`, `int32`, `int64`, `rune`,
+			`uint`, `uint8`, `uint16`, `uint32`, `uint64`, `uintptr`, `byte`,
+			`float32`, `float64`:
+			return `0`
+		}
+
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+
+		switch pkg.Name + `.` + t.Sel.Name {
+		case `time.Duration`, `json.Number`:
+			return `0`
+		case `time.Time`:
+			return `"0001-01-01T00:00:00Z"`
+		}
+	}
+	return `null`
+}
+
+// key returns name as jsonc key, quoted unless made of letters and digits
+// only.
+func key(name string) string {
+
+	for _, ru := range name {
+		if !unicode.IsLetter(ru) && !unicode.IsDigit(ru) {
+			quoted, _ := json.Marshal(name)
+			return string(quoted)
+		}
+	}
+
+	if name == `` {
+		return `""`
+	}
+	return name
+}
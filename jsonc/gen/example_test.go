@@ -0,0 +1,80 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExample(t *testing.T) {
+
+	out, err := Example(`testdata/config`, `Config`)
+	require.NoError(t, err)
+	assert.Equal(t, `// Config is the config of a service.
+//
+// It is read at startup.
+{
+  // Version of the config.
+  version: 2
+
+  // Name is the name of the service.
+  name: alpha
+
+  // Port is the listen port.
+  port: 8080
+  timeout: 5000000000
+  hosts: [alpha,beta]
+  mode: "dev mode"
+
+  // enables the debug log
+  // debug: false
+
+  // extra labels
+  Labels: {}
+  key: ""
+  limits: {
+    max: 1.5
+  }
+  started: "0001-01-01T00:00:00Z"
+  raw: null
+
+  // TLS enables https.
+  // tls: {
+  //   // Cert is the certificate file.
+  //   cert: cert.pem
+  //   key: ""
+  // }
+  // parent: null
+}
+`, string(out))
+
+	var c struct {
+		Version int      `json:"version"`
+		Timeout int64    `json:"timeout"`
+		Hosts   []string `json:"hosts"`
+	}
+	require.NoError(t, jsonc.Unmarshal(out, &c))
+	assert.Equal(t, 2, c.Version)
+	assert.Equal(t, int64(5e9), c.Timeout)
+	assert.Equal(t, []string{`alpha`, `beta`}, c.Hosts)
+}
+
+func TestExampleErrors(t *testing.T) {
+
+	tests := []struct {
+		dir, typeName string
+		err           string
+	}{
+		{dir: `testdata/config`, typeName: `broken`, err: `invalid default of Value: line: 1 col: 5 unexpected end of input`},
+		{dir: `testdata/config`, typeName: `Mode`, err: `type Mode is not a struct`},
+		{dir: `testdata/config`, typeName: `Missing`, err: `type Missing not found in testdata/config`},
+		{dir: `testdata/missing`, typeName: `Config`, err: `open testdata/missing: no such file or directory`},
+	}
+
+	for _, ts := range tests {
+		_, err := Example(ts.dir, ts.typeName)
+		assert.EqualError(t, err, ts.err, ts.typeName)
+	}
+}
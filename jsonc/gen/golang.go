@@ -0,0 +1,404 @@
+// Package gen generates Go type definitions from sample jsonc documents and
+// commented example documents from Go types.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+// Option configures GoTypes.
+type Option func(*config)
+
+type config struct {
+	pkg      string
+	typeName string
+}
+
+// Package sets the package name of the generated file, the default is main.
+func Package(name string) Option {
+	return func(c *config) {
+		c.pkg = name
+	}
+}
+
+// TypeName sets the name of the type of the document, the default is Config.
+func TypeName(name string) Option {
+	return func(c *config) {
+		c.typeName = name
+	}
+}
+
+// GoTypes returns a formatted Go file with the type definitions of the
+// sample document doc, as returned by jsonc.Parse.
+//
+// Objects become structs with json tags, named after their key. The
+// elements of an array are merged into one element type: members missing in
+// some of the objects are tagged omitempty, integers and other numbers give
+// float64 and values of different types interface{}. A value which is also
+// null is a pointer. Structs of the same fields and types share one type.
+// The comments of the members become the doc comments of the fields, the
+// comment of the document the doc comment of its type.
+func GoTypes(doc *jsonc.Node, opts ...Option) ([]byte, error) {
+
+	c := config{pkg: `main`, typeName: `Config`}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	n := doc
+	if doc.Kind == jsonc.DocumentNode || doc.Kind == jsonc.MemberNode {
+		n = doc.Value()
+	}
+
+	if n == nil {
+		return nil, fmt.Errorf("the document is empty")
+	}
+
+	root := &shape{}
+	comment := ``
+	if doc.Kind == jsonc.DocumentNode {
+		comment = doc.Comment(n)
+	}
+	root.add(n, comment)
+
+	g := &generator{names: map[string]bool{c.typeName: true}}
+	typ := g.goType(root, c.typeName, ``, true)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "package %v\n", c.pkg)
+
+	if root.kind() != `object` {
+		writeDoc(buf, ``, root.description)
+		fmt.Fprintf(buf, "type %v %v\n", c.typeName, typ)
+	}
+
+	for _, d := range g.decls {
+		if d != nil {
+			buf.WriteString("\n")
+			writeDoc(buf, ``, d.doc)
+			fmt.Fprintf(buf, "type %v %v\n", d.name, d.body)
+		}
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// shape is the merged shape of the sample values at one location.
+type shape struct {
+	count       int // values seen
+	description string
+	kinds       map[string]bool
+
+	objects int // objects seen
+	names   []string
+	members map[string]*shape
+
+	items *shape
+}
+
+func (s *shape) add(n *jsonc.Node, comment string) {
+
+	s.count++
+	if s.description == `` {
+		s.description = comment
+	}
+
+	if s.kinds == nil {
+		s.kinds = map[string]bool{}
+	}
+
+	switch n.Kind {
+	case jsonc.ObjectNode:
+		s.kinds[`object`] = true
+		s.objects++
+		if s.members == nil {
+			s.members = map[string]*shape{}
+		}
+
+		// as when decoding the last of duplicate members counts
+		members := map[string]*jsonc.Node{}
+		for _, m := range n.Members() {
+			if m.Value() != nil {
+				members[m.Name()] = m
+			}
+		}
+
+		for _, m := range n.Members() {
+
+			name := m.Name()
+			if members[name] != m {
+				continue
+			}
+
+			ms, ok := s.members[name]
+			if !ok {
+				ms = &shape{}
+				s.members[name] = ms
+				s.names = append(s.names, name)
+			}
+			ms.add(m.Value(), n.Comment(m))
+		}
+
+	case jsonc.ArrayNode:
+		s.kinds[`array`] = true
+		if s.items == nil {
+			s.items = &shape{}
+		}
+
+		for _, e := range n.Elements() {
+			s.items.add(e, ``)
+		}
+
+	case jsonc.NumberNode:
+		if _, err := strconv.ParseInt(n.Text, 10, 64); err == nil {
+			s.kinds[`int`] = true
+		} else {
+			s.kinds[`float`] = true
+		}
+
+	case jsonc.StringNode:
+		s.kinds[`string`] = true
+	case jsonc.BoolNode:
+		s.kinds[`bool`] = true
+	case jsonc.NullNode:
+		s.kinds[`null`] = true
+	}
+}
+
+// kind returns the kind of the Go type of the shape: object, array, int,
+// float, string or bool, or an empty string for interface{}.
+func (s *shape) kind() string {
+
+	var kinds []string
+	for k := range s.kinds {
+		if k != `null` && !(k == `int` && s.kinds[`float`]) {
+			kinds = append(kinds, k)
+		}
+	}
+
+	if len(kinds) != 1 {
+		return ``
+	}
+	return kinds[0]
+}
+
+type generator struct {
+	names map[string]bool // the type names in use
+	decls []*decl
+}
+
+// decl is a generated struct type.
+type decl struct {
+	name string
+	doc  string
+	body string
+}
+
+// goType returns the Go type of the shape s. Structs are declared with the
+// given name, or one derived from parent if it is taken.
+func (g *generator) goType(s *shape, name, parent string, root bool) string {
+
+	typ := `interface{}`
+	switch s.kind() {
+	case `object`:
+		typ = g.declare(s, name, parent, root)
+		if s.kinds[`null`] {
+			typ = `*` + typ
+		}
+		return typ
+
+	case `array`:
+		item := `interface{}`
+		if s.items.count > 0 {
+			item = g.goType(s.items, singular(name), parent, false)
+		}
+		return `[]` + item
+
+	case `int`:
+		typ = `int`
+	case `float`:
+		typ = `float64`
+	case `string`:
+		typ = `string`
+	case `bool`:
+		typ = `bool`
+	default:
+		return typ
+	}
+
+	if s.kinds[`null`] {
+		typ = `*` + typ
+	}
+	return typ
+}
+
+// declare declares the struct of the object shape s and returns its name.
+func (g *generator) declare(s *shape, name, parent string, root bool) string {
+
+	// reserve the place of the declaration in front of the nested ones
+	idx := len(g.decls)
+	g.decls = append(g.decls, nil)
+
+	body := &bytes.Buffer{}
+	body.WriteString("struct {\n")
+
+	fields := map[string]bool{}
+	for _, key := range s.names {
+
+		ms := s.members[key]
+		field := unique(fields, goName(key))
+		fields[field] = true
+
+		typ := g.goType(ms, goName(key), name, false)
+
+		tag := `json:"` + key
+		if ms.count < s.objects {
+			tag += `,omitempty`
+		}
+		tag += `"`
+
+		writeDoc(body, "\t", ms.description)
+		fmt.Fprintf(body, "\t%v %v %v\n", field, typ, quoteTag(tag))
+	}
+	body.WriteString("}")
+
+	for _, d := range g.decls {
+		if d != nil && d.body == body.String() && !root {
+			g.decls[idx] = nil
+			return d.name
+		}
+	}
+
+	if !root {
+		if g.names[name] {
+			name = unique(g.names, parent+name)
+		}
+		g.names[name] = true
+	}
+
+	g.decls[idx] = &decl{name: name, doc: s.description, body: body.String()}
+	if !root {
+		g.decls[idx].doc = ``
+	}
+	return name
+}
+
+// writeDoc writes text as line comments.
+func writeDoc(buf *bytes.Buffer, indent, text string) {
+
+	if text == `` {
+		return
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		buf.WriteString(strings.TrimRight(indent+`// `+line, ` `))
+		buf.WriteByte('\n')
+	}
+}
+
+// quoteTag returns the struct tag as Go string literal.
+func quoteTag(tag string) string {
+
+	if strings.ContainsRune(tag, '`') {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// unique returns name, or name with the smallest number appended which is
+// not in names.
+func unique(names map[string]bool, name string) string {
+
+	if !names[name] {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		if n := fmt.Sprintf("%v%v", name, i); !names[n] {
+			return n
+		}
+	}
+}
+
+// initialisms are written in upper case in Go names.
+var initialisms = map[string]bool{
+	`ACL`: true, `API`: true, `ASCII`: true, `CPU`: true, `CSS`: true, `DNS`: true,
+	`EOF`: true, `GUID`: true, `HTML`: true, `HTTP`: true, `HTTPS`: true, `ID`: true,
+	`IP`: true, `JSON`: true, `LHS`: true, `QPS`: true, `RAM`: true, `RHS`: true,
+	`RPC`: true, `SLA`: true, `SMTP`: true, `SQL`: true, `SSH`: true, `TCP`: true,
+	`TLS`: true, `TTL`: true, `UDP`: true, `UI`: true, `UID`: true, `UUID`: true,
+	`URI`: true, `URL`: true, `UTF8`: true, `VM`: true, `XML`: true, `XMPP`: true,
+	`XSRF`: true, `XSS`: true,
+}
+
+// goName returns the exported Go name of the key.
+func goName(key string) string {
+
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	for _, ru := range key {
+
+		switch {
+		case !unicode.IsLetter(ru) && !unicode.IsDigit(ru):
+			flush()
+			continue
+
+		// a new word starts at an upper case letter behind a lower case one
+		case unicode.IsUpper(ru) && len(word) > 0 && unicode.IsLower(word[len(word)-1]):
+			flush()
+		}
+		word = append(word, ru)
+	}
+	flush()
+
+	name := &strings.Builder{}
+	for _, w := range words {
+
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			name.WriteString(upper)
+			continue
+		}
+
+		runes := []rune(w)
+		name.WriteRune(unicode.ToUpper(runes[0]))
+		name.WriteString(string(runes[1:]))
+	}
+
+	s := name.String()
+	if s == `` {
+		return `Field`
+	}
+
+	if first := []rune(s)[0]; !unicode.IsUpper(first) {
+		return `X` + s
+	}
+	return s
+}
+
+// singular returns the name of an element of an array named name.
+func singular(name string) string {
+
+	switch {
+	case strings.HasSuffix(name, `ies`) && len(name) > 3:
+		return strings.TrimSuffix(name, `ies`) + `y`
+	case strings.HasSuffix(name, `ss`):
+		return name + `Item`
+	case strings.HasSuffix(name, `s`) && len(name) > 1:
+		return strings.TrimSuffix(name, `s`)
+	}
+	return name + `Item`
+}
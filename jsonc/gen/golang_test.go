@@ -0,0 +1,171 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoTypes(t *testing.T) {
+
+	doc, err := jsonc.Parse([]byte("// A jsonc example document\n{\n" + `
+ owner:{
+  // the owner's name
+  name: komkom
+  dob: /* just some random dob */ ` + "`1975-01-25T12:00:00-02:00`" + `
+ }
+
+ database:{ // our live db
+  server: "192.168.1.1"
+  ports: [8001, 8002, 8003]
+  connectionMax: 5000
+  enabled: true
+  userID: null
+ }
+
+ servers:{
+  alpha: {ip: "10.0.0.1", dc: eqdc10}
+  beta: {ip: "10.0.0.2", dc: eqdc10}
+ }
+
+ clients: {data: [[gamma, delta], [1, 2]]}
+ menuitems: [
+  {value: New, onclick: "CreateNewDoc()"}
+  {value: Open, ratio: 1.5, parent: null}
+  {value: Close, ratio: 2, parent: {value: Open}}
+ ]
+ "x-tags": []
+ limits: [1, 2.5, null]
+ "": any
+ "a-b": 1
+ aB: 2
+ "9lives": yes
+ "a` + "`" + `b": false
+}`))
+	require.NoError(t, err)
+
+	out, err := GoTypes(doc, Package(`config`), TypeName(`Service`))
+	require.NoError(t, err)
+
+	// the backticks of the expected source are written as ~
+	assert.Equal(t, strings.ReplaceAll(`package config
+
+// A jsonc example document
+type Service struct {
+	Owner Owner ~json:"owner"~
+	// our live db
+	Database  Database      ~json:"database"~
+	Servers   Servers       ~json:"servers"~
+	Clients   Clients       ~json:"clients"~
+	Menuitems []Menuitem    ~json:"menuitems"~
+	XTags     []interface{} ~json:"x-tags"~
+	Limits    []*float64    ~json:"limits"~
+	Field     string        ~json:""~
+	AB        int           ~json:"a-b"~
+	AB2       int           ~json:"aB"~
+	X9lives   string        ~json:"9lives"~
+	AB3       bool          "json:\"a~b\""
+}
+
+type Owner struct {
+	// the owner's name
+	Name string ~json:"name"~
+	// just some random dob
+	Dob string ~json:"dob"~
+}
+
+type Database struct {
+	Server        string      ~json:"server"~
+	Ports         []int       ~json:"ports"~
+	ConnectionMax int         ~json:"connectionMax"~
+	Enabled       bool        ~json:"enabled"~
+	UserID        interface{} ~json:"userID"~
+}
+
+type Servers struct {
+	Alpha Alpha ~json:"alpha"~
+	Beta  Alpha ~json:"beta"~
+}
+
+type Alpha struct {
+	IP string ~json:"ip"~
+	Dc string ~json:"dc"~
+}
+
+type Clients struct {
+	Data [][]interface{} ~json:"data"~
+}
+
+type Menuitem struct {
+	Value   string  ~json:"value"~
+	Onclick string  ~json:"onclick,omitempty"~
+	Ratio   float64 ~json:"ratio,omitempty"~
+	Parent  *Parent ~json:"parent,omitempty"~
+}
+
+type Parent struct {
+	Value string ~json:"value"~
+}
+`, `~`, "`"), string(out))
+}
+
+func TestGoTypesRoot(t *testing.T) {
+
+	tests := []struct {
+		doc string
+		src string
+	}{
+		{doc: `[{a: 1}, {a: 2, b: x}]`, src: "package main\n\ntype Config []ConfigItem\n\ntype ConfigItem struct {\n\tA int    `json:\"a\"`\n\tB string `json:\"b,omitempty\"`\n}\n"},
+		{doc: "// the name\n`komkom`", src: "package main\n\n// the name\ntype Config string\n"},
+		{doc: `{}`, src: "package main\n\ntype Config struct {\n}\n"},
+		{doc: `{children: [{children: []}]}`, src: "package main\n\ntype Config struct {\n\tChildren []ChildrenItem `json:\"children\"`\n}\n\ntype ChildrenItem struct {\n\tChildren []interface{} `json:\"children\"`\n}\n"},
+	}
+
+	for _, ts := range tests {
+
+		doc, err := jsonc.Parse([]byte(ts.doc))
+		require.NoError(t, err)
+
+		src, err := GoTypes(doc)
+		require.NoError(t, err, ts.doc)
+		assert.Equal(t, ts.src, string(src), ts.doc)
+	}
+
+	doc, err := jsonc.Parse([]byte(`// empty`))
+	require.NoError(t, err)
+	_, err = GoTypes(doc)
+	assert.EqualError(t, err, `the document is empty`)
+
+	doc, err = jsonc.Parse([]byte(`{}`))
+	require.NoError(t, err)
+	_, err = GoTypes(doc, Package(`no package`))
+	assert.Error(t, err)
+}
+
+func TestGoName(t *testing.T) {
+
+	tests := map[string]string{
+		`name`:          `Name`,
+		`connectionMax`: `ConnectionMax`,
+		`user_id`:       `UserID`,
+		`HTTPServer`:    `HTTPServer`,
+		`api-url`:       `APIURL`,
+		`$ref`:          `Ref`,
+		`2fa`:           `X2fa`,
+		`été`:           `Été`,
+		`名前`:            `X名前`,
+		`--`:            `Field`,
+	}
+
+	for key, name := range tests {
+		assert.Equal(t, name, goName(key), key)
+	}
+
+	assert.Equal(t, `Server`, singular(`Servers`))
+	assert.Equal(t, `Entry`, singular(`Entries`))
+	assert.Equal(t, `AddressItem`, singular(`Address`))
+	assert.Equal(t, `DataItem`, singular(`Data`))
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Config is the config of a service.
+//
+// It is read at startup.
+type Config struct {
+	Base
+
+	// Name is the name of the service.
+	Name string `json:"name" default:"alpha"`
+
+	// Port is the listen port.
+	Port    int               `json:"port" default:"8080"`
+	Timeout time.Duration     `json:"timeout" default:"5s"`
+	Hosts   []string          `json:"hosts" default:"[alpha, beta]"`
+	Mode    Mode              `json:"mode" default:"dev mode"`
+	Debug   bool              `json:"debug,omitempty" jsonc:",comment=enables the debug log"`
+	Labels  map[string]string `jsonc:"labels,comment=extra labels"`
+	Key     []byte            `json:"key"`
+	Limits  struct {
+		Max float64 `json:"max" default:"1.5"`
+	} `json:"limits"`
+	Started time.Time       `json:"started"`
+	Raw     json.RawMessage `json:"raw"`
+
+	// TLS enables https.
+	TLS *TLS `json:"tls"`
+
+	Parent *Config `json:"parent,omitempty"`
+
+	Skipped string `json:"-"`
+	hidden  string
+}
+
+// Base holds the common fields.
+type Base struct {
+	// Version of the config.
+	Version int `json:"version" default:"2"`
+}
+
+// Mode is the mode of the service.
+type Mode string
+
+// TLS is the tls config.
+type TLS struct {
+	// Cert is the certificate file.
+	Cert string `json:"cert" default:"cert.pem"`
+	Key  string `json:"key"`
+}
+
+type broken struct {
+	Value int `default:"[1"`
+}
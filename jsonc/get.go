@@ -0,0 +1,201 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Result is a value found by Get.
+type Result struct {
+	Kind NodeKind        // ObjectNode, ArrayNode, StringNode, NumberNode, BoolNode or NullNode
+	Raw  json.RawMessage // the value as json, nil if it does not exist
+	Span Span            // location of the value in the document
+}
+
+// Get returns the value at path in the jsonc document data. See GetReader.
+func Get(data []byte, path string, opts ...Option) (Result, error) {
+	return GetReader(bytes.NewReader(data), path, opts...)
+}
+
+// GetReader reads a jsonc document from r up to the value at path and
+// returns it. The path separates member names and array indexes by dots, as
+// in "database.ports.1", dots and backslashes inside names are escaped by a
+// backslash. The empty path selects the whole document.
+//
+// As for LookupReader the last of duplicate members counts, the input is read
+// until the objects on the path are complete and the rest of the document is
+// not checked. A value which does not exist is returned as Result for which
+// Exists is false, errors are returned for invalid documents only.
+func GetReader(r io.Reader, path string, opts ...Option) (Result, error) {
+
+	m, err := lookup(r, splitPath(path), opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if m.missing {
+		return Result{}, nil
+	}
+
+	if m.err != nil {
+		return Result{}, m.err
+	}
+
+	return Result{Kind: rawKind(m.match.JSON), Raw: m.match.JSON, Span: m.match.Span}, nil
+}
+
+// splitPath splits a path of Get into its member names and indexes.
+func splitPath(path string) []string {
+
+	if path == `` {
+		return nil
+	}
+
+	var tokens []string
+	token := &strings.Builder{}
+	for i := 0; i < len(path); i++ {
+
+		switch c := path[i]; {
+		case c == '\\' && i+1 < len(path):
+			i++
+			token.WriteByte(path[i])
+
+		case c == '.':
+			tokens = append(tokens, token.String())
+			token.Reset()
+
+		default:
+			token.WriteByte(c)
+		}
+	}
+	return append(tokens, token.String())
+}
+
+// rawKind returns the kind of the json value raw.
+func rawKind(raw json.RawMessage) NodeKind {
+
+	switch raw[0] {
+	case '{':
+		return ObjectNode
+	case '[':
+		return ArrayNode
+	case '"':
+		return StringNode
+	case 't', 'f':
+		return BoolNode
+	case 'n':
+		return NullNode
+	}
+	return NumberNode
+}
+
+// Exists reports whether the value was found.
+func (r Result) Exists() bool {
+	return r.Raw != nil
+}
+
+// String returns a string unquoted and all other values as json. A value
+// which does not exist gives the empty string.
+func (r Result) String() string {
+
+	if r.Kind == StringNode && r.Exists() {
+		var s string
+		if json.Unmarshal(r.Raw, &s) == nil {
+			return s
+		}
+	}
+	return string(r.Raw)
+}
+
+// Float returns a number, a string holding a number or 1 for true. All other
+// values give 0.
+func (r Result) Float() float64 {
+
+	switch r.Kind {
+	case NumberNode:
+		f, _ := strconv.ParseFloat(string(r.Raw), 64)
+		return f
+
+	case StringNode:
+		f, _ := strconv.ParseFloat(r.String(), 64)
+		return f
+
+	case BoolNode:
+		if r.Bool() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Int returns the value of Float as integer, integers beyond the precision of
+// a float64 are parsed exactly.
+func (r Result) Int() int64 {
+
+	var text string
+	switch r.Kind {
+	case NumberNode:
+		text = string(r.Raw)
+	case StringNode:
+		text = r.String()
+	}
+
+	i, err := strconv.ParseInt(text, 10, 64)
+	if err == nil {
+		return i
+	}
+
+	f := r.Float()
+	switch {
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	case f <= math.MinInt64:
+		return math.MinInt64
+	}
+	return int64(f)
+}
+
+// Bool returns a bool, a string holding a bool or whether a number is not 0.
+// All other values give false.
+func (r Result) Bool() bool {
+
+	switch r.Kind {
+	case BoolNode:
+		return string(r.Raw) == `true`
+
+	case StringNode:
+		b, _ := strconv.ParseBool(r.String())
+		return b
+
+	case NumberNode:
+		return r.Float() != 0
+	}
+	return false
+}
+
+// Value returns the value as decoded by json.Unmarshal into an interface{}, nil
+// if it does not exist.
+func (r Result) Value() interface{} {
+
+	if !r.Exists() {
+		return nil
+	}
+
+	var v interface{}
+	_ = json.Unmarshal(r.Raw, &v)
+	return v
+}
+
+// Decode stores the value in the value pointed to by v.
+func (r Result) Decode(v interface{}) error {
+
+	if !r.Exists() {
+		return fmt.Errorf("value does not exist")
+	}
+	return json.Unmarshal(r.Raw, v)
+}
@@ -0,0 +1,149 @@
+package jsonc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+
+	doc := []byte(`{
+  database: {
+    server: "192.168.1.1" // the live db
+    ports: [8001, 8002]
+    "max.conn": "5000"
+    enabled: true
+    big: 9007199254740993
+    none: null
+  }
+}`)
+
+	r, err := Get(doc, `database.server`)
+	require.NoError(t, err)
+	assert.Equal(t, StringNode, r.Kind)
+	assert.Equal(t, `192.168.1.1`, r.String())
+	assert.Equal(t, Span{Start: Pos{Offset: 28, Line: 3, Column: 13}, End: Pos{Offset: 41, Line: 3, Column: 26}}, r.Span)
+
+	r, err = Get(doc, `database.ports.1`)
+	require.NoError(t, err)
+	assert.Equal(t, NumberNode, r.Kind)
+	assert.Equal(t, int64(8002), r.Int())
+	assert.Equal(t, 8002.0, r.Float())
+	assert.Equal(t, `8002`, r.String())
+
+	r, err = Get(doc, `database.max\.conn`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), r.Int())
+
+	r, err = Get(doc, `database.enabled`)
+	require.NoError(t, err)
+	assert.Equal(t, BoolNode, r.Kind)
+	assert.True(t, r.Bool())
+
+	r, err = Get(doc, `database.big`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9007199254740993), r.Int())
+
+	r, err = Get(doc, `database.none`)
+	require.NoError(t, err)
+	assert.True(t, r.Exists())
+	assert.Equal(t, NullNode, r.Kind)
+	assert.Nil(t, r.Value())
+
+	r, err = GetReader(strings.NewReader(string(doc)), `database.ports`)
+	require.NoError(t, err)
+	assert.Equal(t, ArrayNode, r.Kind)
+	assert.Equal(t, `[8001,8002]`, string(r.Raw))
+	assert.Equal(t, []interface{}{8001.0, 8002.0}, r.Value())
+
+	var ports []int
+	require.NoError(t, r.Decode(&ports))
+	assert.Equal(t, []int{8001, 8002}, ports)
+
+	r, err = Get(doc, ``)
+	require.NoError(t, err)
+	assert.Equal(t, ObjectNode, r.Kind)
+
+	for _, path := range []string{`database.missing`, `database.ports.2`, `database.server.x`, `x.y`} {
+		r, err = Get(doc, path)
+		require.NoError(t, err, path)
+		assert.False(t, r.Exists(), path)
+		assert.Equal(t, ``, r.String(), path)
+		assert.Error(t, r.Decode(&ports), path)
+	}
+
+	_, err = Get([]byte(`{a: [}`), `b`)
+	assert.Error(t, err)
+}
+
+func TestSplitPath(t *testing.T) {
+
+	assert.Nil(t, splitPath(``))
+	assert.Equal(t, []string{`a`, `0`, `b`}, splitPath(`a.0.b`))
+	assert.Equal(t, []string{`a.b`, `c\`}, splitPath(`a\.b.c\\`))
+	assert.Equal(t, []string{`a`, ``}, splitPath(`a.`))
+}
+
+func TestDecodePath(t *testing.T) {
+
+	stream := `{db: {ports: [1, 2, {x: 3}], name: alpha}, other: [4]}
+{db: {name: beta}}
+{db: {ports: [5]}}
+[1]`
+
+	dec, err := NewDecoder(strings.NewReader(stream))
+	require.NoError(t, err)
+
+	var x int
+	require.NoError(t, dec.DecodePath(`db.ports.2.x`, &x))
+	assert.Equal(t, 3, x)
+
+	var name string
+	require.NoError(t, dec.DecodePath(`db.name`, &name))
+	assert.Equal(t, `beta`, name)
+
+	err = dec.DecodePath(`db.ports.1`, &x)
+	require.Error(t, err)
+	assert.Equal(t, `line: 3 col: 14 array index 1 out of range`, err.Error())
+
+	err = dec.DecodePath(`db`, &x)
+	require.Error(t, err)
+	assert.Equal(t, `line: 4 col: 1 invalid array index "db"`, err.Error())
+
+	_, err = dec.Token()
+	assert.Error(t, err)
+}
+
+func TestDecodePathErrors(t *testing.T) {
+
+	tests := []struct {
+		doc  string
+		path string
+		err  string
+	}{
+		{doc: `{a: {b: 1}}`, path: `a.c`, err: `line: 1 col: 5 member "c" not found`},
+		{doc: `{a: {b: 1}}`, path: `a.b.c`, err: `line: 1 col: 9 number has no member "c"`},
+		{doc: `{a: [1]}`, path: `a.-`, err: `line: 1 col: 5 invalid array index "-"`},
+		{doc: `{a: [1]}`, path: `a.0`, err: `line: 1 col: 6 cannot unmarshal number into Go value of type string`},
+		{doc: `{a: x}`, path: `a`, err: `line: 1 col: 5 cannot unmarshal string into Go value of type int`},
+	}
+
+	for _, ts := range tests {
+
+		dec, err := NewDecoder(strings.NewReader(ts.doc + ` {after: 1}`))
+		require.NoError(t, err)
+
+		var v int
+		if strings.HasSuffix(ts.path, `0`) {
+			var s string
+			err = dec.DecodePath(ts.path, &s)
+		} else {
+			err = dec.DecodePath(ts.path, &v)
+		}
+		require.Error(t, err, ts.path)
+		assert.Equal(t, ts.err, err.Error(), ts.path)
+	}
+}
@@ -0,0 +1,213 @@
+package jsonc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolver looks up the values of placeholders, as the environment variable
+// HOME of ${env:HOME}. Resolve returns ErrNotFound if there is no value for
+// the key.
+type Resolver interface {
+	Resolve(key string) (string, error)
+}
+
+// ErrNotFound is returned by a Resolver for unknown keys.
+var ErrNotFound = errors.New(`not found`)
+
+// EnvResolver resolves environment variables.
+type EnvResolver struct{}
+
+// Resolve returns the value of the environment variable key.
+func (EnvResolver) Resolve(key string) (string, error) {
+
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return ``, ErrNotFound
+	}
+	return value, nil
+}
+
+// FileResolver resolves file paths to the contents of the files without
+// trailing line breaks. Relative paths are read from Dir, the working
+// directory if it is empty.
+type FileResolver struct {
+	Dir string
+}
+
+// Resolve returns the contents of the file at path.
+func (r FileResolver) Resolve(path string) (string, error) {
+
+	if r.Dir != `` && !filepath.IsAbs(path) {
+		path = filepath.Join(r.Dir, path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ``, ErrNotFound
+	}
+
+	if err != nil {
+		return ``, err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// MapResolver resolves the keys of the map, as in tests.
+type MapResolver map[string]string
+
+// Resolve returns the value of key in the map.
+func (r MapResolver) Resolve(key string) (string, error) {
+
+	value, ok := r[key]
+	if !ok {
+		return ``, ErrNotFound
+	}
+	return value, nil
+}
+
+// InterpolationError is a placeholder which could not be expanded.
+type InterpolationError struct {
+	Pos         Pos
+	Placeholder string
+	Err         error
+}
+
+func (e *InterpolationError) Error() string {
+	return fmt.Sprintf("line: %v col: %v %v: %v", e.Pos.Line, e.Pos.Column, e.Placeholder, e.Err)
+}
+
+func (e *InterpolationError) Unwrap() error {
+	return e.Err
+}
+
+// interpolator expands the placeholders of values.
+// References and their escapes are kept for resolveReferences if references
+// is set.
+type interpolator struct {
+	resolvers  map[string]Resolver
+	references bool
+}
+
+func newInterpolator(resolvers map[string]Resolver, references bool) *interpolator {
+
+	if resolvers == nil {
+		resolvers = map[string]Resolver{`env`: EnvResolver{}, `file`: FileResolver{}}
+	}
+	return &interpolator{resolvers: resolvers, references: references}
+}
+
+// expand returns s with its placeholders replaced. dollars are the locations
+// of the $ signs of s in the jsonc source, errors are located at pos if they
+// do not match.
+func (ip *interpolator) expand(s string, dollars []Pos, pos Pos) (string, error) {
+
+	// escapes in quoted strings may hide $ signs from the filter
+	located := len(dollars) == strings.Count(s, `$`)
+	at := func(k int) Pos {
+		if located {
+			return dollars[k]
+		}
+		return pos
+	}
+
+	buf := &strings.Builder{}
+	var k int // index of the next $ sign
+	for {
+		idx := strings.IndexByte(s, '$')
+		if idx < 0 {
+			buf.WriteString(s)
+			return buf.String(), nil
+		}
+
+		buf.WriteString(s[:idx])
+		s = s[idx:]
+
+		switch {
+		case strings.HasPrefix(s, `$${`):
+			if ip.references {
+				buf.WriteByte('$')
+			}
+			buf.WriteString(`${`)
+			s = s[3:]
+			k += 2
+
+		case strings.HasPrefix(s, `${`):
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				return ``, &InterpolationError{Pos: at(k), Placeholder: s, Err: errors.New(`placeholder is not closed`)}
+			}
+
+			if ip.references && strings.HasPrefix(s, `${.`) {
+				buf.WriteString(s[:end+1])
+				k += strings.Count(s[:end+1], `$`)
+				s = s[end+1:]
+				continue
+			}
+
+			value, err := ip.resolve(s[2:end])
+			if err != nil {
+				return ``, &InterpolationError{Pos: at(k), Placeholder: s[:end+1], Err: err}
+			}
+
+			buf.WriteString(value)
+			k += strings.Count(s[:end+1], `$`)
+			s = s[end+1:]
+
+		default:
+			buf.WriteByte('$')
+			s = s[1:]
+			k++
+		}
+	}
+}
+
+// resolve returns the value of the placeholder expression expr, which is
+// written as [scheme:]key[:-default].
+func (ip *interpolator) resolve(expr string) (string, error) {
+
+	def, hasDefault := ``, false
+	if idx := strings.Index(expr, `:-`); idx >= 0 {
+		expr, def, hasDefault = expr[:idx], expr[idx+2:], true
+	}
+
+	scheme, key := `env`, expr
+	if idx := strings.IndexByte(expr, ':'); idx >= 0 {
+		scheme, key = expr[:idx], expr[idx+1:]
+	}
+
+	r, ok := ip.resolvers[scheme]
+	if !ok {
+		return ``, fmt.Errorf("no resolver for %q", scheme)
+	}
+
+	value, err := r.Resolve(key)
+	if hasDefault && (errors.Is(err, ErrNotFound) || (err == nil && value == ``)) {
+		return def, nil
+	}
+	return value, err
+}
+
+// interpolate expands the placeholders of the json string held back at the
+// end of the output.
+func (f *Filter) interpolate() error {
+
+	var s string
+	err := json.Unmarshal(f.outbuf[f.hold:], &s)
+	if err != nil || !strings.Contains(s, `$`) {
+		return nil
+	}
+
+	s, err = f.interp.expand(s, f.dollars, f.valueStart)
+	if err != nil {
+		return err
+	}
+
+	f.outbuf = appendQuoted(f.outbuf[:f.hold], s)
+	return nil
+}
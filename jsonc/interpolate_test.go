@@ -0,0 +1,162 @@
+package jsonc
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testResolvers = map[string]Resolver{
+	`env`:    MapResolver{`HOST`: `db.local`, `PORT`: `5432`, `EMPTY`: ``, `QUOTE`: `a"b\c`, `DEBUG`: `true`},
+	`secret`: MapResolver{`db/password`: `s3cr3t`},
+}
+
+func TestInterpolate(t *testing.T) {
+
+	tests := []struct {
+		doc  string
+		json string
+	}{
+		{doc: `{a: "${HOST}:${PORT}"}`, json: `{"a":"db.local:5432"}`},
+		{doc: `{a: ${PORT}, b: ${DEBUG}, c: ${HOST}}`, json: `{"a":5432,"b":true,"c":"db.local"}`},
+		{doc: `{a: ${env:HOST}, b: "${secret:db/password}"}`, json: `{"a":"db.local","b":"s3cr3t"}`},
+		{doc: `{a: ${NAME:-app}, b: "${EMPTY:-none}", c: ${PORT:-80}, d: "${NAME:-}"}`, json: `{"a":"app","b":"none","c":5432,"d":""}`},
+		{doc: `{a: "${QUOTE}", b: ${QUOTE}}`, json: `{"a":"a\"b\\c","b":"a\"b\\c"}`},
+		{doc: `{a: "$${HOST} costs $5", b: "${HOST}"}`, json: `{"a":"${HOST} costs $5","b":"db.local"}`},
+		{doc: "{a: `${HOST}\n${PORT}`}", json: `{"a":"db.local\n5432"}`},
+		{doc: `[x${PORT}, ${NAME:-two words}, ${EMPTY}]`, json: `["x5432","two words",""]`},
+		{doc: `{"${HOST}": 1}`, json: `{"${HOST}":1}`},
+	}
+
+	for _, ts := range tests {
+
+		out, err := ToJSON([]byte(ts.doc), Interpolate(testResolvers))
+		require.NoError(t, err, ts.doc)
+		assert.Equal(t, ts.json, string(out), ts.doc)
+
+		// held back strings span reads
+		dec, err := NewDecoder(strings.NewReader(ts.doc), Interpolate(testResolvers), OutputSize(1))
+		require.NoError(t, err)
+
+		var v interface{}
+		require.NoError(t, dec.Decode(&v), ts.doc)
+
+		var expected interface{}
+		require.NoError(t, Unmarshal([]byte(ts.json), &expected))
+		assert.Equal(t, expected, v, ts.doc)
+	}
+
+	// without the option placeholders are text
+	out, err := ToJSON([]byte(`{a: "${HOST}"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"${HOST}"}`, string(out))
+
+	doc := "{\n  a: ${HOST}\n  b: \"${PORT:-80}\"\n}"
+	out, err = Format([]byte(doc), Interpolate(testResolvers))
+	require.NoError(t, err)
+	assert.Equal(t, doc, string(out))
+
+	_, err = ToJSON([]byte(`{"a": ${PORT}}`), Interpolate(testResolvers), WithDialect(JSON))
+	assert.Error(t, err)
+
+	out, err = ToJSON([]byte(`{"a": "${PORT}"}`), Interpolate(testResolvers), WithDialect(JSON))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"5432"}`, string(out))
+}
+
+func TestInterpolateStream(t *testing.T) {
+
+	dec, err := NewDecoder(strings.NewReader("{a: \"${HOST}\"}\n{a: ${PORT}}"), Interpolate(testResolvers), OutputSize(3))
+	require.NoError(t, err)
+
+	var values []interface{}
+	for {
+		var v interface{}
+		err = dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		values = append(values, v)
+	}
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{`a`: `db.local`},
+		map[string]interface{}{`a`: float64(5432)},
+	}, values)
+}
+
+func TestInterpolateErrors(t *testing.T) {
+
+	tests := []struct {
+		doc  string
+		line int
+		col  int
+		err  string
+	}{
+		{doc: "{\n  a: \"x ${MISSING}\"\n}", line: 2, col: 9, err: `${MISSING}: not found`},
+		{doc: "{\n  a: ${MISSING}\n}", line: 2, col: 6, err: `${MISSING}: not found`},
+		{doc: "{\n  a: \"${HOST\"\n}", line: 2, col: 7, err: `${HOST: placeholder is not closed`},
+		{doc: "{\n  a: ${vault:x}\n}", line: 2, col: 6, err: `${vault:x}: no resolver for "vault"`},
+		{doc: "{\n  a: `one\n    ${HOST} ${NOPE}`\n}", line: 3, col: 13, err: `${NOPE}: not found`},
+	}
+
+	for _, ts := range tests {
+
+		var v interface{}
+		err := Unmarshal([]byte(ts.doc), &v, Interpolate(testResolvers))
+		require.Error(t, err, ts.doc)
+
+		var ierr *InterpolationError
+		require.True(t, errors.As(err, &ierr), ts.doc)
+		assert.Equal(t, ts.line, ierr.Pos.Line, ts.doc)
+		assert.Equal(t, ts.col, ierr.Pos.Column, ts.doc)
+		assert.Contains(t, err.Error(), ts.err, ts.doc)
+	}
+
+	_, err := ToJSON([]byte(`{a: "${MISSING}"}`), Interpolate(testResolvers))
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestResolvers(t *testing.T) {
+
+	dir, err := ioutil.TempDir(``, `jsonc`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, `cert.pem`), []byte("-----BEGIN-----\nabc\n-----END-----\n"), 0600))
+
+	value, err := FileResolver{Dir: dir}.Resolve(`./cert.pem`)
+	require.NoError(t, err)
+	assert.Equal(t, "-----BEGIN-----\nabc\n-----END-----", value)
+
+	value, err = FileResolver{}.Resolve(filepath.Join(dir, `cert.pem`))
+	require.NoError(t, err)
+	assert.Equal(t, "-----BEGIN-----\nabc\n-----END-----", value)
+
+	_, err = FileResolver{Dir: dir}.Resolve(`missing.pem`)
+	assert.Equal(t, ErrNotFound, err)
+
+	key := `JSONC_TEST_INTERPOLATE`
+	os.Setenv(key, `from env`)
+	defer os.Unsetenv(key)
+
+	value, err = EnvResolver{}.Resolve(key)
+	require.NoError(t, err)
+	assert.Equal(t, `from env`, value)
+
+	_, err = EnvResolver{}.Resolve(key + `_MISSING`)
+	assert.Equal(t, ErrNotFound, err)
+
+	// the default resolvers
+	out, err := ToJSON([]byte(`{a: "${`+key+`}", b: "${env:`+key+`}", c: "${file:`+filepath.Join(dir, `cert.pem`)+`}"}`), Interpolate(nil))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"from env","b":"from env","c":"-----BEGIN-----\nabc\n-----END-----"}`, string(out))
+}
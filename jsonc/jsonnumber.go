@@ -1,6 +1,9 @@
 package jsonc
 
-import "strings"
+import (
+	"math/big"
+	"strings"
+)
 
 type NumberState int
 
@@ -105,3 +108,45 @@ func IsNumber(value string) bool {
 	return (s == Digit && strings.ContainsRune(Numbers, lastRune)) ||
 		s == ZeroStart
 }
+
+// json5Number translates the JSON5 number value to json. Hexadecimal numbers
+// become decimal ones, Infinity and NaN become null.
+func json5Number(value string) (string, bool) {
+
+	sign, body := ``, value
+	if strings.HasPrefix(body, `+`) || strings.HasPrefix(body, `-`) {
+		sign, body = strings.TrimPrefix(body[:1], `+`), body[1:]
+	}
+
+	switch {
+	case body == `Infinity` || body == `NaN`:
+		return `null`, true
+
+	case strings.HasPrefix(body, `0x`) || strings.HasPrefix(body, `0X`):
+		n, ok := new(big.Int).SetString(body[2:], 16)
+		if !ok || strings.ContainsAny(body[2:], `+-`) {
+			return ``, false
+		}
+
+		if sign == `-` {
+			n.Neg(n)
+		}
+		return n.String(), true
+	}
+
+	mantissa, exponent := body, ``
+	if idx := strings.IndexAny(body, `eE`); idx >= 0 {
+		mantissa, exponent = body[:idx], body[idx:]
+	}
+
+	if strings.HasPrefix(mantissa, `.`) {
+		mantissa = `0` + mantissa
+	}
+	mantissa = strings.TrimSuffix(mantissa, `.`)
+
+	number := sign + mantissa + exponent
+	if !IsNumber(number) {
+		return ``, false
+	}
+	return number, true
+}
@@ -0,0 +1,324 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Span is the location of a token in a jsonc document, End lies right after
+// its last character.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// Match is the value a JSON Pointer refers to in a jsonc document.
+type Match struct {
+	Value interface{}     // the value as decoded by json.Unmarshal into an interface{}
+	JSON  json.RawMessage // the value as json
+	Key   Span            // the key of a member, zero for array elements and the document
+	Span  Span            // the value including its quotes or braces
+}
+
+// Decode stores the matched value in the value pointed to by v.
+func (m *Match) Decode(v interface{}) error {
+	return json.Unmarshal(m.JSON, v)
+}
+
+// Lookup returns the value the RFC 6901 JSON Pointer pointer refers to in the
+// jsonc document data. See LookupReader.
+func Lookup(data []byte, pointer string, opts ...Option) (*Match, error) {
+	return LookupReader(bytes.NewReader(data), pointer, opts...)
+}
+
+// LookupReader reads a jsonc document from r up to the value the RFC 6901
+// JSON Pointer pointer refers to and returns it with its location. Members and
+// elements off the path are skipped without decoding them. Of duplicate
+// members the last one counts as for Decode, the input is read until the
+// objects on the path are complete.
+//
+// A missing member or element is reported as Error located at the object or
+// array which lacks it.
+func LookupReader(r io.Reader, pointer string, opts ...Option) (*Match, error) {
+
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := lookup(r, tokens, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.match, nil
+}
+
+// lookup reads the document from r up to the value tokens refer to. Errors
+// of the match itself are left in the matcher.
+func lookup(r io.Reader, tokens []string, opts []Option) (*matcher, error) {
+
+	c := newConfig(opts)
+	in := &capture{r: runeReader(r)}
+	f, err := c.filter(in.ReadRune, false, ``)
+	if errors.Is(err, io.EOF) {
+		return nil, errEmpty
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	m := &matcher{tokens: tokens, in: in, useNumber: c.useNumber}
+	f.tokens = m
+
+	_, err = io.Copy(ioutil.Discard, f)
+	if errors.Is(err, errMatched) {
+		return m, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.Done() {
+		return nil, f.unexpectedEOF()
+	}
+	return nil, errEmpty
+}
+
+// errMatched stops the Filter of a matcher.
+var errMatched = errors.New(`jsonc pointer matched`)
+
+// matcher is a tokenHandler finding the value of a JSON Pointer. It follows
+// the nesting of the document and keeps the input of the current key and the
+// matched value only.
+type matcher struct {
+	tokens    []string
+	in        *capture
+	useNumber bool
+
+	stack []frame
+	key   Span
+	name  string
+
+	start  Pos  // start of the matched value
+	inside bool // the matched value is being read
+
+	scalarStart  Pos
+	scalarOnPath bool // the current scalar is on the path of the pointer
+
+	match   *Match
+	err     error
+	missing bool // the value does not exist
+}
+
+// frame is an object or array the matcher is in.
+type frame struct {
+	start  Pos
+	array  bool
+	index  int  // index of the next element
+	onPath bool // the container is on the path of the pointer
+}
+
+func (m *matcher) begin(kind NodeKind, pos Pos) error {
+
+	switch kind {
+	case LineCommentNode, BlockCommentNode:
+		return nil
+
+	case KeyNode:
+		if !m.inside {
+			m.in.discard(pos.Offset)
+		}
+		m.key = Span{Start: pos}
+		return nil
+	}
+
+	if !m.inside {
+		m.in.discard(pos.Offset)
+	}
+
+	depth := len(m.stack)
+	onPath := true
+	var key Span
+
+	if depth > 0 {
+		top := &m.stack[depth-1]
+		token := m.name
+		if top.array {
+			token = strconv.Itoa(top.index)
+			top.index++
+		} else {
+			key = m.key
+		}
+		onPath = !m.inside && top.onPath && m.tokens[depth-1] == token
+	}
+
+	// a duplicate member replaces the value found before
+	if onPath {
+		m.match, m.err, m.missing = nil, nil, false
+	}
+
+	if onPath && depth == len(m.tokens) {
+		m.inside = true
+		m.start = pos
+		m.match = &Match{Key: key}
+	}
+
+	if kind == ObjectNode || kind == ArrayNode {
+		m.stack = append(m.stack, frame{
+			start:  pos,
+			array:  kind == ArrayNode,
+			onPath: onPath && depth < len(m.tokens),
+		})
+		return nil
+	}
+
+	m.scalarOnPath = onPath && depth < len(m.tokens)
+	m.scalarStart = pos
+	return nil
+}
+
+func (m *matcher) end(kind NodeKind, pos Pos) error {
+
+	switch kind {
+	case LineCommentNode, BlockCommentNode:
+		return nil
+
+	case KeyNode:
+		key := m.scalar(KeyNode, m.key.Start, pos)
+		m.key.End = key.End
+		m.name = key.Name()
+		return nil
+
+	case ObjectNode, ArrayNode:
+		top := m.stack[len(m.stack)-1]
+		m.stack = m.stack[:len(m.stack)-1]
+
+		switch {
+		case m.inside && len(m.stack) == len(m.tokens):
+			return m.matched(pos)
+
+		case top.onPath && m.match == nil && m.err == nil:
+			token := m.tokens[len(m.stack)]
+			m.missing = true
+			if top.array {
+				// no element matched, the index is invalid or out of range
+				_, err := arrayIndex(token, top.index, false)
+				m.err = newError(top.start, 0, ``, "%v", err)
+				return m.done()
+			}
+			m.err = newError(top.start, 0, ``, "member %q not found", token)
+			return m.done()
+
+		case top.onPath:
+			return m.done()
+		}
+		return nil
+	}
+
+	if m.inside && len(m.stack) == len(m.tokens) {
+		return m.matched(m.scalar(StringNode, m.start, pos).End)
+	}
+
+	// a scalar on the path ends it
+	if m.scalarOnPath {
+		n := m.scalar(StringNode, m.scalarStart, pos)
+		m.err = newError(n.Start, 0, ``, "%v has no member %q", n.Kind, m.tokens[len(m.stack)])
+		m.missing = true
+		return m.done()
+	}
+	return nil
+}
+
+// done stops the Filter once no object on the path is open, which could
+// hold a duplicate of a member on the path.
+func (m *matcher) done() error {
+
+	for _, fr := range m.stack {
+		if fr.onPath && !fr.array {
+			return nil
+		}
+	}
+	return errMatched
+}
+
+// matched completes the match of the value ending at end.
+func (m *matcher) matched(end Pos) error {
+
+	m.inside = false
+	m.match.Span = Span{Start: m.start, End: end}
+
+	raw, err := ToJSON(wrap(m.in.text(m.start.Offset, end.Offset)))
+	if err != nil {
+		m.err = err
+		return m.done()
+	}
+	m.match.JSON = raw[1 : len(raw)-1]
+
+	dec := json.NewDecoder(bytes.NewReader(m.match.JSON))
+	if m.useNumber {
+		dec.UseNumber()
+	}
+	m.err = dec.Decode(&m.match.Value)
+	return m.done()
+}
+
+// scalar returns the key or scalar read from start to end.
+func (m *matcher) scalar(kind NodeKind, start, end Pos) *Node {
+
+	text := trimControl(string(m.in.text(start.Offset, end.Offset)))
+	n := &Node{Kind: kind, Start: start, End: advance(start, text), Text: text}
+	classify(n)
+	return n
+}
+
+// capture is a rune reader recording its input from an offset on.
+type capture struct {
+	r    io.RuneReader
+	base int // offset of buf
+	buf  []byte
+}
+
+func (c *capture) ReadRune() (rune, int, error) {
+
+	ru, size, err := c.r.ReadRune()
+	if err != nil {
+		return ru, size, err
+	}
+
+	var b [utf8.UTFMax]byte
+	n := utf8.EncodeRune(b[:], ru)
+	if n != size {
+		// keep the offsets of invalid input
+		n = copy(b[:], bytes.Repeat([]byte{'?'}, size))
+	}
+	c.buf = append(c.buf, b[:n]...)
+	return ru, size, nil
+}
+
+// discard drops the input before offset.
+func (c *capture) discard(offset int) {
+
+	if offset <= c.base {
+		return
+	}
+
+	n := copy(c.buf, c.buf[offset-c.base:])
+	c.buf = c.buf[:n]
+	c.base = offset
+}
+
+// text returns the input from start to end.
+func (c *capture) text(start, end int) []byte {
+	return c.buf[start-c.base : end-c.base]
+}
@@ -0,0 +1,207 @@
+package jsonc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lookupDoc = `// service config
+{
+  owner: {name: komkom} // the owner
+  database: {
+    server: "192.168.1.1"
+    ports: [8001, 8002 /* backup */, 8003]
+    "a/b": {"c~d": true}
+    motd: ` + "`hello\nworld`" + `
+  }
+  empty: null
+}
+`
+
+func TestLookup(t *testing.T) {
+
+	tests := []struct {
+		pointer string
+		value   interface{}
+		json    string
+		key     Span
+		span    Span
+	}{
+		{
+			pointer: `/database/ports/1`,
+			value:   8002.0,
+			json:    `8002`,
+			span:    Span{Start: Pos{Offset: 115, Line: 6, Column: 19}, End: Pos{Offset: 119, Line: 6, Column: 23}},
+		},
+		{
+			pointer: `/database/server`,
+			value:   `192.168.1.1`,
+			json:    `"192.168.1.1"`,
+			key:     Span{Start: Pos{Offset: 75, Line: 5, Column: 5}, End: Pos{Offset: 81, Line: 5, Column: 11}},
+			span:    Span{Start: Pos{Offset: 83, Line: 5, Column: 13}, End: Pos{Offset: 96, Line: 5, Column: 26}},
+		},
+		{
+			pointer: `/owner`,
+			value:   map[string]interface{}{`name`: `komkom`},
+			json:    `{"name":"komkom"}`,
+			key:     Span{Start: Pos{Offset: 22, Line: 3, Column: 3}, End: Pos{Offset: 27, Line: 3, Column: 8}},
+			span:    Span{Start: Pos{Offset: 29, Line: 3, Column: 10}, End: Pos{Offset: 43, Line: 3, Column: 24}},
+		},
+		{
+			pointer: `/database/ports`,
+			value:   []interface{}{8001.0, 8002.0, 8003.0},
+			json:    `[8001,8002,8003]`,
+		},
+		{
+			pointer: `/database/a~1b/c~0d`,
+			value:   true,
+			json:    `true`,
+		},
+		{
+			pointer: `/database/motd`,
+			value:   "hello\nworld",
+			json:    `"hello\nworld"`,
+			span:    Span{Start: Pos{Offset: 175, Line: 8, Column: 11}, End: Pos{Offset: 188, Line: 9, Column: 7}},
+		},
+		{
+			pointer: `/empty`,
+			json:    `null`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		m, err := Lookup([]byte(lookupDoc), ts.pointer)
+		require.NoError(t, err, ts.pointer)
+		assert.Equal(t, ts.value, m.Value, ts.pointer)
+		assert.Equal(t, ts.json, string(m.JSON), ts.pointer)
+
+		if ts.key != (Span{}) {
+			assert.Equal(t, ts.key, m.Key, ts.pointer)
+		}
+
+		if ts.span != (Span{}) {
+			assert.Equal(t, ts.span, m.Span, ts.pointer)
+			assert.Equal(t, ts.json, mustJSON(t, lookupDoc[m.Span.Start.Offset:m.Span.End.Offset]), ts.pointer)
+		}
+
+		// the same in a stream read byte by byte
+		m, err = LookupReader(iotest.OneByteReader(strings.NewReader(lookupDoc)), ts.pointer)
+		require.NoError(t, err, ts.pointer)
+		assert.Equal(t, ts.json, string(m.JSON), ts.pointer)
+	}
+}
+
+func mustJSON(t *testing.T, value string) string {
+
+	out, err := ToJSON([]byte(`[` + value + `]`))
+	require.NoError(t, err)
+	return string(out[1 : len(out)-1])
+}
+
+func TestLookupDocument(t *testing.T) {
+
+	m, err := Lookup([]byte(`/* all */ [1, {a: b}]`), ``)
+	require.NoError(t, err)
+	assert.Equal(t, `[1,{"a":"b"}]`, string(m.JSON))
+	assert.Equal(t, Span{}, m.Key)
+	assert.Equal(t, Pos{Offset: 10, Line: 1, Column: 11}, m.Span.Start)
+
+	m, err = Lookup([]byte(`{a: 1}`), `/a`, UseNumber())
+	require.NoError(t, err)
+	assert.Equal(t, json.Number(`1`), m.Value)
+
+	var n int
+	require.NoError(t, m.Decode(&n))
+	assert.Equal(t, 1, n)
+}
+
+func TestLookupStopsEarly(t *testing.T) {
+
+	// the syntax error behind the value is never read, no later member can
+	// replace it
+	m, err := Lookup([]byte(`[{a: {b: 1}}, [}`), `/0/a/b`)
+	require.NoError(t, err)
+	assert.Equal(t, `1`, string(m.JSON))
+
+	// a later duplicate could
+	_, err = Lookup([]byte(`{a: {b: 1}, c: [}`), `/a/b`)
+	assert.Error(t, err)
+}
+
+func TestLookupDuplicates(t *testing.T) {
+
+	tests := []struct {
+		doc     string
+		pointer string
+		json    string
+	}{
+		{doc: `{a: 1, a: 2}`, pointer: `/a`, json: `2`},
+		{doc: `{a: {b: 1, b: 3}}`, pointer: `/a/b`, json: `3`},
+		{doc: `{a: {c: 1}, a: {b: 2}}`, pointer: `/a/b`, json: `2`},
+		{doc: `{a: 1, a: [5]}`, pointer: `/a/0`, json: `5`},
+		{doc: `[{a: 1, a: 2}, 3]`, pointer: `/0/a`, json: `2`},
+	}
+
+	for _, ts := range tests {
+
+		m, err := Lookup([]byte(ts.doc), ts.pointer)
+		require.NoError(t, err, ts.doc)
+		assert.Equal(t, ts.json, string(m.JSON), ts.doc)
+
+		r, err := Get([]byte(ts.doc), strings.ReplaceAll(ts.pointer[1:], `/`, `.`))
+		require.NoError(t, err, ts.doc)
+		assert.Equal(t, ts.json, string(r.Raw), ts.doc)
+	}
+
+	// the member of the last duplicate is missing
+	_, err := Lookup([]byte(`{a: {b: 1}, a: {c: 2}}`), `/a/b`)
+	assert.EqualError(t, err, `line: 1 col: 16 member "b" not found`)
+
+	r, err := Get([]byte(`{a: {b: 1}, a: {c: 2}}`), `a.b`)
+	require.NoError(t, err)
+	assert.False(t, r.Exists())
+}
+
+func TestLookupErrors(t *testing.T) {
+
+	tests := []struct {
+		doc     string
+		pointer string
+		err     string
+	}{
+		{doc: lookupDoc, pointer: `/database/missing`, err: `line: 4 col: 13 member "missing" not found`},
+		{doc: lookupDoc, pointer: `/database/ports/3`, err: `line: 6 col: 12 array index 3 out of range`},
+		{doc: lookupDoc, pointer: `/database/ports/-`, err: `line: 6 col: 12 invalid array index "-"`},
+		{doc: lookupDoc, pointer: `/database/ports/01`, err: `line: 6 col: 12 invalid array index "01"`},
+		{doc: lookupDoc, pointer: `/owner/name/first`, err: `line: 3 col: 17 string has no member "first"`},
+		{doc: lookupDoc, pointer: `/empty/x`, err: `line: 11 col: 10 null has no member "x"`},
+		{doc: lookupDoc, pointer: `database`, err: `json pointer "database" does not start with /`},
+		{doc: `{a: [1, 2}`, pointer: `/b`, err: `line: 1 col: 10 empty no quote state`},
+		{doc: `{a: 1`, pointer: `/b`, err: `line: 1 col: 6 unexpected end of input`},
+		{doc: ``, pointer: `/b`, err: `line: 1 col: 1 unexpected end of input`},
+	}
+
+	for _, ts := range tests {
+
+		m, err := Lookup([]byte(ts.doc), ts.pointer)
+		require.Error(t, err, ts.pointer)
+		assert.Nil(t, m, ts.pointer)
+		assert.Equal(t, ts.err, err.Error(), ts.pointer)
+	}
+}
+
+func TestEscapePointer(t *testing.T) {
+
+	name := `a/b~c`
+	assert.Equal(t, `a~1b~0c`, EscapePointer(name))
+
+	m, err := Lookup([]byte(`{"a/b~c": 1}`), `/`+EscapePointer(name))
+	require.NoError(t, err)
+	assert.Equal(t, `1`, string(m.JSON))
+}
@@ -0,0 +1,484 @@
+package jsonc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ArrayStrategy selects how Merge combines an array of the base document with
+// the array at the same place of an overlay.
+type ArrayStrategy int
+
+const (
+	// ReplaceArrays replaces the array by the one of the overlay. It is the
+	// default.
+	ReplaceArrays ArrayStrategy = iota
+
+	// AppendArrays appends the elements of the overlay.
+	AppendArrays
+
+	// MergeArraysByKey merges objects which have the same value of the key
+	// member, see MergeKey, and appends the other elements. Scalars already
+	// in the array are not appended again.
+	MergeArraysByKey
+)
+
+func (s ArrayStrategy) String() string {
+	switch s {
+	case ReplaceArrays:
+		return `replace`
+	case AppendArrays:
+		return `append`
+	case MergeArraysByKey:
+		return `merge`
+	}
+	return `unknown`
+}
+
+// Merge returns the document base with the overlays merged into it in order,
+// as NewMerger().Merge does.
+func Merge(base []byte, overlays ...[]byte) ([]byte, error) {
+	return NewMerger().Merge(base, overlays...)
+}
+
+// Merger merges jsonc documents.
+type Merger struct {
+	opts []Option
+}
+
+// NewMerger returns a Merger. The options MergeArrays, MergeKey and
+// WithDialect apply.
+func NewMerger(opts ...Option) *Merger {
+	return &Merger{opts: opts}
+}
+
+// Merge returns the document base with the overlays merged into it in order.
+// The text is edited as by Update, so the layout of base stays.
+//
+// A base which is strict json stays strict json, the overlays lose their
+// comments then.
+//
+// Objects are merged member by member, a member of an overlay which is null
+// deletes the member of base and is left out of new values. Arrays are
+// combined by the ArrayStrategy, all other values are replaced. New members
+// and elements are inserted with the comments above them. The comments above
+// a member of an overlay are added to those of base, a comment behind it on
+// its line replaces the one of base.
+func (m *Merger) Merge(base []byte, overlays ...[]byte) ([]byte, error) {
+
+	root, err := Parse(base, m.opts...)
+	if err != nil {
+		return nil, fmt.Errorf("base: %w", err)
+	}
+
+	// the comma style of base holds for all layers, even if the members with
+	// commas were deleted
+	commas := hasComma(root)
+
+	out := base
+	for idx, overlay := range overlays {
+		out, err = m.merge(out, overlay, commas)
+		if err != nil {
+			return nil, fmt.Errorf("overlay %v: %w", idx+1, err)
+		}
+	}
+	return out, nil
+}
+
+func (m *Merger) merge(doc, overlay []byte, commas bool) ([]byte, error) {
+
+	over, err := Parse(overlay, m.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	value := over.Value()
+	if value == nil {
+		return doc, nil
+	}
+
+	root, err := Parse(doc, m.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConfig(m.opts)
+	e := newEditor(doc, root, m.opts)
+	e.commas = e.commas || commas
+
+	// strict json stays strict json, the overlay is written as json as Update
+	// writes new values
+	if e.dialect == JSON && !Valid(overlay, WithDialect(JSON)) {
+
+		overlay, err = Marshal(value, Space(e.unit), WithDialect(JSON))
+		if err != nil {
+			return nil, err
+		}
+
+		over, err = Parse(overlay, m.opts...)
+		if err != nil {
+			return nil, err
+		}
+		value = over.Value()
+	}
+
+	ms := &mergeState{
+		e:      e,
+		o:      newEditor(overlay, over, m.opts),
+		src:    overlay,
+		arrays: c.arrays,
+		key:    c.mergeKey,
+	}
+
+	old := root.Value()
+	if old == nil {
+		ms.o.stripNulls(value)
+		text, err := ms.o.bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		out := append([]byte{}, doc...)
+		if len(out) > 0 && out[len(out)-1] != '\n' {
+			out = append(out, '\n')
+		}
+		return append(out, text...), nil
+	}
+
+	err = ms.merge(root, old, value)
+	if err != nil {
+		return nil, err
+	}
+	return ms.e.bytes()
+}
+
+// mergeState merges an overlay into a document. e edits the document, o the
+// overlay read from src.
+type mergeState struct {
+	e      *editor
+	o      *editor
+	src    []byte
+	arrays ArrayStrategy
+	key    string
+}
+
+// merge merges the value of the overlay into the value old with the parent
+// node.
+func (ms *mergeState) merge(parent, old, value *Node) error {
+
+	switch {
+	case old.Kind == ObjectNode && value.Kind == ObjectNode:
+		return ms.object(old, value)
+
+	case old.Kind == ArrayNode && value.Kind == ArrayNode && ms.arrays != ReplaceArrays:
+		return ms.array(old, value)
+	}
+
+	ms.o.stripNulls(value)
+	return ms.e.set(parent, old, value, ms.src)
+}
+
+func (ms *mergeState) object(old, value *Node) error {
+
+	list := items(old)
+	entries := make([]*Node, len(list))
+
+	// the last member of a name is the one decoded
+	names := map[string]int{}
+	for k, it := range list {
+		entries[k] = old.Children[it.entry]
+		names[entries[k].Name()] = k
+	}
+
+	last := map[string]*Node{}
+	for _, m := range value.Members() {
+		last[m.Name()] = m
+	}
+
+	ms.headerComments(old, value)
+
+	// new members follow the member in front of them in the overlay, or go
+	// to the end
+	inserts := map[int][]int{}
+	var removes []int
+	anchor := len(list) - 1
+
+	for nk, it := range items(value) {
+
+		m := value.Children[it.entry]
+		v := m.Value()
+		if last[m.Name()] != m || v == nil {
+			continue
+		}
+
+		k, ok := names[m.Name()]
+		switch {
+		case v.Kind == NullNode:
+			if ok {
+				removes = append(removes, k)
+			}
+			continue
+
+		case !ok:
+			ms.o.stripNulls(v)
+			inserts[anchor] = append(inserts[anchor], nk)
+			continue
+		}
+		anchor = k
+
+		err := ms.merge(entries[k], entries[k].Value(), v)
+		if err != nil {
+			return err
+		}
+		ms.comments(old, entries[k], value, m)
+	}
+
+	err := ms.e.edit(old, removes, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	// the anchors count the members left
+	shifted := map[int][]int{}
+	for anchor := -1; anchor < len(list); anchor++ {
+
+		if len(inserts[anchor]) == 0 {
+			continue
+		}
+
+		k := anchor
+		for _, r := range removes {
+			if r <= anchor {
+				k--
+			}
+		}
+		shifted[k] = append(shifted[k], inserts[anchor]...)
+	}
+	return ms.e.edit(old, nil, shifted, value, ms.src)
+}
+
+func (ms *mergeState) array(old, value *Node) error {
+
+	list := items(old)
+	entries := make([]*Node, len(list))
+	for k, it := range list {
+		entries[k] = old.Children[it.entry]
+	}
+
+	ms.headerComments(old, value)
+
+	inserts := map[int][]int{}
+	for nk, it := range items(value) {
+
+		v := value.Children[it.entry]
+		if ms.arrays == MergeArraysByKey {
+
+			k, err := ms.find(entries, v)
+			if err != nil {
+				return err
+			}
+
+			if k >= 0 {
+				err = ms.merge(old, entries[k], v)
+				if err != nil {
+					return err
+				}
+				ms.comments(old, entries[k], value, v)
+				continue
+			}
+		}
+
+		ms.o.stripNulls(v)
+		inserts[len(list)-1] = append(inserts[len(list)-1], nk)
+	}
+
+	return ms.e.edit(old, nil, inserts, value, ms.src)
+}
+
+// find returns the index of the entry an element of an overlay array merges
+// with, or -1. Objects match by their key member, other values by value.
+func (ms *mergeState) find(entries []*Node, v *Node) (int, error) {
+
+	var key *Node
+	if v.Kind == ObjectNode {
+		m := v.Lookup(ms.key)
+		if m == nil || m.Value() == nil {
+			return -1, nil
+		}
+		key = m.Value()
+	}
+
+	for k, entry := range entries {
+
+		a, b := entry, v
+		if key != nil {
+			if entry.Kind != ObjectNode {
+				continue
+			}
+
+			m := entry.Lookup(ms.key)
+			if m == nil || m.Value() == nil {
+				continue
+			}
+			a, b = m.Value(), key
+		}
+
+		same, err := sameValue(a, b)
+		if err != nil {
+			return -1, err
+		}
+
+		if same {
+			return k, nil
+		}
+	}
+	return -1, nil
+}
+
+// comments adds the comments of the entry ventry of the overlay container
+// value to the entry of the container c. Comments above ventry which are not
+// above entry yet are added, the comments behind ventry on its line replace
+// those behind entry. Line comments are only added where a line ends behind
+// them.
+func (ms *mergeState) comments(c, entry, value, ventry *Node) {
+
+	it, ok := itemOf(c, entry)
+	vit, vok := itemOf(value, ventry)
+	if !ok || !vok {
+		return
+	}
+
+	var trailing []*Node
+	for i := vit.entry + 1; i < vit.end; i++ {
+		if n := value.Children[i]; n.IsComment() {
+			trailing = append(trailing, &Node{Kind: n.Kind, Text: n.Text})
+		}
+	}
+
+	lineEnd := it.end < len(c.Children)-1 && strings.HasPrefix(c.Children[it.end].Text, "\n")
+	if len(trailing) > 0 && lineEnd {
+
+		var nodes []*Node
+		if it.comma >= 0 {
+			nodes = append(nodes, c.Children[it.comma])
+		}
+
+		for _, n := range trailing {
+			nodes = append(nodes, &Node{Kind: SpaceNode, Text: ` `}, n)
+		}
+		c.Children = splice(c.Children, it.entry+1, it.end, nodes)
+	}
+
+	// comments above an entry need it to start a line
+	startsLine := false
+	for i := it.start; i < it.entry; i++ {
+		if n := c.Children[i]; n.Kind == SpaceNode && strings.Contains(n.Text, "\n") {
+			startsLine = true
+		}
+	}
+
+	if !startsLine {
+		return
+	}
+
+	existing := map[string]bool{}
+	for i := attachedStart(c, it); i < it.entry; i++ {
+		if n := c.Children[i]; n.IsComment() {
+			existing[strings.TrimSpace(n.Text)] = true
+		}
+	}
+
+	indent := lineIndent(ms.e.src, entry.Start.Offset)
+	var nodes []*Node
+	for i := attachedStart(value, vit); i < vit.entry; i++ {
+
+		n := value.Children[i]
+		if !n.IsComment() || existing[strings.TrimSpace(n.Text)] {
+			continue
+		}
+
+		nodes = append(nodes,
+			&Node{Kind: n.Kind, Text: n.Text},
+			&Node{Kind: SpaceNode, Text: "\n" + indent},
+		)
+	}
+	c.Children = splice(c.Children, it.entry, it.entry, nodes)
+}
+
+// headerComments adds the comments behind the opening bracket of the overlay
+// container value to the container c if it has none.
+func (ms *mergeState) headerComments(c, value *Node) {
+
+	var comments []*Node
+	for i := 1; i < header(value); i++ {
+		if n := value.Children[i]; n.IsComment() {
+			comments = append(comments, &Node{Kind: n.Kind, Text: n.Text})
+		}
+	}
+
+	h := header(c)
+	for i := 1; i < h; i++ {
+		if c.Children[i].IsComment() {
+			return
+		}
+	}
+
+	if len(comments) == 0 || h >= len(c.Children)-1 || !strings.HasPrefix(c.Children[h].Text, "\n") {
+		return
+	}
+
+	var nodes []*Node
+	for _, n := range comments {
+		nodes = append(nodes, &Node{Kind: SpaceNode, Text: ` `}, n)
+	}
+	c.Children = splice(c.Children, h, h, nodes)
+}
+
+// stripNulls removes the members which are null from the objects in the
+// value n.
+func (e *editor) stripNulls(n *Node) {
+
+	if n.Kind == ArrayNode {
+		for _, v := range n.Elements() {
+			e.stripNulls(v)
+		}
+		return
+	}
+
+	if n.Kind != ObjectNode {
+		return
+	}
+
+	var removes []int
+	for k, it := range items(n) {
+
+		v := n.Children[it.entry].Value()
+		switch {
+		case v == nil:
+		case v.Kind == NullNode:
+			removes = append(removes, k)
+		default:
+			e.stripNulls(v)
+		}
+	}
+	e.edit(n, removes, nil, nil, nil)
+}
+
+// itemOf returns the item of the entry of the container c.
+func itemOf(c, entry *Node) (item, bool) {
+
+	for _, it := range items(c) {
+		if c.Children[it.entry] == entry {
+			return it, true
+		}
+	}
+	return item{}, false
+}
+
+// splice returns children with children[from:to] replaced by nodes.
+func splice(children []*Node, from, to int, nodes []*Node) []*Node {
+
+	out := append([]*Node{}, children[:from]...)
+	out = append(out, nodes...)
+	return append(out, children[to:]...)
+}
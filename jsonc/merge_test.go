@@ -0,0 +1,272 @@
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mergeBase = `// base config
+{
+  // the service name
+  name: app
+  port: 80 // http
+  database: {
+    server: "192.168.1.1"
+    ports: [8001, 8002]
+    user: admin
+  }
+  servers: [
+    {name: alpha, ip: "10.0.0.1"}
+    {name: beta, ip: "10.0.0.2"}
+  ]
+  debug: true
+}
+`
+
+const mergeOverlay = `{
+  // production port
+  port: 443 // https
+  database: { // the live db
+    ports: [9000]
+    user: null
+    password: secret
+  }
+  servers: [
+    // more memory
+    {name: beta, ip: "10.0.0.3", extra: null}
+    {name: gamma, ip: "10.0.0.4"}
+  ]
+  debug: null
+  // new member
+  tls: {cert: "a.pem", key: null}
+}
+`
+
+func TestMerge(t *testing.T) {
+
+	tests := []struct {
+		strategy ArrayStrategy
+		doc      string
+	}{
+		{
+			strategy: ReplaceArrays,
+			doc: `// base config
+{
+  // the service name
+  name: app
+  // production port
+  port: 443 // https
+  database: { // the live db
+    server: "192.168.1.1"
+    ports: [9000]
+    password: secret
+  }
+  servers: [
+    // more memory
+    {name: beta, ip: "10.0.0.3"}
+    {name: gamma, ip: "10.0.0.4"}
+  ]
+  // new member
+  tls: {cert: "a.pem"}
+}
+`,
+		},
+		{
+			strategy: AppendArrays,
+			doc: `// base config
+{
+  // the service name
+  name: app
+  // production port
+  port: 443 // https
+  database: { // the live db
+    server: "192.168.1.1"
+    ports: [8001, 8002, 9000]
+    password: secret
+  }
+  servers: [
+    {name: alpha, ip: "10.0.0.1"}
+    {name: beta, ip: "10.0.0.2"}
+    // more memory
+    {name: beta, ip: "10.0.0.3"}
+    {name: gamma, ip: "10.0.0.4"}
+  ]
+  // new member
+  tls: {cert: "a.pem"}
+}
+`,
+		},
+		{
+			strategy: MergeArraysByKey,
+			doc: `// base config
+{
+  // the service name
+  name: app
+  // production port
+  port: 443 // https
+  database: { // the live db
+    server: "192.168.1.1"
+    ports: [8001, 8002, 9000]
+    password: secret
+  }
+  servers: [
+    {name: alpha, ip: "10.0.0.1"}
+    // more memory
+    {name: beta, ip: "10.0.0.3"}
+    {name: gamma, ip: "10.0.0.4"}
+  ]
+  // new member
+  tls: {cert: "a.pem"}
+}
+`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		out, err := NewMerger(MergeArrays(ts.strategy)).Merge([]byte(mergeBase), []byte(mergeOverlay))
+		require.NoError(t, err, ts.strategy)
+		assert.Equal(t, ts.doc, string(out), ts.strategy)
+	}
+}
+
+func TestMergeCases(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		base     string
+		overlays []string
+		opts     []Option
+		doc      string
+	}{
+		{
+			name:     `json stays json`,
+			base:     `{"a": 1, "b": [1]}`,
+			overlays: []string{`{"b": [2], "c": {"d": null, "e": true}}`},
+			doc:      `{"a": 1, "b": [2], "c": {"e": true}}`,
+		},
+		{
+			name:     `json base with jsonc overlays`,
+			base:     `{"a": 1, "b": 2}`,
+			overlays: []string{"{\n  // new\n  c: x, d: [y], b: null\n}", `{e: {f: 'g'}}`},
+			opts:     []Option{WithDialect(JSON5)},
+			doc:      "{\"a\": 1, \"c\": \"x\", \"d\": [\"y\"], \"e\": {\n  \"f\": \"g\"\n}}",
+		},
+		{
+			name:     `overlays in order`,
+			base:     `{a: 1, b: 2}`,
+			overlays: []string{`{a: 3, c: 4}`, `{c: null, d: 5}`},
+			doc:      `{a: 3, b: 2, d: 5}`,
+		},
+		{
+			name:     `single line members`,
+			base:     `{a: 1, o: {c: 1}}`,
+			overlays: []string{`{o: {d: 2}}`, `{e: 3}`},
+			doc:      `{a: 1, o: {c: 1, d: 2}, e: 3}`,
+		},
+		{
+			name:     `single line without commas`,
+			base:     `{a: 1 b: 2}`,
+			overlays: []string{`{c: 3}`},
+			doc:      `{a: 1 b: 2 c: 3}`,
+		},
+		{
+			name:     `empty base`,
+			base:     `// empty`,
+			overlays: []string{`{a: 1, b: null}`},
+			doc:      "// empty\n{a: 1}",
+		},
+		{
+			name:     `empty overlay`,
+			base:     `{a: 1}`,
+			overlays: []string{`// nothing`},
+			doc:      `{a: 1}`,
+		},
+		{
+			name:     `kinds change`,
+			base:     `{a: {b: 1}, c: [1], d: x}`,
+			overlays: []string{`{a: [1], c: {e: null, f: 2}, d: {g: [{h: null}]}}`},
+			doc:      `{a: [1], c: {f: 2}, d: {g: [{}]}}`,
+		},
+		{
+			name:     `trailing comment replaced`,
+			base:     "{\n  a: 1, // one\n  b: 2\n}",
+			overlays: []string{"{\n  a: 3 // three\n}"},
+			doc:      "{\n  a: 3, // three\n  b: 2\n}",
+		},
+		{
+			name:     `no line comments on a line`,
+			base:     `{a: 1, b: 2}`,
+			overlays: []string{"{\n  // above\n  a: 3 // behind\n}"},
+			doc:      `{a: 3, b: 2}`,
+		},
+		{
+			name:     `comments not repeated`,
+			base:     "{\n  // the a\n  a: 1\n}",
+			overlays: []string{"{\n  // the a\n  a: 2\n}"},
+			doc:      "{\n  // the a\n  a: 2\n}",
+		},
+		{
+			name:     `scalars by value`,
+			base:     `{tags: [a, b]}`,
+			overlays: []string{`{tags: [b, c]}`},
+			opts:     []Option{MergeArrays(MergeArraysByKey)},
+			doc:      `{tags: [a, b, c]}`,
+		},
+		{
+			name:     `merge key`,
+			base:     `{users: [{id: 1, role: admin}, {name: x}]}`,
+			overlays: []string{`{users: [{id: 1, role: null, mail: "a@b"}, {name: x}]}`},
+			opts:     []Option{MergeArrays(MergeArraysByKey), MergeKey(`id`)},
+			doc:      `{users: [{id: 1, mail: "a@b"}, {name: x}, {name: x}]}`,
+		},
+		{
+			name:     `deleted and added again`,
+			base:     "{\n  a: {x: 1, y: 2}\n}",
+			overlays: []string{`{a: {y: null}}`, `{a: {y: 3}}`},
+			doc:      "{\n  a: {x: 1, y: 3}\n}",
+		},
+		{
+			name:     `member deleted and added again`,
+			base:     `{a: {x: 1}, b: 2}`,
+			overlays: []string{`{a: null}`, `{a: {x: 1}}`},
+			doc:      `{b: 2, a: {x: 1}}`,
+		},
+		{
+			name:     `last member counts`,
+			base:     `{a: 1}`,
+			overlays: []string{`{a: {b: 1}, a: 2}`},
+			doc:      `{a: 2}`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		var overlays [][]byte
+		for _, o := range ts.overlays {
+			overlays = append(overlays, []byte(o))
+		}
+
+		out, err := NewMerger(ts.opts...).Merge([]byte(ts.base), overlays...)
+		require.NoError(t, err, ts.name)
+		assert.Equal(t, ts.doc, string(out), ts.name)
+	}
+
+	out, err := Merge([]byte(`{a: 1}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{a: 1}`, string(out))
+}
+
+func TestMergeErrors(t *testing.T) {
+
+	_, err := Merge([]byte(`{a: `), []byte(`{}`))
+	assert.Regexp(t, `^base: `, err)
+
+	_, err = Merge([]byte(`{}`), []byte(`{}`), []byte(`{a: ]`))
+	assert.Regexp(t, `^overlay 2: `, err)
+
+	_, err = NewMerger(WithDialect(JSON)).Merge([]byte(`{"a": 1}`), []byte(`{a: 1}`))
+	assert.Regexp(t, `^overlay 1: `, err)
+}
@@ -0,0 +1,493 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// NodeKind is the kind of a Node in a jsonc syntax tree.
+type NodeKind int
+
+const (
+	DocumentNode     NodeKind = iota // 0
+	ObjectNode                       // 1
+	MemberNode                       // 2
+	ArrayNode                        // 3
+	KeyNode                          // 4
+	StringNode                       // 5
+	NumberNode                       // 6
+	BoolNode                         // 7
+	NullNode                         // 8
+	LineCommentNode                  // 9
+	BlockCommentNode                 // 10
+	SpaceNode                        // 11
+	PunctNode                        // 12
+)
+
+var nodeKindNames = []string{
+	`document`,
+	`object`,
+	`member`,
+	`array`,
+	`key`,
+	`string`,
+	`number`,
+	`bool`,
+	`null`,
+	`line comment`,
+	`block comment`,
+	`space`,
+	`punctuation`,
+}
+
+func (k NodeKind) String() string {
+	if k < 0 || int(k) >= len(nodeKindNames) {
+		return fmt.Sprintf("NodeKind(%d)", int(k))
+	}
+	return nodeKindNames[k]
+}
+
+// Quote is the quoting style of a key or string.
+type Quote int
+
+const (
+	NoQuote     Quote = iota // a bare word
+	DoubleQuote              // a json string
+	Backtick                 // a multiline string
+	SingleQuote              // a JSON5 string
+)
+
+// Node is a node of a concrete jsonc syntax tree as returned by Parse.
+//
+// Leaf nodes (keys, scalars, comments, spaces and punctuation) carry their
+// source text in Text, all other nodes hold their parts in Children. Printing
+// the Text of all leaves in order reproduces the parsed document byte by
+// byte.
+type Node struct {
+	Kind     NodeKind
+	Start    Pos
+	End      Pos
+	Text     string
+	Quote    Quote
+	Children []*Node
+
+	json5 bool // a JSON5 key or string, JSON translates its escapes
+}
+
+// IsValue reports whether n is an object, an array or a scalar.
+func (n *Node) IsValue() bool {
+	switch n.Kind {
+	case ObjectNode, ArrayNode, StringNode, NumberNode, BoolNode, NullNode:
+		return true
+	}
+	return false
+}
+
+// IsComment reports whether n is a line or a block comment.
+func (n *Node) IsComment() bool {
+	return n.Kind == LineCommentNode || n.Kind == BlockCommentNode
+}
+
+// Value returns the value of a document or a member.
+func (n *Node) Value() *Node {
+	for _, c := range n.Children {
+		if c.IsValue() {
+			return c
+		}
+	}
+	return nil
+}
+
+// Key returns the key of a member.
+func (n *Node) Key() *Node {
+	for _, c := range n.Children {
+		if c.Kind == KeyNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// Name returns the unquoted name of a key or a member.
+func (n *Node) Name() string {
+
+	if n.Kind == MemberNode {
+		k := n.Key()
+		if k == nil {
+			return ``
+		}
+		n = k
+	}
+
+	if n.Quote != DoubleQuote && n.Quote != SingleQuote {
+		return n.Text
+	}
+
+	var name string
+	err := n.Decode(&name)
+	if err != nil {
+		return n.Text
+	}
+	return name
+}
+
+// Members returns the members of an object.
+func (n *Node) Members() []*Node {
+
+	var members []*Node
+	for _, c := range n.Children {
+		if c.Kind == MemberNode {
+			members = append(members, c)
+		}
+	}
+	return members
+}
+
+// Elements returns the values of an array.
+func (n *Node) Elements() []*Node {
+
+	var elements []*Node
+	for _, c := range n.Children {
+		if c.IsValue() {
+			elements = append(elements, c)
+		}
+	}
+	return elements
+}
+
+// Lookup returns the last member of an object with the given name.
+func (n *Node) Lookup(name string) *Node {
+
+	var member *Node
+	for _, m := range n.Members() {
+		if m.Name() == name {
+			member = m
+		}
+	}
+	return member
+}
+
+// Comment returns the text of the comments of the member or element c of the
+// object, array or document n: the comments on the lines directly above c,
+// or else the comments on its line, between key and value, behind an opening
+// bracket or behind c. The comment markers and the
+// surrounding spaces are removed, lines are separated by line breaks.
+func (n *Node) Comment(c *Node) string {
+
+	idx := -1
+	for i, child := range n.Children {
+		if child == c {
+			idx = i
+			break
+		}
+	}
+
+	if idx < 0 {
+		return ``
+	}
+
+	// the comments above, comments behind the previous entry on its line do
+	// not belong to c
+	var above, pending []*Node
+	newline := false
+	i := idx - 1
+
+loop:
+	for ; i >= 0; i-- {
+
+		child := n.Children[i]
+		switch {
+		case child.Kind == SpaceNode:
+			lines := strings.Count(child.Text, "\n")
+			if lines == 0 {
+				continue
+			}
+
+			above = append(pending, above...)
+			pending = nil
+			newline = true
+			if lines > 1 {
+				break loop
+			}
+
+		case child.IsComment():
+			pending = append([]*Node{child}, pending...)
+
+		default:
+			break loop
+		}
+	}
+
+	// comments at the beginning of n or on the line of c before it
+	if i < 0 || !newline {
+		above = append(pending, above...)
+	}
+
+	if len(above) > 0 {
+		return commentText(above)
+	}
+
+	// the comments within a member, behind the opening bracket of a
+	// container and behind c
+	var behind []*Node
+	v := c
+	if c.Kind == MemberNode {
+		for _, child := range c.Children {
+			if child.IsComment() {
+				behind = append(behind, child)
+			}
+		}
+		v = c.Value()
+	}
+
+	if v != nil && (v.Kind == ObjectNode || v.Kind == ArrayNode) {
+		behind = append(behind, lineComments(v.Children[1:])...)
+	}
+	behind = append(behind, lineComments(n.Children[idx+1:])...)
+	return commentText(behind)
+}
+
+// lineComments returns the leading comments of children up to the first line
+// break or entry.
+func lineComments(children []*Node) []*Node {
+
+	var comments []*Node
+	for _, child := range children {
+
+		if child.IsComment() {
+			comments = append(comments, child)
+			continue
+		}
+
+		if (child.Kind == SpaceNode && !strings.Contains(child.Text, "\n")) ||
+			(child.Kind == PunctNode && child.Text == `,`) {
+			continue
+		}
+		break
+	}
+	return comments
+}
+
+// commentText returns the text of comments without markers.
+func commentText(comments []*Node) string {
+
+	var lines []string
+	for _, c := range comments {
+
+		text := strings.TrimPrefix(c.Text, `//`)
+		if strings.HasPrefix(c.Text, `#`) {
+			text = c.Text[1:]
+		}
+
+		block := c.Kind == BlockCommentNode
+		if block {
+			text = strings.TrimSuffix(strings.TrimPrefix(c.Text, `/*`), `*/`)
+		}
+
+		for _, line := range strings.Split(text, "\n") {
+
+			// the leading stars of block comment lines
+			line = strings.TrimSpace(line)
+			if block && strings.HasPrefix(line, `*`) {
+				line = strings.TrimSpace(line[1:])
+			}
+
+			if line != `` || len(lines) > 0 {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == `` {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String returns the source text of the subtree rooted at n.
+func (n *Node) String() string {
+	buf := &strings.Builder{}
+	_ = Print(buf, n)
+	return buf.String()
+}
+
+// Print writes the source text of the subtree rooted at n to w.
+func Print(w io.Writer, n *Node) error {
+
+	if n.Children == nil {
+		_, err := io.WriteString(w, n.Text)
+		return err
+	}
+
+	for _, c := range n.Children {
+		err := Print(w, c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSON returns the subtree rooted at n as minified json.
+func (n *Node) JSON() ([]byte, error) {
+	return n.appendJSON(nil)
+}
+
+// Decode stores the json value of n in the value pointed to by v.
+func (n *Node) Decode(v interface{}) error {
+
+	data, err := n.JSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Interface returns the json value of n as decoded into an interface{}, with
+// numbers as json.Number.
+func (n *Node) Interface() (interface{}, error) {
+
+	data, err := n.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	err = dec.Decode(&v)
+	return v, err
+}
+
+func (n *Node) appendJSON(buf []byte) ([]byte, error) {
+
+	var err error
+	switch n.Kind {
+	case DocumentNode:
+		v := n.Value()
+		if v == nil {
+			return buf, nil
+		}
+		return v.appendJSON(buf)
+
+	case MemberNode:
+		k, v := n.Key(), n.Value()
+		if k == nil || v == nil {
+			return nil, fmt.Errorf("incomplete member %v", n.Start)
+		}
+
+		buf, err = k.appendJSON(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, ':')
+		return v.appendJSON(buf)
+
+	case ObjectNode, ArrayNode:
+
+		children := n.Members()
+		open, close := byte('{'), byte('}')
+		if n.Kind == ArrayNode {
+			children = n.Elements()
+			open, close = '[', ']'
+		}
+
+		buf = append(buf, open)
+		for idx, c := range children {
+			if idx > 0 {
+				buf = append(buf, ',')
+			}
+			buf, err = c.appendJSON(buf)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, close), nil
+
+	case KeyNode, StringNode:
+		if n.json5 {
+			s, err := convert([]byte(n.Text), false, ``, []Option{WithDialect(JSON5)})
+			if err != nil {
+				return nil, err
+			}
+			return append(buf, s...), nil
+		}
+
+		switch n.Quote {
+		case DoubleQuote:
+			return append(buf, normalize(n.Text)...), nil
+
+		case Backtick:
+			return appendMultiline(buf, n.Text)
+		}
+
+		buf = append(buf, '"')
+		buf = append(buf, n.Text...)
+		return append(buf, '"'), nil
+
+	case NumberNode:
+		if IsNumber(n.Text) {
+			return append(buf, n.Text...), nil
+		}
+
+		number, ok := json5Number(n.Text)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %v", n.Text)
+		}
+		return append(buf, number...), nil
+
+	case BoolNode, NullNode:
+		return append(buf, n.Text...), nil
+	}
+
+	return nil, fmt.Errorf("%v is not a value", n.Kind)
+}
+
+// appendMultiline appends the backtick quoted text as json string.
+func appendMultiline(buf []byte, text string) ([]byte, error) {
+
+	text = normalize(strings.TrimSuffix(strings.TrimPrefix(text, "`"), "`"))
+
+	buf = append(buf, '"')
+	for _, ru := range text {
+
+		if ru == '\\' {
+			return nil, fmt.Errorf("character \\ found in multiline string")
+		}
+
+		if rep, ok := needsReplacement(ru); ok {
+			buf = append(buf, '\\', byte(rep))
+			continue
+		}
+
+		buf = append(buf, string(ru)...)
+	}
+	return append(buf, '"'), nil
+}
+
+// normalize applies the rune transformations of Filter.fill to s: control
+// characters except the line break are dropped, all other spaces become a
+// single space.
+func normalize(s string) string {
+
+	buf := &bytes.Buffer{}
+	for _, ru := range s {
+
+		if ru != '\n' {
+			if unicode.IsControl(ru) {
+				continue
+			}
+
+			if unicode.IsSpace(ru) {
+				ru = ' '
+			}
+		}
+		buf.WriteRune(ru)
+	}
+	return buf.String()
+}
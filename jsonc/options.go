@@ -0,0 +1,228 @@
+package jsonc
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// Dialect selects the syntax accepted by a Filter.
+type Dialect int
+
+const (
+	// JSONC accepts comments, unquoted keys and values, multiline strings in
+	// backticks and optional commas. It is the default.
+	JSONC Dialect = iota
+
+	// JSON accepts strict json only, any jsonc extension is a syntax error.
+	JSON
+
+	// JSON5 accepts JSON5 in addition to jsonc: strings and keys in single
+	// quotes, the escapes of JavaScript and escaped line breaks, keys holding
+	// $ and _, hexadecimal numbers, numbers with a leading or trailing
+	// decimal point or a + sign. They are translated to json, Infinity and
+	// NaN become null as json has no such numbers.
+	JSON5
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case JSONC:
+		return `jsonc`
+	case JSON:
+		return `json`
+	case JSON5:
+		return `json5`
+	}
+	return `unknown`
+}
+
+// Option configures a Decoder, a Filter or the functions reading jsonc.
+type Option func(c *config)
+
+// Options is the former name of Option.
+type Options = Option
+
+type config struct {
+	ringSize    int
+	ringMinSize int
+	outSize     int
+	maxDepth    int
+	dialect     Dialect
+	space       string
+	arrays      ArrayStrategy
+	mergeKey    string
+	interpolate bool
+	resolvers   map[string]Resolver
+	references  bool
+	hash        bool
+	refs        *includes
+	file        string // the name of the document in refs
+
+	disallowUnknownFields bool
+	useNumber             bool
+}
+
+func newConfig(opts []Option) config {
+
+	c := config{
+		ringSize:    256,
+		ringMinSize: 64,
+		outSize:     256,
+		space:       `  `,
+		mergeKey:    `name`,
+	}
+
+	for _, o := range opts {
+		o(&c)
+	}
+	return c
+}
+
+// DisallowUnknownFields makes Decode return an error when an object key does
+// not match an exported field of the destination struct, as
+// json.Decoder.DisallowUnknownFields.
+func DisallowUnknownFields() Option {
+	return func(c *config) {
+		c.disallowUnknownFields = true
+	}
+}
+
+// UseNumber makes Decode unmarshal numbers into an interface{} as json.Number
+// instead of float64, as json.Decoder.UseNumber.
+func UseNumber() Option {
+	return func(c *config) {
+		c.useNumber = true
+	}
+}
+
+// RingSize sets the number of runes the input ring buffer holds at most and
+// refills at, the defaults are 256 and 64. maxSize has to be greater than
+// minSize.
+func RingSize(maxSize, minSize int) Option {
+	return func(c *config) {
+		c.ringSize = maxSize
+		c.ringMinSize = minSize
+	}
+}
+
+// OutputSize sets the number of bytes the filter produces before it returns
+// from a read, the default is 256. The size must be positive.
+func OutputSize(size int) Option {
+	return func(c *config) {
+		c.outSize = size
+	}
+}
+
+// MaxDepth limits the nesting of objects and arrays, deeper documents are a
+// syntax error. Zero, the default, allows any depth.
+func MaxDepth(depth int) Option {
+	return func(c *config) {
+		c.maxDepth = depth
+	}
+}
+
+// WithDialect selects the accepted syntax, the default is JSONC.
+func WithDialect(d Dialect) Option {
+	return func(c *config) {
+		c.dialect = d
+	}
+}
+
+// Space sets the string Format indents nested lines with, the default is two
+// spaces.
+func Space(space string) Option {
+	return func(c *config) {
+		c.space = space
+	}
+}
+
+// Interpolate makes the conversion to json, as by a Decoder, expand the
+// placeholders in string and bare values. ${scheme:key} is replaced by the
+// value the resolver of the scheme returns for key, ${key} is resolved by
+// the resolver of env. ${key:-default} gives default if the key is unset or
+// empty, $${ is a literal ${. A bare value whose expansion is a number, a
+// boolean or null stays one, otherwise it becomes a string.
+//
+// resolvers maps the schemes to their resolvers. If it is nil env resolves
+// environment variables and file the contents of files, as EnvResolver and
+// FileResolver. Placeholders which cannot be resolved are reported as
+// *InterpolationError located at the placeholder. Format keeps the
+// placeholders.
+func Interpolate(resolvers map[string]Resolver) Option {
+	return func(c *config) {
+		c.interpolate = true
+		c.resolvers = resolvers
+	}
+}
+
+// HashComments makes a Filter accept # as the start of a line comment
+// wherever comments are allowed, as in files taken over from YAML or shell
+// scripts. Format keeps the # marker. It does not apply to the JSON dialect.
+func HashComments() Option {
+	return func(c *config) {
+		c.hash = true
+	}
+}
+
+// References makes Decoder.Decode and the conversion to json resolve
+// references to other values of the same document, as ${.database.server}.
+// The path after the dot is written as for Get, ${.} is the whole document.
+// A string which is a single reference becomes a copy of the referenced
+// value, references within text are replaced by the text of the scalars they
+// refer to. $${ is a literal ${. Cycles and references to missing values are
+// reported as *InterpolationError located at the referring value.
+func References() Option {
+	return func(c *config) {
+		c.references = true
+	}
+}
+
+// ResolveRefs makes Decoder.Decode and the conversion to json replace JSON
+// Reference objects, as {"$ref": "common.jsonc#/timeouts"}, by the values
+// they refer to. The file path of $ref is relative to the referring file, the
+// optional fragment is a JSON Pointer into the file. A $ref without a path
+// refers to the same file. $ref objects on the way of a pointer are followed.
+//
+// The files are read from fsys, name is the path of the document itself in
+// fsys. Each file is read and converted with the same options once, only the
+// values referred to are resolved. Reference cycles and unresolvable
+// references are reported as *RefError listing the $ref objects followed.
+func ResolveRefs(fsys fs.FS, name string) Option {
+	return func(c *config) {
+		c.refs = newIncludes(fsys)
+		c.file = name
+	}
+}
+
+// MergeArrays selects how a Merger combines arrays, the default is
+// ReplaceArrays.
+func MergeArrays(strategy ArrayStrategy) Option {
+	return func(c *config) {
+		c.arrays = strategy
+	}
+}
+
+// MergeKey sets the member which identifies the objects of arrays merged by
+// MergeArraysByKey, the default is name.
+func MergeKey(key string) Option {
+	return func(c *config) {
+		c.mergeKey = key
+	}
+}
+
+// filter creates a Filter on readRune with the configured buffer sizes.
+func (c config) filter(readRune ReadRune, format bool, space string) (*Filter, error) {
+
+	if c.outSize <= 0 {
+		return nil, fmt.Errorf("outSize(%v) <= 0", c.outSize)
+	}
+
+	ring, err := NewRing(c.ringSize, c.ringMinSize, readRune)
+	if err != nil {
+		return nil, err
+	}
+
+	f := NewFilter(ring, c.outSize, format, space)
+	f.configure(c)
+	return f, nil
+}
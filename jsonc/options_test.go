@@ -0,0 +1,259 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialectJSON(t *testing.T) {
+
+	tests := []struct {
+		json string
+		err  string
+	}{
+		{json: `{"a": [1, 2.5, true, null, "x"], "b": {}}`},
+		{json: "[\n  {},\n  []\n]"},
+		{json: `{a: 1}`, err: `line: 1 col: 2 unquoted keys are not allowed in json`},
+		{json: `{"a": x}`, err: `line: 1 col: 7 unquoted strings are not allowed in json`},
+		{json: `{"a": 1,}`, err: `line: 1 col: 9 trailing commas are not allowed in json`},
+		{json: `[1, 2,]`, err: `line: 1 col: 7 trailing commas are not allowed in json`},
+		{json: "{\"a\": 1\n\"b\": 2}", err: `line: 2 col: 1 missing comma`},
+		{json: `[1 2]`, err: `line: 1 col: 4 missing comma`},
+		{json: "[`x`]", err: `line: 1 col: 2 multiline strings are not allowed in json`},
+		{json: `{"a": 1 /* c */}`, err: `line: 1 col: 9 comments are not allowed in json`},
+		{json: "// c\n{}", err: `line: 1 col: 1 comments are not allowed in json`},
+	}
+
+	for _, ts := range tests {
+
+		f, err := New(strings.NewReader(ts.json), true, ``, WithDialect(JSON))
+		require.NoError(t, err)
+
+		_, err = (&bytes.Buffer{}).ReadFrom(f)
+		if ts.err == `` {
+			assert.NoError(t, err, ts.json)
+			continue
+		}
+		assert.EqualError(t, err, ts.err, ts.json)
+	}
+}
+
+func TestDialectJSON5(t *testing.T) {
+
+	tests := []struct {
+		json5 string
+		json  string
+		err   string
+	}{
+		{json5: `{'a': 'it\'s "quoted"', b: "it's"}`, json: `{"a":"it's \"quoted\"","b":"it's"}`},
+		{json5: `{$id: 1, _x_1: 2, a$b: 3}`, json: `{"$id":1,"_x_1":2,"a$b":3}`},
+		{json5: `[0x1F, -0XfF, 0x10000000000000000, .5, -.5e3, 5., +1, +1.5E-2, -1]`, json: `[31,-255,18446744073709551616,0.5,-0.5e3,5,1,1.5E-2,-1]`},
+		{json5: `[Infinity, -Infinity, +Infinity, NaN, infinity]`, json: `[null,null,null,null,"infinity"]`},
+		{json5: "['one \\\ntwo', 'a\\\r\nb']", json: `["one two","ab"]`},
+		{json5: `['\x41\v\0\a\/\u0042\n']`, json: `["\u0041\u000b\u0000a\/\u0042\n"]`},
+		{json5: `{a: [1, 2,], // comment
+  b: /* c */ bare, c: ` + "`multi`" + `,}`, json: `{"a":[1,2],"b":"bare","c":"multi"}`},
+		{json5: `'top'`, json: `"top"`},
+		{json5: `{a: 'line
+break'}`, err: `line: 1 col: 10 line break in string value`},
+		{json5: `[0x1G]`, err: `line: 1 col: 2 invalid identifier`},
+		{json5: `[1-2]`, err: `line: 1 col: 3 invalid identifier`},
+		{json5: `{a-b: 1}`, err: `line: 1 col: 3 invalid key`},
+		{json5: `{bad: '\u00'}`, err: `line: 1 col: 12 invalid escape`},
+		{json5: `{bad: "\x4"}`, err: `line: 1 col: 11 invalid escape`},
+		{json5: `['\u00G0']`, err: `line: 1 col: 7 invalid escape`},
+	}
+
+	for _, ts := range tests {
+
+		out, err := ToJSON([]byte(ts.json5), WithDialect(JSON5))
+		if ts.err != `` {
+			assert.EqualError(t, err, ts.err, ts.json5)
+			assert.False(t, Valid([]byte(ts.json5), WithDialect(JSON5)), ts.json5)
+			continue
+		}
+		require.NoError(t, err, ts.json5)
+		assert.Equal(t, ts.json, string(out), ts.json5)
+		assert.True(t, json.Valid(out), ts.json5)
+
+		dec, err := NewDecoder(strings.NewReader(ts.json5), WithDialect(JSON5), OutputSize(1))
+		require.NoError(t, err)
+
+		var v, expected interface{}
+		require.NoError(t, dec.Decode(&v), ts.json5)
+		require.NoError(t, json.Unmarshal([]byte(ts.json), &expected))
+		assert.Equal(t, expected, v, ts.json5)
+	}
+
+	// the extensions are errors in jsonc
+	for _, doc := range []string{`{'a': 1}`, `{a: 'x'}`, `{$a: 1}`, `[0x10]`, `[.5]`, `[+1]`} {
+		_, err := ToJSON([]byte(doc))
+		assert.Error(t, err, doc)
+	}
+
+	out, err := Format([]byte("{\n  'a': 0x10 // hex\n}"), WithDialect(JSON5))
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": 16 // hex\n}", string(out))
+}
+
+func TestHashComments(t *testing.T) {
+
+	doc := "# service config\n{\n  port: 80 # listen port\n  host: a#b\n  url: \"http://x/#top\" // anchor\n  # tags: [a b]\n  tags: [a # first\n    b]\n}\n"
+
+	out, err := ToJSON([]byte(doc), HashComments())
+	require.NoError(t, err)
+	assert.Equal(t, `{"port":80,"host":"a","url":"http://x/#top","tags":["a","b"]}`, string(out))
+
+	out, err = Format([]byte(doc), HashComments())
+	require.NoError(t, err)
+	assert.Equal(t, "# service config\n{\n  port: 80 # listen port\n  host: a #b\n  url: \"http://x/#top\" // anchor\n  # tags: [a b]\n  tags: [a # first\n    b]\n}\n", string(out))
+
+	root, err := Parse([]byte(doc), HashComments())
+	require.NoError(t, err)
+	assert.Equal(t, doc, root.String())
+
+	obj := root.Value()
+	assert.Equal(t, `listen port`, obj.Comment(obj.Lookup(`port`)))
+	assert.Equal(t, `tags: [a b]`, obj.Comment(obj.Lookup(`tags`)))
+
+	_, err = ToJSON([]byte(doc))
+	assert.Error(t, err)
+
+	_, err = ToJSON([]byte("{\"a\": 1 # c\n}"), HashComments(), WithDialect(JSON))
+	assert.Error(t, err)
+
+	out, err = ToJSON([]byte("{'a': 0x1 # hex\n}"), HashComments(), WithDialect(JSON5))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(out))
+
+	// line comments end at the end of the input
+	for doc, formatted := range map[string]string{
+		`{a: 1}#`:     `{a: 1} #`,
+		`{a: 1}//`:    `{a: 1} //`,
+		`{a: 1} # c`:  `{a: 1} # c`,
+		`{a: 1} // c`: `{a: 1} // c`,
+	} {
+
+		out, err = ToJSON([]byte(doc), HashComments())
+		require.NoError(t, err, doc)
+		assert.Equal(t, `{"a":1}`, string(out), doc)
+		assert.True(t, Valid([]byte(doc), HashComments()), doc)
+
+		out, err = Format([]byte(doc), HashComments())
+		require.NoError(t, err, doc)
+		assert.Equal(t, formatted, string(out), doc)
+
+		root, err := Parse([]byte(doc), HashComments())
+		require.NoError(t, err, doc)
+		assert.Equal(t, doc, root.String(), doc)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+
+	tests := []struct {
+		jsonc string
+		depth int
+		err   string
+	}{
+		{jsonc: `{a:[{b:[]}]}`, depth: 0},
+		{jsonc: `{a:[{b:[]}]}`, depth: 4},
+		{jsonc: `{a:[{b:[]}]}`, depth: 3, err: `line: 1 col: 8 maximum nesting depth of 3 exceeded`},
+		{jsonc: `[[]]`, depth: 1, err: `line: 1 col: 2 maximum nesting depth of 1 exceeded`},
+	}
+
+	for _, ts := range tests {
+
+		f, err := New(strings.NewReader(ts.jsonc), true, ``, MaxDepth(ts.depth))
+		require.NoError(t, err)
+
+		_, err = (&bytes.Buffer{}).ReadFrom(f)
+		if ts.err == `` {
+			assert.NoError(t, err, ts.jsonc)
+			continue
+		}
+		assert.EqualError(t, err, ts.err, ts.jsonc)
+	}
+}
+
+func TestDecoderOptions(t *testing.T) {
+
+	t.Run(`use number`, func(t *testing.T) {
+
+		dec, err := NewDecoder(strings.NewReader(`{a: 12345678901234567890}`), UseNumber())
+		require.NoError(t, err)
+
+		var v map[string]interface{}
+		require.NoError(t, dec.Decode(&v))
+		assert.Equal(t, json.Number(`12345678901234567890`), v[`a`])
+	})
+
+	t.Run(`disallow unknown fields`, func(t *testing.T) {
+
+		var v struct {
+			A int `json:"a"`
+		}
+
+		dec, err := NewDecoder(strings.NewReader(`{a: 1, b: 2}`))
+		require.NoError(t, err)
+		require.NoError(t, dec.Decode(&v))
+
+		dec, err = NewDecoder(strings.NewReader(`{a: 1, b: 2}`), DisallowUnknownFields())
+		require.NoError(t, err)
+		assert.EqualError(t, dec.Decode(&v), `json: unknown field "b"`)
+	})
+
+	t.Run(`buffer sizes`, func(t *testing.T) {
+
+		in := `{list: [` + strings.Repeat(`{x: "abcdef"}, `, 50) + `]}`
+
+		dec, err := NewDecoder(strings.NewReader(in), RingSize(8, 4), OutputSize(3))
+		require.NoError(t, err)
+
+		var v struct {
+			List []struct{ X string }
+		}
+		require.NoError(t, dec.Decode(&v))
+		assert.Len(t, v.List, 50)
+
+		_, err = NewDecoder(strings.NewReader(in), RingSize(4, 4))
+		assert.Error(t, err)
+
+		for _, size := range []int{0, -1} {
+			_, err = NewDecoder(strings.NewReader(in), OutputSize(size))
+			assert.EqualError(t, err, fmt.Sprintf("outSize(%v) <= 0", size))
+		}
+
+		ring, err := NewRing(8, 4, runeReader(strings.NewReader(`[1, 2]`)).ReadRune)
+		require.NoError(t, err)
+		out, err := ioutil.ReadAll(NewFilter(ring, 0, false, ``))
+		require.NoError(t, err)
+		assert.Equal(t, `[1,2]`, string(out))
+	})
+
+	t.Run(`dialect and depth`, func(t *testing.T) {
+
+		var v interface{}
+
+		dec, err := NewDecoder(strings.NewReader(`{a: 1}`), WithDialect(JSON))
+		require.NoError(t, err)
+
+		var jerr Error
+		require.True(t, errors.As(dec.Decode(&v), &jerr))
+		assert.Equal(t, `unquoted keys are not allowed in json`, jerr.err)
+
+		dec, err = NewDecoder(strings.NewReader(`[[[1]]]`), MaxDepth(2))
+		require.NoError(t, err)
+
+		require.True(t, errors.As(dec.Decode(&v), &jerr))
+		assert.Equal(t, 3, jerr.Column())
+	})
+}
@@ -0,0 +1,255 @@
+package jsonc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parse parses a jsonc document into a concrete syntax tree. The tree keeps
+// all comments, spaces and quoting choices of the source, Print turns it back
+// into the identical text. JSON5 strings and numbers keep their text as well,
+// Node.JSON converts them.
+func Parse(data []byte, opts ...Option) (*Node, error) {
+
+	b := newBuilder(data)
+	if len(data) == 0 {
+		return b.finish(Pos{Line: 1, Column: 1}), nil
+	}
+
+	c := newConfig(opts)
+	f, err := c.filter(bytes.NewReader(data).ReadRune, false, ``)
+	if err != nil {
+		return nil, err
+	}
+	f.tokens = b
+	b.json5 = c.dialect == JSON5
+
+	_, err = io.Copy(ioutil.Discard, f)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.Done() {
+		return nil, f.unexpectedEOF()
+	}
+
+	if f.Err() != nil && !errors.Is(f.Err(), io.EOF) {
+		return nil, f.Err()
+	}
+
+	return b.finish(f.ring.EndPos()), nil
+}
+
+// builder builds a syntax tree from the tokens reported by a Filter.
+type builder struct {
+	src   []byte
+	json5 bool
+	last  Pos
+	stack []*Node
+}
+
+func newBuilder(src []byte) *builder {
+
+	doc := &Node{Kind: DocumentNode, Start: Pos{Line: 1, Column: 1}, Children: []*Node{}}
+	return &builder{
+		src:   src,
+		last:  doc.Start,
+		stack: []*Node{doc},
+	}
+}
+
+func (b *builder) top() *Node {
+	return b.stack[len(b.stack)-1]
+}
+
+func (b *builder) push(n *Node) {
+	parent := b.top()
+	parent.Children = append(parent.Children, n)
+	b.stack = append(b.stack, n)
+}
+
+func (b *builder) pop() *Node {
+	n := b.top()
+	b.stack = b.stack[:len(b.stack)-1]
+	return n
+}
+
+func (b *builder) begin(kind NodeKind, pos Pos) error {
+
+	b.gap(pos)
+
+	if kind == KeyNode {
+		b.push(&Node{Kind: MemberNode, Start: pos, Children: []*Node{}})
+	}
+
+	n := &Node{Kind: kind, Start: pos}
+	b.push(n)
+
+	if kind == ObjectNode || kind == ArrayNode {
+		n.Children = []*Node{}
+		b.leaf(PunctNode, advance(pos, string(b.src[pos.Offset])))
+	}
+	return nil
+}
+
+func (b *builder) end(kind NodeKind, pos Pos) error {
+
+	n := b.top()
+	switch kind {
+	case ObjectNode, ArrayNode:
+		b.gap(Pos{Offset: pos.Offset - 1, Line: pos.Line, Column: pos.Column - 1})
+		b.leaf(PunctNode, pos)
+		n.End = pos
+		b.pop()
+
+	default:
+		text := string(b.src[n.Start.Offset:pos.Offset])
+		if n.Kind != BlockCommentNode {
+			// skipped control characters may trail bare words and comments
+			text = trimControl(text)
+		}
+
+		n.Text = text
+		n.End = advance(n.Start, text)
+		b.last = n.End
+		b.pop()
+
+		if kind == KeyNode || kind == StringNode {
+			classify(n)
+			if b.json5 {
+				classifyJSON5(n)
+			}
+		}
+
+		if kind == KeyNode || n.IsComment() {
+			return nil
+		}
+	}
+
+	// a value completes its member
+	if b.top().Kind == MemberNode {
+		b.top().End = n.End
+		b.pop()
+	}
+	return nil
+}
+
+// leaf adds the text from the last token up to end as leaf of the top node.
+func (b *builder) leaf(kind NodeKind, end Pos) {
+
+	n := &Node{
+		Kind:  kind,
+		Start: b.last,
+		End:   end,
+		Text:  string(b.src[b.last.Offset:end.Offset]),
+	}
+	parent := b.top()
+	parent.Children = append(parent.Children, n)
+	b.last = end
+}
+
+// gap adds the spaces and punctuation between the last token and pos.
+func (b *builder) gap(pos Pos) {
+
+	for b.last.Offset < pos.Offset {
+
+		text := b.src[b.last.Offset:pos.Offset]
+		idx := bytes.IndexAny(text, `:,`)
+		switch {
+		case idx == 0:
+			b.leaf(PunctNode, advance(b.last, string(text[:1])))
+		case idx > 0:
+			b.leaf(SpaceNode, advance(b.last, string(text[:idx])))
+		default:
+			b.leaf(SpaceNode, pos)
+		}
+	}
+}
+
+func (b *builder) finish(end Pos) *Node {
+	b.gap(end)
+	doc := b.stack[0]
+	doc.End = end
+	return doc
+}
+
+// classify sets the quote style of keys and strings and the kind of bare
+// scalars.
+func classify(n *Node) {
+
+	switch {
+	case len(n.Text) > 0 && n.Text[0] == '"':
+		n.Quote = DoubleQuote
+		return
+	case len(n.Text) > 0 && n.Text[0] == '`':
+		n.Quote = Backtick
+		return
+	}
+
+	n.Quote = NoQuote
+	if n.Kind == KeyNode {
+		return
+	}
+
+	switch {
+	case IsNumber(n.Text):
+		n.Kind = NumberNode
+	case n.Text == `true` || n.Text == `false`:
+		n.Kind = BoolNode
+	case n.Text == `null`:
+		n.Kind = NullNode
+	}
+}
+
+// classifyJSON5 marks quoted keys and strings as JSON5, which have escapes
+// json does not know, and sets the quote style of those quoted by ' and the
+// kind of the numbers JSON5 adds to json.
+func classifyJSON5(n *Node) {
+
+	switch {
+	case n.Quote == DoubleQuote:
+		n.json5 = true
+
+	case len(n.Text) > 0 && n.Text[0] == '\'':
+		n.Quote = SingleQuote
+		n.json5 = true
+
+	case n.Kind == StringNode && n.Quote == NoQuote:
+		if _, ok := json5Number(n.Text); ok {
+			n.Kind = NumberNode
+		}
+	}
+}
+
+// advance returns the position after text starting at pos.
+func advance(pos Pos, text string) Pos {
+
+	for len(text) > 0 {
+		ru, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+
+		pos.Offset += size
+		if ru == '\n' {
+			pos.Line++
+			pos.Column = 1
+			continue
+		}
+		pos.Column++
+	}
+	return pos
+}
+
+func trimControl(s string) string {
+	for len(s) > 0 {
+		ru, size := utf8.DecodeLastRuneInString(s)
+		if !unicode.IsControl(ru) {
+			break
+		}
+		s = s[:len(s)-size]
+	}
+	return s
+}
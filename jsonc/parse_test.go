@@ -0,0 +1,239 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+
+	var docs []string
+	for _, d := range JsonData() {
+		if d.ExpectedStringInError == `` {
+			docs = append(docs, d.JsonCString)
+		}
+	}
+
+	for _, path := range []string{`test-objects.txt`, `test-arrays.txt`, `test-complex.txt`} {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		for _, d := range strings.Split(string(data), "###") {
+			split := strings.Split(d, "##")
+			if len(split) == 2 {
+				docs = append(docs, split[0], split[1])
+			}
+		}
+	}
+
+	data, err := ioutil.ReadFile(`multiline-test.jsonc`)
+	require.NoError(t, err)
+	docs = append(docs, string(data), "\t{x:x\t}\r\n// end\r\n", "{ä:\"ö\"}", ``, `// only a comment`)
+
+	for idx, d := range docs {
+
+		doc, err := Parse([]byte(d))
+		require.NoError(t, err, "idx: %v", idx)
+		assert.Equal(t, d, doc.String(), "idx: %v", idx)
+
+		if strings.TrimSpace(d) == `` {
+			continue
+		}
+
+		expected := &bytes.Buffer{}
+		f, err := New(strings.NewReader(d), true, ``)
+		require.NoError(t, err)
+		_, err = expected.ReadFrom(f)
+		require.NoError(t, err)
+
+		data, err := doc.JSON()
+		require.NoError(t, err, "idx: %v", idx)
+		assert.Equal(t, expected.String(), string(data), "idx: %v", idx)
+	}
+}
+
+func TestParseTree(t *testing.T) {
+
+	src := `// config
+{
+	name: "jsonc" /* block */,
+
+	ports: [8001, 8002]
+	text: ` + "`two\nlines`" + `
+	"on": true
+	none: null
+}
+`
+	doc, err := Parse([]byte(src))
+	require.NoError(t, err)
+
+	require.Equal(t, LineCommentNode, doc.Children[0].Kind)
+	assert.Equal(t, `// config`, doc.Children[0].Text)
+
+	obj := doc.Value()
+	require.NotNil(t, obj)
+	require.Equal(t, ObjectNode, obj.Kind)
+	assert.Equal(t, Pos{Offset: 10, Line: 2, Column: 1}, obj.Start)
+	assert.Equal(t, Pos{Offset: len(src) - 1, Line: 10, Column: 2}, obj.End)
+
+	members := obj.Members()
+	require.Len(t, members, 5)
+
+	name := members[0]
+	assert.Equal(t, `name`, name.Name())
+	assert.Equal(t, NoQuote, name.Key().Quote)
+	assert.Equal(t, StringNode, name.Value().Kind)
+	assert.Equal(t, DoubleQuote, name.Value().Quote)
+	assert.Equal(t, Pos{Offset: 19, Line: 3, Column: 8}, name.Value().Start)
+
+	ports := obj.Lookup(`ports`)
+	require.NotNil(t, ports)
+	elements := ports.Value().Elements()
+	require.Len(t, elements, 2)
+	assert.Equal(t, NumberNode, elements[1].Kind)
+	assert.Equal(t, `8002`, elements[1].Text)
+
+	text := obj.Lookup(`text`).Value()
+	assert.Equal(t, Backtick, text.Quote)
+	assert.Equal(t, 6, text.Start.Line)
+	assert.Equal(t, 7, text.End.Line)
+
+	assert.Equal(t, BoolNode, obj.Lookup(`on`).Value().Kind)
+	assert.Equal(t, DoubleQuote, obj.Lookup(`on`).Key().Quote)
+	assert.Equal(t, NullNode, obj.Lookup(`none`).Value().Kind)
+
+	var kinds []NodeKind
+	for _, c := range obj.Children {
+		kinds = append(kinds, c.Kind)
+	}
+	assert.Equal(t, []NodeKind{
+		PunctNode, SpaceNode, MemberNode, SpaceNode, BlockCommentNode, PunctNode,
+		SpaceNode, MemberNode, SpaceNode, MemberNode, SpaceNode, MemberNode,
+		SpaceNode, MemberNode, SpaceNode, PunctNode,
+	}, kinds)
+	assert.Equal(t, "\n\n\t", obj.Children[6].Text)
+
+	v := struct {
+		Name  string `json:"name"`
+		Ports []int  `json:"ports"`
+		Text  string `json:"text"`
+	}{}
+	require.NoError(t, doc.Decode(&v))
+	assert.Equal(t, `jsonc`, v.Name)
+	assert.Equal(t, []int{8001, 8002}, v.Ports)
+	assert.Equal(t, "two\nlines", v.Text)
+}
+
+func TestParseErrors(t *testing.T) {
+
+	for _, d := range []string{`{x:`, `[1,2`, `{x:x} y`, `[ x, y, z,, ]`, `/* open`} {
+		_, err := Parse([]byte(d))
+		assert.Error(t, err, d)
+	}
+}
+
+func TestNodeComment(t *testing.T) {
+
+	doc, err := Parse([]byte(`// the config
+{
+  // the name
+  //   of the service
+  name: alpha // trailing
+
+  // detached
+
+  /* block */ port: 1, /* inline */ tls: true
+  hosts: [
+    a // first
+    /*
+     * second
+     */
+    b
+  ]
+  debug: false
+  db: { // the database
+    url: /* the address */ x
+  }
+}`))
+	require.NoError(t, err)
+
+	obj := doc.Value()
+	assert.Equal(t, `the config`, doc.Comment(obj))
+
+	comments := map[string]string{}
+	for _, m := range obj.Members() {
+		comments[m.Name()] = obj.Comment(m)
+	}
+	assert.Equal(t, map[string]string{
+		`name`:  "the name\nof the service",
+		`port`:  `block`,
+		`tls`:   `inline`,
+		`hosts`: ``,
+		`debug`: ``,
+		`db`:    `the database`,
+	}, comments)
+
+	db := obj.Lookup(`db`).Value()
+	assert.Equal(t, `the address`, db.Comment(db.Lookup(`url`)))
+
+	hosts := obj.Lookup(`hosts`).Value()
+	elements := hosts.Elements()
+	assert.Equal(t, `first`, hosts.Comment(elements[0]))
+	assert.Equal(t, `second`, hosts.Comment(elements[1]))
+	assert.Equal(t, ``, hosts.Comment(obj))
+}
+
+func TestNodeInterface(t *testing.T) {
+
+	doc, err := Parse([]byte(`{a: 1.50, b: [x, true, null]}`))
+	require.NoError(t, err)
+
+	v, err := doc.Value().Interface()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		`a`: json.Number(`1.50`),
+		`b`: []interface{}{`x`, true, nil},
+	}, v)
+}
+
+func TestParseJSON5(t *testing.T) {
+
+	src := `{'a\'b': 'x "y"', $c: [0x10, +.5, Infinity, 'z\x41']}`
+	doc, err := Parse([]byte(src), WithDialect(JSON5))
+	require.NoError(t, err)
+	assert.Equal(t, src, doc.String())
+
+	members := doc.Value().Members()
+	require.Len(t, members, 2)
+	assert.Equal(t, `a'b`, members[0].Name())
+	assert.Equal(t, SingleQuote, members[0].Key().Quote)
+	assert.Equal(t, `$c`, members[1].Name())
+	assert.Equal(t, NumberNode, members[1].Value().Elements()[0].Kind)
+
+	out, err := doc.JSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a'b":"x \"y\"","$c":[16,0.5,null,"z\u0041"]}`, string(out))
+
+	// double quoted strings translate the escapes of JSON5 as well
+	src = "{\"k\\x41\": \"x\\'y\", b: \"\\v\", c: \"\\x41\", d: \"one \\\ntwo\", e: 'a\\\r\nb'}"
+	doc, err = Parse([]byte(src), WithDialect(JSON5))
+	require.NoError(t, err)
+
+	out, err = doc.Value().JSON()
+	require.NoError(t, err)
+
+	expected, err := ToJSON([]byte(src), WithDialect(JSON5))
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(out))
+	assert.Equal(t, `kA`, doc.Value().Members()[0].Name())
+
+	var v map[string]string
+	require.NoError(t, doc.Decode(&v))
+	assert.Equal(t, map[string]string{`kA`: `x'y`, `b`: "\v", `c`: `A`, `d`: `one two`, `e`: `ab`}, v)
+}
@@ -0,0 +1,359 @@
+package jsonc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ApplyPatch applies the RFC 6902 JSON Patch patch to the jsonc document doc
+// and returns the changed document. The operations edit the text of doc like
+// Update, comments, blank lines and the layout of the untouched parts stay
+// as they are. Added values are written like Marshal writes them, moved and
+// copied values keep their text.
+//
+// The patch may be json or jsonc. If an operation fails no document is
+// returned, the error is a *PatchError located in the document the operation
+// was applied to.
+func ApplyPatch(doc, patch []byte, opts ...Option) ([]byte, error) {
+
+	var ops []patchOperation
+	err := Unmarshal(patch, &ops)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	for idx, op := range ops {
+
+		var pos Pos
+		doc, pos, err = op.apply(doc, opts)
+		if err != nil {
+			return nil, &PatchError{Index: idx, Op: op.Op, Path: op.Path, Pos: pos, Err: err}
+		}
+	}
+	return doc, nil
+}
+
+// PatchError is a failed operation of a JSON Patch.
+type PatchError struct {
+	Index int    // index of the operation in the patch
+	Op    string // the operation
+	Path  string // the path of the operation
+	Pos   Pos    // location of the value the operation failed at, if any
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+
+	msg := fmt.Sprintf("patch operation %v (%v %q) failed: %v", e.Index, e.Op, e.Path, e.Err)
+	if e.Pos.Line == 0 {
+		return msg
+	}
+	return fmt.Sprintf("line: %v col: %v %v", e.Pos.Line, e.Pos.Column, msg)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ErrTestFailed is the error of a failed test operation.
+var ErrTestFailed = errors.New(`test failed`)
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// apply applies the operation to doc. The returned location belongs to the
+// error.
+func (op patchOperation) apply(doc []byte, opts []Option) ([]byte, Pos, error) {
+
+	root, err := Parse(doc, opts...)
+	if err != nil {
+		return nil, Pos{}, err
+	}
+	e := newEditor(doc, root, opts)
+
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, Pos{}, err
+	}
+
+	var pos Pos
+	switch op.Op {
+	case `add`, `replace`, `test`:
+
+		value, src, verr := e.value(op.Value)
+		if verr != nil {
+			return nil, Pos{}, verr
+		}
+
+		switch op.Op {
+		case `add`:
+			pos, err = e.add(path, value, src)
+		case `replace`:
+			pos, err = e.replaceAt(path, value, src)
+		default:
+			pos, err = e.test(path, value)
+			if err == nil {
+				return doc, Pos{}, nil
+			}
+		}
+
+	case `remove`:
+		pos, err = e.removeAt(path)
+
+	case `move`, `copy`:
+
+		from, perr := parsePointer(op.From)
+		if perr != nil {
+			return nil, Pos{}, perr
+		}
+		return op.moveOrCopy(doc, e, from, path, opts)
+
+	default:
+		return nil, Pos{}, fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	if err != nil {
+		return nil, pos, err
+	}
+
+	out, err := e.bytes()
+	return out, Pos{}, err
+}
+
+func (op patchOperation) moveOrCopy(doc []byte, e *editor, from, path []string, opts []Option) ([]byte, Pos, error) {
+
+	source, err := e.resolve(from)
+	if err != nil {
+		return nil, source.Start, err
+	}
+
+	if op.Op == `copy` {
+		pos, err := e.add(path, source, doc)
+		if err != nil {
+			return nil, pos, err
+		}
+
+		out, err := e.bytes()
+		return out, Pos{}, err
+	}
+
+	if isPrefix(from, path) {
+		if len(from) == len(path) {
+			return doc, Pos{}, nil
+		}
+		return nil, source.Start, fmt.Errorf("cannot move a value into itself")
+	}
+
+	pos, err := e.removeAt(from)
+	if err != nil {
+		return nil, pos, err
+	}
+
+	removed, err := e.bytes()
+	if err != nil {
+		return nil, Pos{}, err
+	}
+
+	root, err := Parse(removed, opts...)
+	if err != nil {
+		return nil, Pos{}, err
+	}
+
+	// the source keeps the text and locations of doc
+	e = newEditor(removed, root, opts)
+	pos, err = e.add(path, source, doc)
+	if err != nil {
+		return nil, pos, err
+	}
+
+	out, err := e.bytes()
+	return out, Pos{}, err
+}
+
+func isPrefix(prefix, tokens []string) bool {
+
+	if len(prefix) > len(tokens) {
+		return false
+	}
+
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the syntax tree of the json value raw written in the style of
+// the document and its text.
+func (e *editor) value(raw json.RawMessage) (*Node, []byte, error) {
+
+	if raw == nil {
+		return nil, nil, fmt.Errorf("missing value")
+	}
+
+	// a document holds no bare scalars, values are parsed as array elements
+	n, err := Parse(wrap(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := Marshal(n.Value().Elements()[0], Space(e.unit), WithDialect(e.dialect))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data = wrap(data)
+	n, err = Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return n.Value().Elements()[0], data, nil
+}
+
+func wrap(value []byte) []byte {
+
+	data := make([]byte, 0, len(value)+2)
+	data = append(data, '[')
+	data = append(data, value...)
+	return append(data, ']')
+}
+
+// resolve returns the value at path. On failure the deepest value found is
+// returned.
+func (e *editor) resolve(path []string) (*Node, error) {
+
+	v := e.root.Value()
+	if v == nil {
+		return e.root, fmt.Errorf("the document is empty")
+	}
+	return resolve(v, path)
+}
+
+// entry returns the container holding the value at path and the index of
+// the value in its items, -1 for a missing member.
+func (e *editor) entry(path []string, end bool) (c *Node, k int, err error) {
+
+	c, err = e.resolve(path[:len(path)-1])
+	if err != nil {
+		return c, 0, err
+	}
+
+	last := path[len(path)-1]
+	switch c.Kind {
+	case ObjectNode:
+		k = -1
+		for idx, it := range items(c) {
+			if c.Children[it.entry].Name() == last {
+				k = idx
+			}
+		}
+		return c, k, nil
+
+	case ArrayNode:
+		k, err = arrayIndex(last, len(c.Elements()), end)
+		return c, k, err
+	}
+	return c, 0, fmt.Errorf("%v has no member %q", c.Kind, last)
+}
+
+// add adds value read from src at path, an existing member is replaced.
+func (e *editor) add(path []string, value *Node, src []byte) (Pos, error) {
+
+	if len(path) == 0 {
+		old := e.root.Value()
+		if old == nil {
+			if len(e.src) > 0 && e.src[len(e.src)-1] != '\n' {
+				e.root.Children = append(e.root.Children, &Node{Kind: SpaceNode, Text: "\n"})
+			}
+			e.root.Children = append(e.root.Children, &Node{Kind: value.Kind, Text: reindent(value, lineIndent(src, value.Start.Offset), ``)})
+			return Pos{}, nil
+		}
+		return Pos{}, e.update(e.root, old, value, src, nil)
+	}
+
+	c, k, err := e.entry(path, true)
+	if err != nil {
+		return c.Start, err
+	}
+
+	if c.Kind == ArrayNode {
+		return Pos{}, e.insert(c, k-1, ``, value, src)
+	}
+
+	if k < 0 {
+		return Pos{}, e.insert(c, len(items(c))-1, path[len(path)-1], value, src)
+	}
+
+	m := c.Children[items(c)[k].entry]
+	return Pos{}, e.update(m, m.Value(), value, src, nil)
+}
+
+// replaceAt replaces the existing value at path by value read from src.
+func (e *editor) replaceAt(path []string, value *Node, src []byte) (Pos, error) {
+
+	if len(path) == 0 {
+		old := e.root.Value()
+		if old == nil {
+			return Pos{}, fmt.Errorf("the document is empty")
+		}
+		return Pos{}, e.update(e.root, old, value, src, nil)
+	}
+
+	c, k, err := e.entry(path, false)
+	if err != nil {
+		return c.Start, err
+	}
+
+	if k < 0 {
+		return c.Start, fmt.Errorf("member %q not found", path[len(path)-1])
+	}
+
+	entry := c.Children[items(c)[k].entry]
+	if c.Kind == ArrayNode {
+		return Pos{}, e.update(c, entry, value, src, nil)
+	}
+	return Pos{}, e.update(entry, entry.Value(), value, src, nil)
+}
+
+// removeAt removes the value at path.
+func (e *editor) removeAt(path []string) (Pos, error) {
+
+	if len(path) == 0 {
+		return Pos{}, fmt.Errorf("cannot remove the document")
+	}
+
+	c, k, err := e.entry(path, false)
+	if err != nil {
+		return c.Start, err
+	}
+
+	if k < 0 {
+		return c.Start, fmt.Errorf("member %q not found", path[len(path)-1])
+	}
+	return Pos{}, e.remove(c, k)
+}
+
+// test checks that the value at path equals value.
+func (e *editor) test(path []string, value *Node) (Pos, error) {
+
+	n, err := e.resolve(path)
+	if err != nil {
+		return n.Start, err
+	}
+
+	same, err := sameValue(n, value)
+	if err != nil {
+		return n.Start, err
+	}
+
+	if !same {
+		return n.Start, ErrTestFailed
+	}
+	return Pos{}, nil
+}
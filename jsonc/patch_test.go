@@ -0,0 +1,223 @@
+package jsonc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const patchDoc = `// service config
+{
+  // the name
+  name: alpha
+
+  /* tags of the service */
+  tags: [a, b]
+  servers: {
+    one: {port: 1} // first
+  }
+}
+`
+
+func TestApplyPatch(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		patch string
+		doc   string
+	}{
+		{
+			name:  `replace`,
+			patch: `[{"op": "replace", "path": "/name", "value": "beta gamma"}]`,
+			doc:   "// service config\n{\n  // the name\n  name: \"beta gamma\"\n\n  /* tags of the service */\n  tags: [a, b]\n  servers: {\n    one: {port: 1} // first\n  }\n}\n",
+		},
+		{
+			name:  `add member`,
+			patch: `[{op: add, path: "/port", value: 8080}]`,
+			doc:   "// service config\n{\n  // the name\n  name: alpha\n\n  /* tags of the service */\n  tags: [a, b]\n  servers: {\n    one: {port: 1} // first\n  }\n  port: 8080\n}\n",
+		},
+		{
+			name:  `add elements`,
+			patch: `[{op: add, path: "/tags/0", value: x}, {op: add, path: "/tags/-", value: z}]`,
+			doc:   "// service config\n{\n  // the name\n  name: alpha\n\n  /* tags of the service */\n  tags: [x, a, b, z]\n  servers: {\n    one: {port: 1} // first\n  }\n}\n",
+		},
+		{
+			name:  `add to a single line object`,
+			patch: `[{op: add, path: "/servers/one/host", value: h}]`,
+			doc:   "// service config\n{\n  // the name\n  name: alpha\n\n  /* tags of the service */\n  tags: [a, b]\n  servers: {\n    one: {port: 1, host: h} // first\n  }\n}\n",
+		},
+		{
+			name:  `replace a single line object`,
+			patch: `[{op: replace, path: "/servers/one", value: {"a": "x", "b": "y z"}}]`,
+			doc:   "// service config\n{\n  // the name\n  name: alpha\n\n  /* tags of the service */\n  tags: [a, b]\n  servers: {\n    one: {a: x, b: \"y z\"} // first\n  }\n}\n",
+		},
+		{
+			name:  `remove`,
+			patch: `[{op: remove, path: "/tags"}, {op: remove, path: "/servers/one/port"}]`,
+			doc:   "// service config\n{\n  // the name\n  name: alpha\n  servers: {\n    one: {} // first\n  }\n}\n",
+		},
+		{
+			name:  `move`,
+			patch: `[{op: move, from: "/servers/one", path: "/primary"}]`,
+			doc:   "// service config\n{\n  // the name\n  name: alpha\n\n  /* tags of the service */\n  tags: [a, b]\n  servers: {}\n  primary: {port: 1}\n}\n",
+		},
+		{
+			name:  `copy and test`,
+			patch: `[{op: copy, from: "/tags", path: "/labels"}, {op: test, path: "/labels", value: ["a", "b"]}]`,
+			doc:   "// service config\n{\n  // the name\n  name: alpha\n\n  /* tags of the service */\n  tags: [a, b]\n  servers: {\n    one: {port: 1} // first\n  }\n  labels: [a, b]\n}\n",
+		},
+		{
+			name:  `escaped pointer`,
+			patch: `[{op: add, path: "/a~1b", value: {"c~d": 1}}, {op: test, path: "/a~1b/c~0d", value: 1.0}]`,
+			doc:   "// service config\n{\n  // the name\n  name: alpha\n\n  /* tags of the service */\n  tags: [a, b]\n  servers: {\n    one: {port: 1} // first\n  }\n  \"a/b\": {\n    \"c~d\": 1\n  }\n}\n",
+		},
+	}
+
+	for _, ts := range tests {
+
+		out, err := ApplyPatch([]byte(patchDoc), []byte(ts.patch))
+		require.NoError(t, err, ts.name)
+		assert.Equal(t, ts.doc, string(out), ts.name)
+		assert.True(t, Valid(out), ts.name)
+	}
+}
+
+func TestApplyPatchRoot(t *testing.T) {
+
+	out, err := ApplyPatch([]byte(`// empty`), []byte(`[{op: add, path: "", value: {a: 1}}]`))
+	require.NoError(t, err)
+	assert.Equal(t, "// empty\n{\n  a: 1\n}", string(out))
+
+	out, err = ApplyPatch([]byte(`{"a": 1}`), []byte(`[{op: replace, path: "", value: [1, 2]}]`))
+	require.NoError(t, err)
+	assert.Equal(t, `[1,2]`, string(out))
+
+	out, err = ApplyPatch([]byte(`{"a": 1}`), []byte(`[{op: add, path: "/b", value: x}]`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a": 1, "b": "x"}`, string(out))
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+
+	tests := []struct {
+		patch string
+		err   string
+	}{
+		{
+			patch: `[{op: replace, path: "/missing", value: 1}]`,
+			err:   `line: 2 col: 1 patch operation 0 (replace "/missing") failed: member "missing" not found`,
+		},
+		{
+			patch: `[{op: test, path: "/name", value: alpha}, {op: remove, path: "/tags/2"}]`,
+			err:   `line: 7 col: 9 patch operation 1 (remove "/tags/2") failed: array index 2 out of range`,
+		},
+		{
+			patch: `[{op: test, path: "/servers/one/port", value: 2}]`,
+			err:   `line: 9 col: 17 patch operation 0 (test "/servers/one/port") failed: test failed`,
+		},
+		{
+			patch: `[{op: add, path: "/name/x", value: 1}]`,
+			err:   `line: 4 col: 9 patch operation 0 (add "/name/x") failed: string has no member "x"`,
+		},
+		{
+			patch: `[{op: move, from: "/servers", path: "/servers/two"}]`,
+			err:   `line: 8 col: 12 patch operation 0 (move "/servers/two") failed: cannot move a value into itself`,
+		},
+		{
+			patch: `[{op: add, path: name, value: 1}]`,
+			err:   `patch operation 0 (add "name") failed: json pointer "name" does not start with /`,
+		},
+		{
+			patch: `[{op: add, path: "/a"}]`,
+			err:   `patch operation 0 (add "/a") failed: missing value`,
+		},
+		{
+			patch: `[{op: remove, path: ""}]`,
+			err:   `patch operation 0 (remove "") failed: cannot remove the document`,
+		},
+		{
+			patch: `[{op: merge, path: "/a"}]`,
+			err:   `patch operation 0 (merge "/a") failed: unknown operation "merge"`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		out, err := ApplyPatch([]byte(patchDoc), []byte(ts.patch))
+		require.Error(t, err, ts.patch)
+		assert.Nil(t, out, ts.patch)
+		assert.Equal(t, ts.err, err.Error(), ts.patch)
+
+		var perr *PatchError
+		assert.True(t, errors.As(err, &perr), ts.patch)
+	}
+
+	_, err := ApplyPatch([]byte(patchDoc), []byte(`[{op: test, path: "/name", value: beta}]`))
+	assert.True(t, errors.Is(err, ErrTestFailed))
+
+	_, err = ApplyPatch([]byte(patchDoc), []byte(`{op: add}`))
+	assert.Error(t, err)
+}
+
+func TestParsePointer(t *testing.T) {
+
+	tests := []struct {
+		pointer string
+		tokens  []string
+		err     bool
+	}{
+		{pointer: ``},
+		{pointer: `/`, tokens: []string{``}},
+		{pointer: `/a/0`, tokens: []string{`a`, `0`}},
+		{pointer: `/a~1b/c~0d/~01`, tokens: []string{`a/b`, `c~d`, `~1`}},
+		{pointer: `a`, err: true},
+		{pointer: `/a~`, err: true},
+		{pointer: `/a~2`, err: true},
+	}
+
+	for _, ts := range tests {
+
+		tokens, err := parsePointer(ts.pointer)
+		if ts.err {
+			assert.Error(t, err, ts.pointer)
+			continue
+		}
+
+		require.NoError(t, err, ts.pointer)
+		assert.Equal(t, ts.tokens, tokens, ts.pointer)
+	}
+}
+
+func TestArrayIndex(t *testing.T) {
+
+	tests := []struct {
+		token string
+		end   bool
+		idx   int
+		err   bool
+	}{
+		{token: `0`, idx: 0},
+		{token: `2`, idx: 2},
+		{token: `3`, err: true},
+		{token: `3`, end: true, idx: 3},
+		{token: `-`, err: true},
+		{token: `-`, end: true, idx: 3},
+		{token: `01`, err: true},
+		{token: `+1`, err: true},
+		{token: ``, err: true},
+	}
+
+	for _, ts := range tests {
+
+		idx, err := arrayIndex(ts.token, 3, ts.end)
+		if ts.err {
+			assert.Error(t, err, ts.token)
+			continue
+		}
+
+		require.NoError(t, err, ts.token)
+		assert.Equal(t, ts.idx, idx, ts.token)
+	}
+}
@@ -0,0 +1,101 @@
+package jsonc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	pointerEscaper   = strings.NewReplacer(`~`, `~0`, `/`, `~1`)
+	pointerUnescaper = strings.NewReplacer(`~1`, `/`, `~0`, `~`)
+)
+
+// EscapePointer escapes a member name as reference token of an RFC 6901 JSON
+// Pointer.
+func EscapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped reference
+// tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+
+	if pointer == `` {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("json pointer %q does not start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], `/`)
+	for i, t := range tokens {
+
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j+1 == len(t) || (t[j+1] != '0' && t[j+1] != '1')) {
+				return nil, fmt.Errorf("json pointer %q has an invalid escape", pointer)
+			}
+		}
+
+		tokens[i] = pointerUnescaper.Replace(t)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses the reference token of an array element. The token "-"
+// refers to the element behind the last one and is allowed with end only.
+func arrayIndex(token string, length int, end bool) (int, error) {
+
+	if token == `-` && end {
+		return length, nil
+	}
+
+	if token == `` || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, `0123456789`) != `` {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	idx, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	max := length - 1
+	if end {
+		max = length
+	}
+
+	if idx > max {
+		return 0, fmt.Errorf("array index %v out of range", idx)
+	}
+	return idx, nil
+}
+
+// resolve returns the value the reference tokens refer to starting at the
+// value n. On failure it returns the deepest value found with the error.
+func resolve(n *Node, tokens []string) (*Node, error) {
+
+	for _, t := range tokens {
+
+		switch n.Kind {
+		case ObjectNode:
+			m := n.Lookup(t)
+			if m == nil {
+				return n, fmt.Errorf("member %q not found", t)
+			}
+			n = m.Value()
+
+		case ArrayNode:
+			elements := n.Elements()
+			idx, err := arrayIndex(t, len(elements), false)
+			if err != nil {
+				return n, err
+			}
+			n = elements[idx]
+
+		default:
+			return n, fmt.Errorf("%v has no member %q", n.Kind, t)
+		}
+	}
+	return n, nil
+}
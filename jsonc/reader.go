@@ -2,13 +2,10 @@ package jsonc
 
 import "io"
 
-func New(r io.RuneReader, minimize bool, space string) (*Filter, error) {
-
-	ring, err := NewRing(256, 64, r.ReadRune)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return NewFilter(ring, 256, !minimize, space), nil
+// New returns a Filter reading jsonc from r, readers which do not implement
+// io.RuneReader are buffered. Unless minimize is set the output is formatted
+// jsonc indented by space per nesting level, earlier versions indented by a
+// single space per level whatever space was.
+func New(r io.Reader, minimize bool, space string, opts ...Option) (*Filter, error) {
+	return newConfig(opts).filter(runeReader(r).ReadRune, !minimize, space)
 }
@@ -0,0 +1,425 @@
+package jsonc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Include is a $ref object which was followed to another file.
+type Include struct {
+	File string // the file holding the $ref object, empty for the document
+	Pos  Pos    // the location of the $ref object
+	Ref  string // the value of $ref
+}
+
+// RefError is a $ref which could not be resolved. Chain lists the $ref
+// objects followed from the document to File, the file in which Err
+// occurred.
+type RefError struct {
+	Chain []Include
+	File  string
+	Err   error
+}
+
+func (e *RefError) Error() string {
+
+	buf := &strings.Builder{}
+	for _, inc := range e.Chain {
+
+		if inc.File != `` {
+			buf.WriteString(inc.File)
+			buf.WriteByte(':')
+		}
+		fmt.Fprintf(buf, "%v $ref %q -> ", inc.Pos, inc.Ref)
+	}
+
+	if e.File != `` {
+		buf.WriteString(e.File)
+		buf.WriteString(`: `)
+	}
+	buf.WriteString(e.Err.Error())
+	return buf.String()
+}
+
+func (e *RefError) Unwrap() error {
+	return e.Err
+}
+
+// includes loads the files $ref objects refer to. A file is loaded once, its
+// values are resolved when they are referred to.
+type includes struct {
+	fsys    fs.FS
+	files   map[string]*references
+	walking map[*Node]bool // the $ref objects followed by a JSON Pointer
+	chain   []Include      // the $ref objects being followed
+}
+
+func newIncludes(fsys fs.FS) *includes {
+	return &includes{fsys: fsys, files: map[string]*references{}, walking: map[*Node]bool{}}
+}
+
+// load returns the references of the file name converted with c.
+func (in *includes) load(c config, name string) (*references, error) {
+
+	if rs, ok := in.files[name]; ok {
+		return rs, nil
+	}
+
+	data, err := fs.ReadFile(in.fsys, name)
+	if err != nil {
+		return nil, in.wrap(name, err)
+	}
+
+	c.file = name
+	out, srcmap, err := c.filterAll(data, false, ``, true)
+	if err != nil {
+		return nil, in.wrap(name, err)
+	}
+
+	doc, err := Parse(out)
+	if err != nil {
+		return nil, in.wrap(name, err)
+	}
+
+	rs := newReferences(c, doc.Value(), func(offset int) Pos {
+		pos, _ := srcmap.lookup(offset)
+		return pos
+	})
+	in.files[name] = rs
+	return rs, nil
+}
+
+// wrap returns err of the file name as *RefError. Errors of files included
+// by the file already are.
+func (in *includes) wrap(name string, err error) error {
+
+	var rerr *RefError
+	if errors.As(err, &rerr) {
+		return err
+	}
+	return &RefError{Chain: append([]Include(nil), in.chain...), File: name, Err: err}
+}
+
+func (in *includes) errorf(name string, format string, args ...interface{}) error {
+	return in.wrap(name, fmt.Errorf(format, args...))
+}
+
+// resolveReferences returns the json value data with its ${.path} references
+// and $ref objects resolved as configured by c. locate returns the jsonc
+// location of an offset in data.
+func (c config) resolveReferences(data []byte, locate func(offset int) Pos) ([]byte, error) {
+
+	if !(c.references && bytes.Contains(data, []byte(`${`))) &&
+		!(c.refs != nil && bytes.Contains(data, []byte(`"$ref"`))) {
+		return data, nil
+	}
+
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	root := doc.Value()
+	if root == nil {
+		return data, nil
+	}
+
+	// $ref objects naming the document refer to the document itself
+	rs := newReferences(c, root, locate)
+	if c.refs != nil && c.file != `` {
+		c.refs.files[c.file] = rs
+	}
+	return rs.value(root)
+}
+
+func newReferences(c config, root *Node, locate func(offset int) Pos) *references {
+	return &references{
+		c:      c,
+		root:   root,
+		locate: locate,
+		done:   map[*Node][]byte{},
+		active: map[*Node]bool{},
+	}
+}
+
+// references resolves the references of a document.
+type references struct {
+	c      config
+	root   *Node
+	locate func(offset int) Pos
+	done   map[*Node][]byte // the json of the resolved values
+	active map[*Node]bool   // the values being resolved
+	chain  []string         // the references being followed
+}
+
+// value returns the json of n with its references resolved.
+func (rs *references) value(n *Node) ([]byte, error) {
+
+	if out, ok := rs.done[n]; ok {
+		return out, nil
+	}
+
+	rs.active[n] = true
+	defer delete(rs.active, n)
+
+	ref, isRef := rs.ref(n)
+
+	var out []byte
+	var err error
+	switch {
+	case isRef:
+		out, err = rs.include(n, ref)
+
+	case n.Kind == ObjectNode:
+		out = append(out, '{')
+		for idx, m := range n.Members() {
+
+			if idx > 0 {
+				out = append(out, ',')
+			}
+			out = appendQuoted(out, m.Name())
+			out = append(out, ':')
+
+			var v []byte
+			v, err = rs.value(m.Value())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v...)
+		}
+		out = append(out, '}')
+
+	case n.Kind == ArrayNode:
+		out = append(out, '[')
+		for idx, e := range n.Elements() {
+
+			if idx > 0 {
+				out = append(out, ',')
+			}
+
+			var v []byte
+			v, err = rs.value(e)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v...)
+		}
+		out = append(out, ']')
+
+	case n.Kind == StringNode && rs.c.references:
+		out, err = rs.str(n)
+
+	default:
+		out, err = n.JSON()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	rs.done[n] = out
+	return out, nil
+}
+
+// str returns the json of the string n. A string which is a single reference
+// is the referenced value, references within text are replaced by the text
+// of the scalars they refer to.
+func (rs *references) str(n *Node) ([]byte, error) {
+
+	var s string
+	err := n.Decode(&s)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.Contains(s, `${`) {
+		return n.JSON()
+	}
+
+	if strings.HasPrefix(s, `${.`) && strings.IndexByte(s, '}') == len(s)-1 {
+		return rs.follow(n, s)
+	}
+
+	buf := &strings.Builder{}
+	for {
+		idx := strings.IndexByte(s, '$')
+		if idx < 0 {
+			buf.WriteString(s)
+			return appendQuoted(nil, buf.String()), nil
+		}
+
+		buf.WriteString(s[:idx])
+		s = s[idx:]
+
+		end := strings.IndexByte(s, '}')
+		switch {
+		case strings.HasPrefix(s, `$${`):
+			buf.WriteString(`${`)
+			s = s[3:]
+
+		case strings.HasPrefix(s, `${.`) && end > 0:
+			v, err := rs.follow(n, s[:end+1])
+			if err != nil {
+				return nil, err
+			}
+
+			text, err := refText(v)
+			if err != nil {
+				return nil, rs.errorf(n, s[:end+1], "%v", err)
+			}
+			buf.WriteString(text)
+			s = s[end+1:]
+
+		default:
+			buf.WriteByte('$')
+			s = s[1:]
+		}
+	}
+}
+
+// follow returns the json of the value the reference ref of the string n
+// refers to.
+func (rs *references) follow(n *Node, ref string) ([]byte, error) {
+
+	path := strings.TrimSuffix(strings.TrimPrefix(ref, `${.`), `}`)
+	target, err := resolve(rs.root, splitPath(path))
+	if err != nil {
+		return nil, rs.errorf(n, ref, "%v", err)
+	}
+
+	rs.chain = append(rs.chain, ref)
+	defer func() {
+		rs.chain = rs.chain[:len(rs.chain)-1]
+	}()
+
+	if rs.active[target] {
+		return nil, rs.errorf(n, ref, "reference cycle %v", strings.Join(rs.chain, ` -> `))
+	}
+	return rs.value(target)
+}
+
+func (rs *references) errorf(n *Node, ref string, format string, args ...interface{}) error {
+	return &InterpolationError{
+		Pos:         rs.locate(n.Start.Offset),
+		Placeholder: ref,
+		Err:         fmt.Errorf(format, args...),
+	}
+}
+
+// ref returns the value of $ref if n is a $ref object.
+func (rs *references) ref(n *Node) (string, bool) {
+
+	if rs.c.refs == nil || n.Kind != ObjectNode {
+		return ``, false
+	}
+
+	m := n.Lookup(`$ref`)
+	if m == nil || m.Value().Kind != StringNode {
+		return ``, false
+	}
+
+	var ref string
+	err := m.Value().Decode(&ref)
+	return ref, err == nil
+}
+
+// include returns the json of the value the $ref object n refers to.
+func (rs *references) include(n *Node, ref string) ([]byte, error) {
+
+	in := rs.c.refs
+	in.chain = append(in.chain, Include{File: rs.c.file, Pos: rs.locate(n.Start.Offset), Ref: ref})
+	defer func() {
+		in.chain = in.chain[:len(in.chain)-1]
+	}()
+
+	ts, target, err := rs.target(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if ts.active[target] {
+		if strings.HasPrefix(ref, `#`) {
+			return nil, in.errorf(ts.c.file, `reference cycle`)
+		}
+		return nil, in.errorf(ts.c.file, `include cycle`)
+	}
+	return ts.value(target)
+}
+
+// target returns the value ref refers to and the references of the file
+// holding it. ref is a file path relative to the file of rs, a JSON Pointer
+// fragment or both. The $ref objects the pointer passes are followed.
+func (rs *references) target(ref string) (*references, *Node, error) {
+
+	in := rs.c.refs
+	file, fragment := ref, ``
+	if idx := strings.IndexByte(ref, '#'); idx >= 0 {
+		file, fragment = ref[:idx], ref[idx+1:]
+	}
+
+	tokens, err := parsePointer(fragment)
+	if err != nil {
+		return nil, nil, in.wrap(rs.c.file, err)
+	}
+
+	ts := rs
+	if file != `` {
+		ts, err = in.load(rs.c, path.Join(path.Dir(rs.c.file), file))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	chain := len(in.chain)
+	defer func() {
+		in.chain = in.chain[:chain]
+	}()
+
+	n := ts.root
+	for _, t := range tokens {
+
+		for {
+			ref, isRef := ts.ref(n)
+			if !isRef {
+				break
+			}
+
+			if in.walking[n] {
+				return nil, nil, in.errorf(ts.c.file, `reference cycle`)
+			}
+			in.chain = append(in.chain, Include{File: ts.c.file, Pos: ts.locate(n.Start.Offset), Ref: ref})
+
+			from := n
+			in.walking[from] = true
+			ts, n, err = ts.target(ref)
+			delete(in.walking, from)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		n, err = resolve(n, []string{t})
+		if err != nil {
+			return nil, nil, in.wrap(ts.c.file, err)
+		}
+	}
+	return ts, n, nil
+}
+
+// refText returns the text of the json scalar v as written into strings.
+func refText(v []byte) (string, error) {
+
+	switch v[0] {
+	case '{', '[':
+		return ``, fmt.Errorf("%v is not a scalar", rawKind(v))
+
+	case '"':
+		var s string
+		err := Unmarshal(v, &s)
+		return s, err
+	}
+	return string(v), nil
+}
@@ -0,0 +1,249 @@
+package jsonc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferences(t *testing.T) {
+
+	tests := []struct {
+		doc  string
+		json string
+	}{
+		{doc: `{a: db, b: "${.a}"}`, json: `{"a":"db","b":"db"}`},
+		{doc: `{b: ${.a}, a: db}`, json: `{"b":"db","a":"db"}`},
+		{doc: `{port: 80, b: ${.port}, c: "${.debug}", debug: true}`, json: `{"port":80,"b":80,"c":true,"debug":true}`},
+		{doc: `{db: {host: h, port: 5432}, copy: ${.db}}`, json: `{"db":{"host":"h","port":5432},"copy":{"host":"h","port":5432}}`},
+		{doc: `{db: {host: h, port: 5432}, url: "pg://${.db.host}:${.db.port}/x"}`, json: `{"db":{"host":"h","port":5432},"url":"pg://h:5432/x"}`},
+		{doc: `{servers: [{ip: "10.0.0.1"}], primary: ${.servers.0.ip}}`, json: `{"servers":[{"ip":"10.0.0.1"}],"primary":"10.0.0.1"}`},
+		{doc: `{a: "x", b: ${.a}, c: ${.b}}`, json: `{"a":"x","b":"x","c":"x"}`},
+		{doc: `{"a.b": 1, c: ${.a\.b}}`, json: `{"a.b":1,"c":1}`},
+		{doc: `{a: 1, b: "$${.a} is ${.a}", c: "${HOST} costs $5"}`, json: `{"a":1,"b":"${.a} is 1","c":"${HOST} costs $5"}`},
+		{doc: `{a: [1, 2], b: {c: ${.a}}}`, json: `{"a":[1,2],"b":{"c":[1,2]}}`},
+		{doc: `{a: "q\"${.b}", b: "x\\y"}`, json: `{"a":"q\"x\\y","b":"x\\y"}`},
+		{doc: `[1, ${.0}]`, json: `[1,1]`},
+	}
+
+	for _, ts := range tests {
+
+		out, err := ToJSON([]byte(ts.doc), References())
+		require.NoError(t, err, ts.doc)
+		assert.Equal(t, ts.json, string(out), ts.doc)
+
+		dec, err := NewDecoder(strings.NewReader(ts.doc), References(), OutputSize(1))
+		require.NoError(t, err)
+
+		var v interface{}
+		require.NoError(t, dec.Decode(&v), ts.doc)
+
+		var expected interface{}
+		require.NoError(t, Unmarshal([]byte(ts.json), &expected))
+		assert.Equal(t, expected, v, ts.doc)
+	}
+
+	// without the option references are text
+	out, err := ToJSON([]byte(`{a: 1, b: "${.a}"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1,"b":"${.a}"}`, string(out))
+
+	doc := "{\n  a: 1\n  b: ${.a}\n}"
+	out, err = Format([]byte(doc), References())
+	require.NoError(t, err)
+	assert.Equal(t, doc, string(out))
+
+	// placeholders are expanded before references are resolved
+	out, err = ToJSON([]byte(`{host: ${HOST}, url: "http://${.host}:${PORT}", lit: "$${.host}"}`), Interpolate(testResolvers), References())
+	require.NoError(t, err)
+	assert.Equal(t, `{"host":"db.local","url":"http://db.local:5432","lit":"${.host}"}`, string(out))
+}
+
+func TestReferencesStream(t *testing.T) {
+
+	dec, err := NewDecoder(strings.NewReader("{a: 1, b: ${.a}}\n{a: 2, b: \"${.a}\"}"), References(), OutputSize(3))
+	require.NoError(t, err)
+
+	var values []interface{}
+	for {
+		var v interface{}
+		err = dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		values = append(values, v)
+	}
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{`a`: float64(1), `b`: float64(1)},
+		map[string]interface{}{`a`: float64(2), `b`: float64(2)},
+	}, values)
+}
+
+func TestReferencesErrors(t *testing.T) {
+
+	tests := []struct {
+		doc  string
+		line int
+		col  int
+		err  string
+	}{
+		{doc: "{\n  a: 1\n  b: ${.missing}\n}", line: 3, col: 6, err: `${.missing}: member "missing" not found`},
+		{doc: "{\n  a: \"${.b}\"\n  b: ${.a}\n}", line: 3, col: 6, err: `reference cycle ${.b} -> ${.a}`},
+		{doc: "{\n  a: {\n    b: ${.a}\n  }\n}", line: 3, col: 8, err: `reference cycle ${.a}`},
+		{doc: "{\n  a: ${.a}\n}", line: 2, col: 6, err: `reference cycle ${.a}`},
+		{doc: "{\n  a: [1]\n  b: \"x ${.a}\"\n}", line: 3, col: 6, err: `${.a}: array is not a scalar`},
+		{doc: "{\n  a: [1]\n  b: ${.a.3}\n}", line: 3, col: 6, err: `${.a.3}`},
+	}
+
+	for _, ts := range tests {
+
+		var v interface{}
+		err := Unmarshal([]byte(ts.doc), &v, References())
+		require.Error(t, err, ts.doc)
+
+		var ierr *InterpolationError
+		require.True(t, errors.As(err, &ierr), ts.doc)
+		assert.Equal(t, ts.line, ierr.Pos.Line, ts.doc)
+		assert.Equal(t, ts.col, ierr.Pos.Column, ts.doc)
+		assert.Contains(t, err.Error(), ts.err, ts.doc)
+
+		_, err = ToJSON([]byte(ts.doc), References())
+		require.True(t, errors.As(err, &ierr), ts.doc)
+		assert.Equal(t, ts.line, ierr.Pos.Line, ts.doc)
+	}
+}
+
+func TestResolveRefs(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		`conf/common.jsonc`: {Data: []byte(`{
+  // shared by all services
+  timeouts: {read: 5, write: ${.timeouts.read}}
+  hosts: {"$ref": "hosts/list.jsonc"}
+}`)},
+		`conf/hosts/list.jsonc`: {Data: []byte(`[a, b] // relative to common.jsonc`)},
+		`conf/a~b.jsonc`:        {Data: []byte(`{"x/y": 1}`)},
+		`conf/pair/a.jsonc`:     {Data: []byte(`{x: {"$ref": "b.jsonc#/y"}, z: 1}`)},
+		`conf/pair/b.jsonc`:     {Data: []byte(`{y: 2, w: {"$ref": "a.jsonc#/z"}}`)},
+		`conf/self.jsonc`:       {Data: []byte(`{x: {"$ref": "self.jsonc#/y"}, y: 3}`)},
+		`conf/sub/db.jsonc`:     {Data: []byte(`{prod: {port: 5432}, staging: {"$ref": "#/prod"}}`)},
+	}
+
+	tests := []struct {
+		doc  string
+		json string
+	}{
+		{doc: `{timeouts: {"$ref": "common.jsonc#/timeouts"}}`, json: `{"timeouts":{"read":5,"write":5}}`},
+		{doc: `{hosts: {"$ref": "common.jsonc#/hosts/1"}, all: {"$ref": "common.jsonc#/hosts"}}`, json: `{"hosts":"b","all":["a","b"]}`},
+		{doc: `{c: {"$ref": "common.jsonc"}}`, json: `{"c":{"timeouts":{"read":5,"write":5},"hosts":["a","b"]}}`},
+		{doc: `{a: {"$ref": "hosts/list.jsonc#/0"}, b: {"$ref": "./a~b.jsonc#/x~1y"}}`, json: `{"a":"a","b":1}`},
+		{doc: `{defaults: {port: 80}, web: {"$ref": "#/defaults"}, ref: {"$ref": 1}}`, json: `{"defaults":{"port":80},"web":{"port":80},"ref":{"$ref":1}}`},
+		{doc: `{"$ref": "common.jsonc#/timeouts/read"}`, json: `5`},
+		{doc: `{a: {"$ref": "pair/a.jsonc"}, b: {"$ref": "pair/b.jsonc"}}`, json: `{"a":{"x":2,"z":1},"b":{"y":2,"w":1}}`},
+		{doc: `{s: {"$ref": "self.jsonc"}}`, json: `{"s":{"x":3,"y":3}}`},
+		{doc: `{db: {"$ref": "sub/db.jsonc#/prod"}, x: {"$ref": "#/db/port"}}`, json: `{"db":{"port":5432},"x":5432}`},
+		{doc: `{x: {"$ref": "sub/db.jsonc#/staging/port"}}`, json: `{"x":5432}`},
+	}
+
+	for _, ts := range tests {
+
+		out, err := ToJSON([]byte(ts.doc), ResolveRefs(fsys, `conf/main.jsonc`), References())
+		require.NoError(t, err, ts.doc)
+		assert.Equal(t, ts.json, string(out), ts.doc)
+
+		dec, err := NewDecoder(strings.NewReader(ts.doc), ResolveRefs(fsys, `conf/main.jsonc`), References(), OutputSize(1))
+		require.NoError(t, err)
+
+		var v interface{}
+		require.NoError(t, dec.Decode(&v), ts.doc)
+
+		var expected interface{}
+		require.NoError(t, json.Unmarshal([]byte(ts.json), &expected))
+		assert.Equal(t, expected, v, ts.doc)
+	}
+
+	// files referring to each other are read on their own as well
+	for name, json := range map[string]string{
+		`conf/pair/b.jsonc`: `{"y":2,"w":1}`,
+		`conf/self.jsonc`:   `{"x":3,"y":3}`,
+	} {
+		out, err := ToJSON(fsys[name].Data, ResolveRefs(fsys, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, json, string(out), name)
+	}
+
+	// without the option $ref objects stay
+	out, err := ToJSON([]byte(`{a: {"$ref": "common.jsonc"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":{"$ref":"common.jsonc"}}`, string(out))
+}
+
+func TestResolveRefsErrors(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		`main.jsonc`:   {Data: []byte("{\n  a: {\"$ref\": \"a.jsonc#/x\"}\n}")},
+		`a.jsonc`:      {Data: []byte("{\n  x: {\"$ref\": \"broken.jsonc\"}\n}")},
+		`broken.jsonc`: {Data: []byte("{\n  x: -\n}")},
+		`ports.jsonc`:  {Data: []byte(`{http: 80}`)},
+		`loop.jsonc`:   {Data: []byte(`{"$ref": "main.jsonc"}`)},
+		`self.jsonc`:   {Data: []byte(`{a: {"$ref": "#/b"}, b: {"$ref": "#/a"}}`)},
+		`walk.jsonc`:   {Data: []byte(`{a: {"$ref": "#/a/b"}}`)},
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		err  string
+	}{
+		{name: `main.jsonc`, doc: "{\n  a: {\"$ref\": \"a.jsonc#/x\"}\n}",
+			err: `main.jsonc:2:6 $ref "a.jsonc#/x" -> a.jsonc:2:6 $ref "broken.jsonc" -> broken.jsonc: line: 2 col: 6 invalid identifier`},
+		{name: `main.jsonc`, doc: `{a: {"$ref": "loop.jsonc"}}`,
+			err: `main.jsonc:1:5 $ref "loop.jsonc" -> loop.jsonc:1:1 $ref "main.jsonc" -> main.jsonc: include cycle`},
+		{name: `main.jsonc`, doc: `{a: {"$ref": "missing.jsonc"}}`,
+			err: `main.jsonc:1:5 $ref "missing.jsonc" -> missing.jsonc: open missing.jsonc: file does not exist`},
+		{name: `main.jsonc`, doc: `{a: {"$ref": "ports.jsonc#/https"}}`,
+			err: `main.jsonc:1:5 $ref "ports.jsonc#/https" -> ports.jsonc: member "https" not found`},
+		{name: `main.jsonc`, doc: `{a: {"$ref": "self.jsonc"}}`,
+			err: `main.jsonc:1:5 $ref "self.jsonc" -> self.jsonc:1:5 $ref "#/b" -> self.jsonc:1:25 $ref "#/a" -> self.jsonc: reference cycle`},
+		{name: `main.jsonc`, doc: `{a: {"$ref": "walk.jsonc#/a"}}`,
+			err: `main.jsonc:1:5 $ref "walk.jsonc#/a" -> walk.jsonc:1:5 $ref "#/a/b" -> walk.jsonc:1:5 $ref "#/a/b" -> walk.jsonc: reference cycle`},
+		{name: `main.jsonc`, doc: `{a: {"$ref": "#/p/https"}, p: {"$ref": "ports.jsonc"}}`,
+			err: `main.jsonc:1:5 $ref "#/p/https" -> main.jsonc:1:31 $ref "ports.jsonc" -> ports.jsonc: member "https" not found`},
+		{doc: "{\n  a: {\"$ref\": \"#x\"}\n}",
+			err: `2:6 $ref "#x" -> json pointer "x" does not start with /`},
+		{doc: `{a: {"$ref": "../a.jsonc"}}`,
+			err: `1:5 $ref "../a.jsonc" -> ../a.jsonc: open ../a.jsonc: file does not exist`},
+	}
+
+	for _, ts := range tests {
+
+		var v interface{}
+		err := Unmarshal([]byte(ts.doc), &v, ResolveRefs(fsys, ts.name))
+		require.Error(t, err, ts.doc)
+
+		var rerr *RefError
+		require.True(t, errors.As(err, &rerr), ts.doc)
+		assert.Equal(t, ts.err, err.Error(), ts.doc)
+	}
+
+	// the whole include chain is reported for syntax errors
+	_, err := ToJSON(fsys[`main.jsonc`].Data, ResolveRefs(fsys, `main.jsonc`))
+	var rerr *RefError
+	require.True(t, errors.As(err, &rerr))
+	assert.Equal(t, []Include{
+		{File: `main.jsonc`, Pos: Pos{Offset: 7, Line: 2, Column: 6}, Ref: `a.jsonc#/x`},
+		{File: `a.jsonc`, Pos: Pos{Offset: 7, Line: 2, Column: 6}, Ref: `broken.jsonc`},
+	}, rerr.Chain)
+	assert.Equal(t, `broken.jsonc`, rerr.File)
+
+	var serr Error
+	assert.True(t, errors.As(err, &serr))
+}
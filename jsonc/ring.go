@@ -6,8 +6,21 @@ import (
 
 type ReadRune func() (r rune, size int, err error)
 
+// Pos is a location in the input read by a Ring.
+type Pos struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number counted in runes, starting at 1
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%v:%v", p.Line, p.Column)
+}
+
 type Ring struct {
 	buf         []rune
+	pos         []Pos
+	next        Pos
 	readRune    ReadRune
 	minSize     int
 	maxSize     int
@@ -21,7 +34,7 @@ func NewRing(maxSize int, minSize int, readRune ReadRune) (r *Ring, err error) {
 		return nil, fmt.Errorf("maxSize(%v) <= minSize(%v)", maxSize, minSize)
 	}
 
-	r = &Ring{readRune: readRune, minSize: minSize, maxSize: maxSize}
+	r = &Ring{readRune: readRune, minSize: minSize, maxSize: maxSize, next: Pos{Line: 1, Column: 1}}
 	if readRune != nil {
 		err = r.fill()
 	}
@@ -31,6 +44,8 @@ func NewRing(maxSize int, minSize int, readRune ReadRune) (r *Ring, err error) {
 func (r *Ring) Clear(rr ReadRune) (err error) {
 	r.readRune = rr
 	r.buf = nil
+	r.pos = nil
+	r.next = Pos{Line: 1, Column: 1}
 	r.position = 0
 	r.absPosition = 0
 
@@ -42,6 +57,22 @@ func (r *Ring) Position() int {
 	return r.absPosition + r.position - len(r.buf)
 }
 
+// Pos returns the location of the current rune.
+func (r *Ring) Pos() Pos {
+	if len(r.pos) == 0 {
+		return r.next
+	}
+	return r.pos[r.position]
+}
+
+// EndPos returns the location right after the current rune.
+func (r *Ring) EndPos() Pos {
+	if r.position+1 < len(r.pos) {
+		return r.pos[r.position+1]
+	}
+	return r.next
+}
+
 func (r *Ring) Advance() error {
 
 	if r.position+1 >= len(r.buf) {
@@ -49,6 +80,7 @@ func (r *Ring) Advance() error {
 		if len(r.buf) > r.maxSize {
 
 			r.buf = r.buf[r.maxSize-r.minSize:]
+			r.pos = r.pos[r.maxSize-r.minSize:]
 			r.position -= (r.maxSize - r.minSize)
 			if r.position < 0 {
 				panic(fmt.Errorf("unexpected error"))
@@ -66,12 +98,21 @@ func (r *Ring) Advance() error {
 }
 
 func (r *Ring) fill() error {
-	ru, _, err := r.readRune()
+	ru, size, err := r.readRune()
 	if err != nil {
 		return err
 	}
 
 	r.buf = append(r.buf, ru)
+	r.pos = append(r.pos, r.next)
+
+	r.next.Offset += size
+	if ru == '\n' {
+		r.next.Line++
+		r.next.Column = 1
+	} else {
+		r.next.Column++
+	}
 	r.absPosition += 1
 	return nil
 }
@@ -0,0 +1,74 @@
+package jsonc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+var ErrInvalidRune = fmt.Errorf(`invalid-rune`)
+
+// NewRuneReader returns a RuneReader decoding the utf-8 input of reader.
+func NewRuneReader(reader io.Reader) *RuneReader {
+	return &RuneReader{reader: reader}
+}
+
+// RuneReader adapts an io.Reader to an io.RuneReader, unlike bufio.Reader it
+// returns ErrInvalidRune for invalid utf-8 input.
+type RuneReader struct {
+	reader io.Reader
+	isEOF  bool
+	buf    [4]byte
+	length int
+}
+
+func (r *RuneReader) decodeRune() (rune, int, error) {
+
+	ru, size := utf8.DecodeRune(r.buf[:r.length])
+
+	if ru == utf8.RuneError && size <= 1 {
+		return '0', 0, ErrInvalidRune
+	}
+
+	copy(r.buf[:], r.buf[size:r.length])
+	r.length -= size
+
+	return ru, size, nil
+}
+
+func (r *RuneReader) ReadRune() (rune, int, error) {
+
+	// a rune may be split over several reads
+	for !r.isEOF && !utf8.FullRune(r.buf[:r.length]) {
+
+		n, err := r.reader.Read(r.buf[r.length:])
+		r.length += n
+
+		if errors.Is(err, io.EOF) {
+			r.isEOF = true
+			break
+		}
+
+		if err != nil {
+			return '0', 0, fmt.Errorf(`runeReader.ReadRune reader.Read failed %w`, err)
+		}
+	}
+
+	if r.length == 0 {
+		return '0', 0, io.EOF
+	}
+
+	return r.decodeRune()
+}
+
+// runeReader returns r if it reads runes already, other readers are buffered
+// and decoded by a RuneReader.
+func runeReader(r io.Reader) io.RuneReader {
+
+	if rr, ok := r.(io.RuneReader); ok {
+		return rr
+	}
+	return NewRuneReader(bufio.NewReader(r))
+}
@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// readComments parses the Go files in dir and returns the doc comments of
+// the type declarations by type name and of their struct fields by
+// "Type.Field".
+func readComments(dir string) (map[string]string, error) {
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := map[string]string{}
+	add := func(name string, groups ...*ast.CommentGroup) {
+
+		for _, g := range groups {
+			if text := strings.TrimSpace(g.Text()); text != `` {
+				comments[name] = text
+				return
+			}
+		}
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range gd.Specs {
+
+					ts := spec.(*ast.TypeSpec)
+					if len(gd.Specs) == 1 {
+						add(ts.Name.Name, ts.Doc, gd.Doc)
+					} else {
+						add(ts.Name.Name, ts.Doc)
+					}
+
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+
+					for _, f := range st.Fields.List {
+						for _, name := range f.Names {
+							add(ts.Name.Name+`.`+name.Name, f.Doc, f.Comment)
+						}
+					}
+				}
+			}
+		}
+	}
+	return comments, nil
+}
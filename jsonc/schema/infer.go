@@ -0,0 +1,193 @@
+package schema
+
+import (
+	"sort"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+// maxEnum is the largest number of distinct strings Infer turns into an enum.
+const maxEnum = 5
+
+// Infer returns a draft JSON Schema of the documents docs, as returned by
+// jsonc.Parse, which all of them satisfy.
+//
+// The shapes of the documents are merged: members present in every object
+// at the same location are required, the elements of all arrays at a
+// location share one items schema and values of different types give an
+// anyOf of the types. Integers seen together with other numbers are numbers.
+// Strings become an enum if at most five distinct values were seen and some
+// of them repeat. The comments of the members are carried over as
+// descriptions.
+func Infer(docs ...*jsonc.Node) *Definition {
+
+	root := &shape{}
+	for _, doc := range docs {
+
+		n := doc
+		if doc.Kind == jsonc.DocumentNode || doc.Kind == jsonc.MemberNode {
+			n = doc.Value()
+		}
+
+		if n == nil {
+			continue
+		}
+
+		comment := ``
+		if doc.Kind == jsonc.DocumentNode {
+			comment = doc.Comment(n)
+		}
+		root.add(n, comment)
+	}
+
+	d := root.definition()
+	d.Schema = Draft
+	return d
+}
+
+// shape is the merged shape of the values seen at one location.
+type shape struct {
+	count       int // values seen
+	description string
+	types       map[string]bool
+
+	strings     []string // the distinct strings up to maxEnum+1
+	stringCount int
+
+	objects int // objects seen
+	members map[string]*shape
+
+	items *shape
+}
+
+// typeOrder is the order of the types in anyOf.
+var typeOrder = []string{`object`, `array`, `string`, `integer`, `number`, `boolean`, `null`}
+
+func (s *shape) add(n *jsonc.Node, comment string) {
+
+	s.count++
+	if s.description == `` {
+		s.description = comment
+	}
+
+	if s.types == nil {
+		s.types = map[string]bool{}
+	}
+	s.types[typeName(n)] = true
+
+	switch n.Kind {
+	case jsonc.StringNode:
+		s.addString(n)
+
+	case jsonc.ObjectNode:
+		s.objects++
+		if s.members == nil {
+			s.members = map[string]*shape{}
+		}
+
+		// as when decoding the last of duplicate members counts
+		members := map[string]*jsonc.Node{}
+		for _, m := range n.Members() {
+			if m.Value() != nil {
+				members[m.Name()] = m
+			}
+		}
+
+		for _, m := range n.Members() {
+
+			name := m.Name()
+			if members[name] != m {
+				continue
+			}
+
+			ms, ok := s.members[name]
+			if !ok {
+				ms = &shape{}
+				s.members[name] = ms
+			}
+			ms.add(m.Value(), n.Comment(m))
+		}
+
+	case jsonc.ArrayNode:
+		if s.items == nil {
+			s.items = &shape{}
+		}
+
+		for _, e := range n.Elements() {
+			s.items.add(e, ``)
+		}
+	}
+}
+
+func (s *shape) addString(n *jsonc.Node) {
+
+	var str string
+	if n.Decode(&str) != nil {
+		return
+	}
+	s.stringCount++
+
+	for _, seen := range s.strings {
+		if seen == str {
+			return
+		}
+	}
+
+	if len(s.strings) <= maxEnum {
+		s.strings = append(s.strings, str)
+	}
+}
+
+// definition returns the schema of the shape, an empty schema if no value
+// was seen.
+func (s *shape) definition() *Definition {
+
+	var types []string
+	for _, t := range typeOrder {
+		if s.types[t] && !(t == `integer` && s.types[`number`]) {
+			types = append(types, t)
+		}
+	}
+
+	var defs []*Definition
+	for _, t := range types {
+		defs = append(defs, s.typeDefinition(t))
+	}
+
+	if len(defs) == 1 {
+		defs[0].Description = s.description
+		return defs[0]
+	}
+	return &Definition{Description: s.description, AnyOf: defs}
+}
+
+// typeDefinition returns the schema of the values of type t.
+func (s *shape) typeDefinition(t string) *Definition {
+
+	d := &Definition{Type: t}
+	switch t {
+	case `string`:
+		if len(s.strings) <= maxEnum && s.stringCount > len(s.strings) {
+			for _, str := range s.strings {
+				d.Enum = append(d.Enum, str)
+			}
+		}
+
+	case `object`:
+		d.Properties = map[string]*Definition{}
+		for name, ms := range s.members {
+
+			d.Properties[name] = ms.definition()
+			if ms.count == s.objects {
+				d.Required = append(d.Required, name)
+			}
+		}
+		sort.Strings(d.Required)
+
+	case `array`:
+		if s.items != nil && s.items.count > 0 {
+			d.Items = s.items.definition()
+		}
+	}
+	return d
+}
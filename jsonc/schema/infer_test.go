@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfer(t *testing.T) {
+
+	var docs []*jsonc.Node
+	for _, src := range []string{
+		`// a service
+{
+  name: alpha // the service name
+  // the listen port
+  port: 8080
+  mode: dev
+  ratio: 1
+  tags: [a, b]
+  backend: {url: "http://a"}
+}`,
+		`{
+  name: beta
+  port: 8081
+  mode: prod
+  ratio: 0.5
+  tags: []
+  backend: null
+}`,
+		`{
+  name: gamma
+  /* the listen port
+     of the server */
+  port: 8082
+  mode: dev
+  ratio: 2
+  tags: [c, 1]
+  debug: true
+}`,
+		`// empty`,
+	} {
+		doc, err := jsonc.Parse([]byte(src))
+		require.NoError(t, err)
+		docs = append(docs, doc)
+	}
+
+	out, err := jsonc.Marshal(Infer(docs...))
+	require.NoError(t, err)
+
+	assert.Equal(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema"
+  description: "a service"
+  type: object
+  properties: {
+    backend: {
+      anyOf: [
+        {
+          type: object
+          properties: {
+            url: {
+              type: string
+            }
+          }
+          required: [url]
+        }
+        {
+          type: "null"
+        }
+      ]
+    }
+    debug: {
+      type: boolean
+    }
+    mode: {
+      type: string
+      enum: [dev,prod]
+    }
+    name: {
+      description: "the service name"
+      type: string
+    }
+    port: {
+      description: "the listen port"
+      type: integer
+    }
+    ratio: {
+      type: number
+    }
+    tags: {
+      type: array
+      items: {
+        anyOf: [
+          {
+            type: string
+          }
+          {
+            type: integer
+          }
+        ]
+      }
+    }
+  }
+  required: [mode,name,port,ratio,tags]
+}`, string(out))
+
+	s, err := Compile(out)
+	require.NoError(t, err)
+
+	for _, doc := range docs {
+		assert.Nil(t, s.Validate(doc))
+	}
+}
+
+func TestInferEmpty(t *testing.T) {
+
+	d := Infer()
+	assert.Equal(t, &Definition{Schema: Draft}, d)
+
+	doc, err := jsonc.Parse([]byte(`[]`))
+	require.NoError(t, err)
+	assert.Equal(t, &Definition{Schema: Draft, Type: `array`}, Infer(doc))
+}
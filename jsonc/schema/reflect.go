@@ -0,0 +1,382 @@
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+// Draft is the JSON Schema dialect of the schemas Reflect returns.
+const Draft = `https://json-schema.org/draft/2020-12/schema`
+
+// Definition is a JSON Schema as generated by Reflect and Infer. It is written
+// as json by encoding/json and as jsonc by jsonc.Marshal, the result compiles
+// with Compile. Members are written sorted by name.
+type Definition struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"` // a type name or a list of them
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Properties           map[string]*Definition `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *Definition            `json:"additionalProperties,omitempty"`
+	Items                *Definition            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	AnyOf                []*Definition          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Definition `json:"$defs,omitempty"`
+}
+
+// ReflectOption configures Reflect.
+type ReflectOption func(*reflector)
+
+// GoComments takes the descriptions of types and fields from the doc
+// comments of the Go package importPath, read from the source files in dir.
+// Fields without a doc comment use their line comment.
+func GoComments(importPath, dir string) ReflectOption {
+	return func(r *reflector) {
+
+		comments, err := readComments(dir)
+		if err != nil {
+			r.err = err
+			return
+		}
+
+		for name, text := range comments {
+			r.comments[importPath+`.`+name] = text
+		}
+	}
+}
+
+// Reflect returns the JSON Schema of the Go type of v, the documents valid
+// against it decode into the type with encoding/json. Pointers, maps and
+// slices below the root accept null, which encoding/json writes for their
+// nil values, as do interfaces and json.Marshaler types which allow any
+// value.
+//
+// Struct fields are named and skipped by their json tag as encoding/json
+// does, the fields of embedded structs are promoted. Fields are required
+// unless they are pointers, tagged omitempty or promoted from an embedded
+// pointer. Named struct types are defined once in $defs and referenced. Maps
+// are objects of their element schema, slices and arrays are arrays, byte
+// slices are base64 strings. A time.Duration is an integer of nanoseconds,
+// time.Time a date-time string, types implementing encoding.TextMarshaler
+// are strings and other json.Marshaler types allow any value.
+//
+// Descriptions are taken from the comment of the jsonc struct tag, see
+// jsonc.Marshal, or from the Go doc comments read with GoComments.
+func Reflect(v interface{}, opts ...ReflectOption) (*Definition, error) {
+
+	r := &reflector{
+		comments: map[string]string{},
+		names:    map[reflect.Type]string{},
+		types:    map[string]reflect.Type{},
+		defs:     map[string]*Definition{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("cannot reflect the type of nil")
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// references to the root type point to the root
+	if t.Kind() == reflect.Struct && t.Name() != `` && !isSpecial(t) {
+		r.names[t] = ``
+	}
+
+	d, err := r.define(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Description == `` {
+		d.Description = r.comments[typeKey(t)]
+	}
+
+	if len(r.defs) > 0 {
+		d.Defs = r.defs
+	}
+	d.Schema = Draft
+	return d, nil
+}
+
+type reflector struct {
+	comments map[string]string
+
+	names map[reflect.Type]string // the $defs name of the named struct types
+	types map[string]reflect.Type
+	defs  map[string]*Definition
+
+	err error
+}
+
+var (
+	durationType      = reflect.TypeOf(time.Duration(0))
+	timeType          = reflect.TypeOf(time.Time{})
+	numberType        = reflect.TypeOf(json.Number(``))
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schema returns the schema of a value of the type t, named struct types are
+// referenced. Nil pointers, maps and slices are null.
+func (r *reflector) schema(t reflect.Type) (*Definition, error) {
+
+	d, err := r.reference(t)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case t.Kind() == reflect.Ptr:
+		return nullable(d), nil
+
+	case (t.Kind() == reflect.Map || t.Kind() == reflect.Slice) && !isSpecial(t):
+		return nullable(d), nil
+	}
+	return d, nil
+}
+
+// nullable returns d changed to accept null as well.
+func nullable(d *Definition) *Definition {
+
+	switch t := d.Type.(type) {
+	case string:
+		d.Type = []string{t, `null`}
+		return d
+
+	case nil:
+		if d.Ref != `` {
+			return &Definition{AnyOf: []*Definition{d, {Type: `null`}}}
+		}
+	}
+
+	// any value
+	return d
+}
+
+// reference returns the schema of a value of the type t without null, named
+// struct types are referenced.
+func (r *reflector) reference(t reflect.Type) (*Definition, error) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t.Name() == `` || isSpecial(t) {
+		return r.define(t)
+	}
+
+	name, ok := r.names[t]
+	if !ok {
+
+		name = r.name(t)
+		r.names[t] = name
+		r.types[name] = t
+
+		d, err := r.define(t)
+		if err != nil {
+			return nil, err
+		}
+		d.Description = r.comments[typeKey(t)]
+		r.defs[name] = d
+	}
+
+	if name == `` {
+		return &Definition{Ref: `#`}, nil
+	}
+	return &Definition{Ref: `#/$defs/` + name}, nil
+}
+
+// name returns an unused $defs name of the struct type t.
+func (r *reflector) name(t reflect.Type) string {
+
+	name := t.Name()
+	if _, ok := r.types[name]; !ok {
+		return name
+	}
+
+	name = path.Base(t.PkgPath()) + `.` + t.Name()
+	for i := 2; ; i++ {
+
+		if _, ok := r.types[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("%v.%v%v", path.Base(t.PkgPath()), t.Name(), i)
+	}
+}
+
+// define returns the schema of the type t itself.
+func (r *reflector) define(t reflect.Type) (*Definition, error) {
+
+	switch t {
+	case durationType:
+		return &Definition{Type: `integer`, Description: `a duration in nanoseconds`}, nil
+	case timeType:
+		return &Definition{Type: `string`, Format: `date-time`}, nil
+	case numberType:
+		return &Definition{Type: `number`}, nil
+	}
+
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return &Definition{}, nil
+	}
+
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return &Definition{Type: `string`}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Definition{Type: `boolean`}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Definition{Type: `integer`}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &Definition{Type: `number`}, nil
+
+	case reflect.String:
+		return &Definition{Type: `string`}, nil
+
+	case reflect.Interface:
+		return &Definition{}, nil
+
+	case reflect.Ptr:
+		return r.schema(t)
+
+	case reflect.Slice, reflect.Array:
+
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 &&
+			!reflect.PtrTo(t.Elem()).Implements(marshalerType) &&
+			!reflect.PtrTo(t.Elem()).Implements(textMarshalerType) {
+			return &Definition{Type: `string`, ContentEncoding: `base64`}, nil
+		}
+
+		items, err := r.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		d := &Definition{Type: `array`, Items: items}
+		if t.Kind() == reflect.Array {
+			n := t.Len()
+			d.MinItems, d.MaxItems = &n, &n
+		}
+		return d, nil
+
+	case reflect.Map:
+
+		switch t.Key().Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				return nil, fmt.Errorf("unsupported map key type %v", t.Key())
+			}
+		}
+
+		values, err := r.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Definition{Type: `object`, AdditionalProperties: values}, nil
+
+	case reflect.Struct:
+		return r.object(t)
+	}
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
+
+// object returns the schema of the struct type t.
+func (r *reflector) object(t reflect.Type) (*Definition, error) {
+
+	d := &Definition{Type: `object`, Properties: map[string]*Definition{}}
+	for _, f := range jsonc.Fields(t) {
+
+		p, err := r.schema(f.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.Quoted {
+			p = &Definition{Type: `string`}
+			if f.Type.Kind() == reflect.Ptr {
+				p = nullable(p)
+			}
+		}
+
+		owner, pointer := declaring(t, f.Index)
+		key := ``
+		if owner.Name() != `` {
+			key = typeKey(owner) + `.` + owner.Field(f.Index[len(f.Index)-1]).Name
+		}
+
+		switch {
+		case f.Comment != ``:
+			p.Description = f.Comment
+		case r.comments[key] != ``:
+			p.Description = r.comments[key]
+		}
+
+		d.Properties[f.Name] = p
+		if !(f.OmitEmpty || pointer || f.Type.Kind() == reflect.Ptr) {
+			d.Required = append(d.Required, f.Name)
+		}
+	}
+	return d, nil
+}
+
+// isSpecial reports whether the schema of t does not follow from its kind.
+func isSpecial(t reflect.Type) bool {
+
+	switch t {
+	case durationType, timeType, numberType:
+		return true
+	}
+
+	return t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+// typeKey returns the key of the comments of the named type t.
+func typeKey(t reflect.Type) string {
+	return t.PkgPath() + `.` + t.Name()
+}
+
+// declaring returns the struct type declaring the field at index of the
+// struct type t and whether embedded pointers lead to it.
+func declaring(t reflect.Type, index []int) (reflect.Type, bool) {
+
+	var pointer bool
+	for _, i := range index[:len(index)-1] {
+		t = t.Field(i).Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+			pointer = true
+		}
+	}
+	return t, pointer
+}
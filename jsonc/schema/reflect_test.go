@@ -0,0 +1,233 @@
+package schema
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig is the config of a service.
+type testConfig struct {
+	testBase
+
+	// Port is the listen port.
+	Port    int           `json:"port"`
+	Name    string        `json:"name,omitempty" jsonc:",comment=the service name"`
+	Timeout time.Duration `json:"timeout"`
+	Started time.Time     `json:"started"`
+	Addr    net.IP        `json:"addr"`
+	Tags    []string      `json:"tags"`
+	Limits  [2]float64    `json:"limits"`
+	Key     []byte        `json:"key"`
+	Count   int64         `json:"count,string"`
+	Extra   interface{}   `json:"extra"`
+	Raw     json.RawMessage
+
+	Primary *testServer           `json:"primary"` // the main server
+	Servers map[string]testServer `json:"servers"`
+	Parent  *testConfig           `json:"parent,omitempty"`
+
+	Skipped string `json:"-"`
+	hidden  string
+}
+
+type testBase struct {
+	Version int `json:"version"`
+}
+
+// testServer is a server.
+type testServer struct {
+	*testAddr
+
+	// IP is the address.
+	IP string `json:"ip"`
+}
+
+type testAddr struct {
+	Host string `json:"host"`
+}
+
+func TestReflect(t *testing.T) {
+
+	d, err := Reflect(&testConfig{}, GoComments(`github.com/komkom/jsonc/jsonc/schema`, `.`))
+	require.NoError(t, err)
+
+	out, err := jsonc.Marshal(d)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema"
+  description: "testConfig is the config of a service."
+  type: object
+  properties: {
+    Raw: {}
+    addr: {
+      type: string
+    }
+    count: {
+      type: string
+    }
+    extra: {}
+    key: {
+      type: [string,"null"]
+      contentEncoding: base64
+    }
+    limits: {
+      type: array
+      items: {
+        type: number
+      }
+      minItems: 2
+      maxItems: 2
+    }
+    name: {
+      description: "the service name"
+      type: string
+    }
+    parent: {
+      anyOf: [
+        {
+          "$ref": "#"
+        }
+        {
+          type: "null"
+        }
+      ]
+    }
+    port: {
+      description: "Port is the listen port."
+      type: integer
+    }
+    primary: {
+      description: "the main server"
+      anyOf: [
+        {
+          "$ref": "#/$defs/testServer"
+        }
+        {
+          type: "null"
+        }
+      ]
+    }
+    servers: {
+      type: [object,"null"]
+      additionalProperties: {
+        "$ref": "#/$defs/testServer"
+      }
+    }
+    started: {
+      type: string
+      format: "date-time"
+    }
+    tags: {
+      type: [array,"null"]
+      items: {
+        type: string
+      }
+    }
+    timeout: {
+      description: "a duration in nanoseconds"
+      type: integer
+    }
+    version: {
+      type: integer
+    }
+  }
+  required: [version,port,timeout,started,addr,tags,limits,key,count,extra,Raw,servers]
+  "$defs": {
+    testServer: {
+      description: "testServer is a server."
+      type: object
+      properties: {
+        host: {
+          type: string
+        }
+        ip: {
+          description: "IP is the address."
+          type: string
+        }
+      }
+      required: [ip]
+    }
+  }
+}`, string(out))
+
+	s, err := Compile(out)
+	require.NoError(t, err)
+
+	doc, err := json.Marshal(testConfig{
+		Port:    8080,
+		Tags:    []string{`a`},
+		Key:     []byte(`key`),
+		Raw:     json.RawMessage(`[1]`),
+		Primary: &testServer{IP: `10.0.0.1`},
+		Servers: map[string]testServer{`alpha`: {testAddr: &testAddr{Host: `a`}}},
+	})
+	require.NoError(t, err)
+
+	violations, err := s.ValidateBytes(doc)
+	require.NoError(t, err)
+	assert.Nil(t, violations)
+
+	// nil pointers, maps and slices are null
+	doc, err = json.Marshal(testConfig{})
+	require.NoError(t, err)
+
+	violations, err = s.ValidateBytes(doc)
+	require.NoError(t, err)
+	assert.Nil(t, violations)
+
+	violations, err = s.ValidateBytes([]byte(`{port: x, servers: {a: {host: 1}}}`))
+	require.NoError(t, err)
+	assert.Len(t, violations, 13)
+}
+
+func TestReflectTypes(t *testing.T) {
+
+	type named struct {
+		A int
+	}
+
+	tests := []struct {
+		value  interface{}
+		schema string
+	}{
+		{value: true, schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"boolean"}`},
+		{value: uint8(1), schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"integer"}`},
+		{value: map[int]bool{}, schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","additionalProperties":{"type":"boolean"}}`},
+		{value: []named{}, schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"array","items":{"$ref":"#/$defs/named"},"$defs":{"named":{"type":"object","properties":{"A":{"type":"integer"}},"required":["A"]}}}`},
+		{value: struct{ B *int }{}, schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{"B":{"type":["integer","null"]}}}`},
+		{value: struct{ M map[string]int }{}, schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{"M":{"type":["object","null"],"additionalProperties":{"type":"integer"}}},"required":["M"]}`},
+		{value: []*int{}, schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"array","items":{"type":["integer","null"]}}`},
+	}
+
+	for _, ts := range tests {
+
+		d, err := Reflect(ts.value)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(d)
+		require.NoError(t, err)
+		assert.Equal(t, ts.schema, string(data))
+	}
+}
+
+func TestReflectErrors(t *testing.T) {
+
+	_, err := Reflect(nil)
+	assert.EqualError(t, err, `cannot reflect the type of nil`)
+
+	_, err = Reflect(struct{ C chan int }{})
+	assert.EqualError(t, err, `unsupported type chan int`)
+
+	_, err = Reflect(map[[2]int]string{})
+	assert.EqualError(t, err, `unsupported map key type [2]int`)
+
+	_, err = Reflect(testConfig{}, GoComments(`x`, `missing`))
+	assert.Error(t, err)
+}
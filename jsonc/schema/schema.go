@@ -0,0 +1,490 @@
+// Package schema validates jsonc documents against JSON Schema.
+//
+// It implements a practical subset of JSON Schema Draft 2020-12: type, enum,
+// const, the numeric limits, multipleOf, minLength, maxLength, pattern,
+// items, prefixItems, minItems, maxItems, uniqueItems, properties,
+// patternProperties, additionalProperties, required, minProperties,
+// maxProperties, allOf, anyOf, oneOf, not and $ref to locations inside the
+// schema. Other keywords are ignored. Patterns use the syntax of the regexp
+// package.
+//
+// Schemas are jsonc documents themselves, violations are reported with the
+// line and column of the offending value.
+package schema
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	root *schema
+}
+
+// schema is a compiled schema object or boolean schema.
+type schema struct {
+	never bool // the false schema
+
+	types       []string
+	enum        []interface{}
+	constant    interface{}
+	hasConstant bool
+
+	minimum, maximum                   *limit
+	exclusiveMinimum, exclusiveMaximum *limit
+	multipleOf                         *limit
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	items              *schema
+	prefixItems        []*schema
+	minItems, maxItems *int
+	uniqueItems        bool
+
+	properties           map[string]*schema
+	patternProperties    []patternSchema
+	additionalProperties *schema
+	required             []string
+	minProps, maxProps   *int
+
+	allOf, anyOf, oneOf []*schema
+	not                 *schema
+	ref                 *schema
+}
+
+// limit is a number of a schema with its text.
+type limit struct {
+	value *big.Rat
+	text  string
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *schema
+}
+
+// Compile compiles the JSON Schema given as jsonc document data. References
+// must point into the same schema, as "#/$defs/port". Schemas which apply
+// themselves to the same value, as {"$ref": "#"}, are an error.
+func Compile(data []byte, opts ...jsonc.Option) (*Schema, error) {
+
+	doc, err := jsonc.Parse(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	root := doc.Value()
+	if root == nil {
+		return nil, fmt.Errorf("the schema is empty")
+	}
+
+	c := &compiler{root: root, compiled: map[*jsonc.Node]*schema{}, source: map[*schema]*jsonc.Node{}}
+	s, err := c.compile(root)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.cycles()
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: s}, nil
+}
+
+// MustCompile is like Compile but panics if the schema is invalid.
+func MustCompile(data []byte, opts ...jsonc.Option) *Schema {
+
+	s, err := Compile(data, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+type compiler struct {
+	root     *jsonc.Node
+	compiled map[*jsonc.Node]*schema
+	source   map[*schema]*jsonc.Node
+	order    []*schema // the compiled schemas in document order
+}
+
+// errorf returns an error located at the schema node n.
+func errorf(n *jsonc.Node, format string, args ...interface{}) error {
+	return fmt.Errorf("line: %v col: %v %v", n.Start.Line, n.Start.Column, fmt.Sprintf(format, args...))
+}
+
+func (c *compiler) compile(n *jsonc.Node) (*schema, error) {
+
+	if s, ok := c.compiled[n]; ok {
+		return s, nil
+	}
+
+	s := &schema{}
+	c.compiled[n] = s
+	c.source[s] = n
+	c.order = append(c.order, s)
+
+	switch n.Kind {
+	case jsonc.BoolNode:
+		s.never = n.Text == `false`
+		return s, nil
+
+	case jsonc.ObjectNode:
+
+	default:
+		return nil, errorf(n, "a schema must be an object or a bool, got %v", n.Kind)
+	}
+
+	var err error
+	for _, m := range n.Members() {
+
+		v := m.Value()
+		switch m.Name() {
+		case `type`:
+			s.types, err = typeNames(v)
+
+		case `enum`:
+			if v.Kind != jsonc.ArrayNode {
+				return nil, errorf(v, "enum must be an array")
+			}
+			for _, e := range v.Elements() {
+				value, verr := e.Interface()
+				if verr != nil {
+					return nil, verr
+				}
+				s.enum = append(s.enum, value)
+			}
+
+		case `const`:
+			s.hasConstant = true
+			s.constant, err = v.Interface()
+
+		case `minimum`:
+			s.minimum, err = number(v)
+		case `maximum`:
+			s.maximum, err = number(v)
+		case `exclusiveMinimum`:
+			s.exclusiveMinimum, err = number(v)
+		case `exclusiveMaximum`:
+			s.exclusiveMaximum, err = number(v)
+
+		case `multipleOf`:
+			s.multipleOf, err = number(v)
+			if err == nil && s.multipleOf.value.Sign() <= 0 {
+				return nil, errorf(v, "multipleOf must be greater than 0")
+			}
+
+		case `minLength`:
+			s.minLength, err = count(v)
+		case `maxLength`:
+			s.maxLength, err = count(v)
+		case `minItems`:
+			s.minItems, err = count(v)
+		case `maxItems`:
+			s.maxItems, err = count(v)
+		case `minProperties`:
+			s.minProps, err = count(v)
+		case `maxProperties`:
+			s.maxProps, err = count(v)
+
+		case `pattern`:
+			s.pattern, err = pattern(v)
+
+		case `items`:
+			// the array form of earlier drafts is prefixItems
+			if v.Kind == jsonc.ArrayNode {
+				s.prefixItems, err = c.list(v, false)
+				break
+			}
+			s.items, err = c.compile(v)
+
+		case `prefixItems`:
+			s.prefixItems, err = c.list(v, true)
+
+		case `uniqueItems`:
+			s.uniqueItems = v.Kind == jsonc.BoolNode && v.Text == `true`
+
+		case `properties`:
+			if v.Kind != jsonc.ObjectNode {
+				return nil, errorf(v, "properties must be an object")
+			}
+			s.properties = map[string]*schema{}
+			for _, p := range v.Members() {
+				s.properties[p.Name()], err = c.compile(p.Value())
+				if err != nil {
+					return nil, err
+				}
+			}
+
+		case `patternProperties`:
+			if v.Kind != jsonc.ObjectNode {
+				return nil, errorf(v, "patternProperties must be an object")
+			}
+			for _, p := range v.Members() {
+				re, perr := regexp.Compile(p.Name())
+				if perr != nil {
+					return nil, errorf(p, "invalid pattern: %v", perr)
+				}
+
+				ps, perr := c.compile(p.Value())
+				if perr != nil {
+					return nil, perr
+				}
+				s.patternProperties = append(s.patternProperties, patternSchema{pattern: re, schema: ps})
+			}
+
+		case `additionalProperties`:
+			s.additionalProperties, err = c.compile(v)
+
+		case `required`:
+			s.required, err = stringList(v)
+
+		case `allOf`:
+			s.allOf, err = c.list(v, true)
+		case `anyOf`:
+			s.anyOf, err = c.list(v, true)
+		case `oneOf`:
+			s.oneOf, err = c.list(v, true)
+		case `not`:
+			s.not, err = c.compile(v)
+
+		case `$ref`:
+			s.ref, err = c.reference(v)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// cycles returns an error if a schema applies itself to the same value, by
+// $ref, allOf, anyOf, oneOf or not without a member or element in between.
+func (c *compiler) cycles() error {
+
+	const visiting, visited = 1, 2
+	state := map[*schema]int{}
+
+	var walk func(s *schema) error
+	walk = func(s *schema) error {
+
+		switch state[s] {
+		case visiting:
+			return errorf(c.source[s], "schema reference cycle")
+		case visited:
+			return nil
+		}
+		state[s] = visiting
+
+		for _, sub := range s.inPlace() {
+			err := walk(sub)
+			if err != nil {
+				return err
+			}
+		}
+		state[s] = visited
+		return nil
+	}
+
+	for _, s := range c.order {
+		err := walk(s)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inPlace returns the schemas s applies to the value it validates itself.
+func (s *schema) inPlace() []*schema {
+
+	var list []*schema
+	if s.ref != nil {
+		list = append(list, s.ref)
+	}
+	if s.not != nil {
+		list = append(list, s.not)
+	}
+	list = append(list, s.allOf...)
+	list = append(list, s.anyOf...)
+	return append(list, s.oneOf...)
+}
+
+// list compiles an array of schemas.
+func (c *compiler) list(n *jsonc.Node, nonEmpty bool) ([]*schema, error) {
+
+	if n.Kind != jsonc.ArrayNode || (nonEmpty && len(n.Elements()) == 0) {
+		return nil, errorf(n, "expected a non-empty array of schemas")
+	}
+
+	var list []*schema
+	for _, e := range n.Elements() {
+		s, err := c.compile(e)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// reference compiles the schema $ref points to.
+func (c *compiler) reference(n *jsonc.Node) (*schema, error) {
+
+	var ref string
+	if n.Kind != jsonc.StringNode || n.Decode(&ref) != nil {
+		return nil, errorf(n, "$ref must be a string")
+	}
+
+	if !strings.HasPrefix(ref, `#`) {
+		return nil, errorf(n, "reference %q does not point into the schema", ref)
+	}
+
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, errorf(n, "invalid reference %q", ref)
+	}
+
+	target := c.root
+	if pointer != `` {
+
+		if pointer[0] != '/' {
+			return nil, errorf(n, "invalid reference %q", ref)
+		}
+
+		for _, token := range strings.Split(pointer[1:], `/`) {
+
+			token = strings.NewReplacer(`~1`, `/`, `~0`, `~`).Replace(token)
+			target = child(target, token)
+			if target == nil {
+				return nil, errorf(n, "reference %q not found", ref)
+			}
+		}
+	}
+	return c.compile(target)
+}
+
+// child returns the member or element token of the value n.
+func child(n *jsonc.Node, token string) *jsonc.Node {
+
+	switch n.Kind {
+	case jsonc.ObjectNode:
+		if m := n.Lookup(token); m != nil {
+			return m.Value()
+		}
+
+	case jsonc.ArrayNode:
+		idx, err := strconv.Atoi(token)
+		elements := n.Elements()
+		if err == nil && idx >= 0 && idx < len(elements) {
+			return elements[idx]
+		}
+	}
+	return nil
+}
+
+var typeNameSet = map[string]bool{
+	`object`:  true,
+	`array`:   true,
+	`string`:  true,
+	`number`:  true,
+	`integer`: true,
+	`boolean`: true,
+	`null`:    true,
+}
+
+// typeNames reads the value of the type keyword.
+func typeNames(n *jsonc.Node) ([]string, error) {
+
+	var names []string
+	if n.Kind == jsonc.StringNode {
+		var name string
+		if err := n.Decode(&name); err != nil {
+			return nil, err
+		}
+		names = []string{name}
+	} else {
+		var err error
+		names, err = stringList(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range names {
+		if !typeNameSet[name] {
+			return nil, errorf(n, "unknown type %q", name)
+		}
+	}
+	return names, nil
+}
+
+// stringList reads an array of strings.
+func stringList(n *jsonc.Node) ([]string, error) {
+
+	if n.Kind != jsonc.ArrayNode {
+		return nil, errorf(n, "expected an array of strings")
+	}
+
+	var list []string
+	for _, e := range n.Elements() {
+
+		var s string
+		if e.Kind != jsonc.StringNode || e.Decode(&s) != nil {
+			return nil, errorf(e, "expected a string")
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+func number(n *jsonc.Node) (*limit, error) {
+
+	if n.Kind != jsonc.NumberNode {
+		return nil, errorf(n, "expected a number")
+	}
+
+	r, ok := new(big.Rat).SetString(n.Text)
+	if !ok {
+		return nil, errorf(n, "invalid number %v", n.Text)
+	}
+	return &limit{value: r, text: n.Text}, nil
+}
+
+func count(n *jsonc.Node) (*int, error) {
+
+	l, err := number(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.value.IsInt() || l.value.Sign() < 0 || !l.value.Num().IsInt64() {
+		return nil, errorf(n, "expected a non-negative integer")
+	}
+
+	c := int(l.value.Num().Int64())
+	return &c, nil
+}
+
+func pattern(n *jsonc.Node) (*regexp.Regexp, error) {
+
+	var p string
+	if n.Kind != jsonc.StringNode || n.Decode(&p) != nil {
+		return nil, errorf(n, "pattern must be a string")
+	}
+
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, errorf(n, "invalid pattern: %v", err)
+	}
+	return re, nil
+}
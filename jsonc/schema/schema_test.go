@@ -0,0 +1,236 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/komkom/jsonc/jsonc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const serviceSchema = `{
+  // a service config
+  type: object
+  required: [name, port]
+  additionalProperties: false
+  properties: {
+    name: {type: string, pattern: "^[a-z]+$", maxLength: 8}
+    port: {"$ref": "#/$defs/port"}
+    mode: {enum: [dev, prod]}
+    tags: {type: array, items: {type: string}, uniqueItems: true, maxItems: 3}
+    ratio: {type: number, exclusiveMinimum: 0, maximum: 1, multipleOf: 0.25}
+    replicas: {type: integer}
+    backend: {
+      oneOf: [
+        {type: object, required: [url]}
+        {type: object, required: [socket]}
+      ]
+    }
+    version: {const: 2}
+    labels: {
+      type: object
+      patternProperties: {"^x-": {type: string}}
+      additionalProperties: {type: integer}
+    }
+    owner: {anyOf: [{type: string}, {type: "null"}]}
+    mirror: {not: {type: "null"}}
+  }
+  "$defs": {
+    port: {type: integer, minimum: 1, maximum: 65535}
+  }
+}`
+
+func TestValidate(t *testing.T) {
+
+	s, err := Compile([]byte(serviceSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		doc        string
+		violations []string
+	}{
+		{
+			doc: `{
+  name: alpha // the name
+  port: 8080
+  mode: dev
+  tags: [a, b]
+  ratio: 0.5
+  replicas: 2.0
+  backend: {url: "http://a"}
+  version: 2.0
+  labels: {"x-team": core, count: 3}
+  owner: null
+  mirror: false
+}`,
+		},
+		{
+			doc: `{
+  name: Alpha
+  port: 70000
+  mode: test
+  tags: [a, a, 1, c]
+  ratio: 0.3
+  replicas: 1.5
+  backend: {url: a, socket: b}
+  version: 3
+  labels: {"x-team": 1, count: many}
+  owner: 1
+  mirror: null
+  extra: 1
+}`,
+			violations: []string{
+				`line: 2 col: 9 /name: "Alpha" does not match the pattern "^[a-z]+$"`,
+				`line: 3 col: 9 /port: 70000 is greater than the maximum 65535`,
+				`line: 4 col: 9 /mode: value must be one of "dev", "prod"`,
+				`line: 5 col: 9 /tags: array has more than 3 items`,
+				`line: 5 col: 16 /tags/2: expected string, got integer`,
+				`line: 5 col: 13 /tags/1: array items must be unique`,
+				`line: 6 col: 10 /ratio: 0.3 is not a multiple of 0.25`,
+				`line: 7 col: 13 /replicas: expected integer, got number`,
+				`line: 8 col: 12 /backend: value matches 2 schemas of oneOf, expected exactly one`,
+				`line: 9 col: 12 /version: value must be 2`,
+				`line: 10 col: 22 /labels/x-team: expected string, got integer`,
+				`line: 10 col: 32 /labels/count: expected integer, got string`,
+				`line: 11 col: 10 /owner: value does not match any schema of anyOf`,
+				`line: 12 col: 11 /mirror: value must not match the schema of not`,
+				`line: 13 col: 3 /extra: member "extra" is not allowed`,
+			},
+		},
+		{
+			doc: `{port: 0, backend: {}}`,
+			violations: []string{
+				`line: 1 col: 1 missing required member "name"`,
+				`line: 1 col: 8 /port: 0 is less than the minimum 1`,
+				`line: 1 col: 20 /backend: value does not match any schema of oneOf`,
+			},
+		},
+		{
+			doc:        `[1]`,
+			violations: []string{`line: 1 col: 1 expected object, got array`},
+		},
+		{
+			doc: `// nothing`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		violations, err := s.ValidateBytes([]byte(ts.doc))
+		require.NoError(t, err, ts.doc)
+
+		var msgs []string
+		for _, v := range violations {
+			msgs = append(msgs, v.Error())
+		}
+		assert.Equal(t, ts.violations, msgs, ts.doc)
+	}
+
+	_, err = s.ValidateBytes([]byte(`{a: }`))
+	assert.Error(t, err)
+}
+
+func TestViolation(t *testing.T) {
+
+	s := MustCompile([]byte(`{properties: {"a/b": {type: string, minLength: 2}}}`))
+	violations, err := s.ValidateBytes([]byte(`{"a/b": "é"}`))
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+
+	v := violations[0]
+	assert.Equal(t, `/a~1b`, v.Path)
+	assert.Equal(t, `minLength`, v.Keyword)
+	assert.Equal(t, 1, v.Pos.Line)
+	assert.Equal(t, 9, v.Pos.Column)
+	assert.Equal(t, `string is shorter than 2 characters`, v.Message)
+}
+
+func TestRecursiveSchema(t *testing.T) {
+
+	s := MustCompile([]byte(`{
+  type: object
+  properties: {
+    value: {type: integer}
+    children: {type: array, items: {"$ref": "#"}}
+  }
+}`))
+
+	violations, err := s.ValidateBytes([]byte(`{value: 1, children: [{value: 2}, {children: [{value: x}]}]}`))
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, `/children/1/children/0/value`, violations[0].Path)
+}
+
+func TestBooleanSchemas(t *testing.T) {
+
+	violations, err := MustCompile([]byte(`{items: true}`)).ValidateBytes([]byte(`[1, {}]`))
+	require.NoError(t, err)
+	assert.Nil(t, violations)
+
+	s := MustCompile([]byte(`{prefixItems: [true, false], items: false}`))
+	violations, err = s.ValidateBytes([]byte(`[1, 2, 3]`))
+	require.NoError(t, err)
+	require.Len(t, violations, 2)
+	assert.Equal(t, `line: 1 col: 5 /1: no value is allowed`, violations[0].Error())
+	assert.Equal(t, `line: 1 col: 8 /2: no value is allowed`, violations[1].Error())
+}
+
+func TestDuplicateMembers(t *testing.T) {
+
+	s := MustCompile([]byte(`{minProperties: 2, maxProperties: 2, properties: {a: {type: integer}}}`))
+
+	violations, err := s.ValidateBytes([]byte(`{a: x, a: 1, b: 2}`))
+	require.NoError(t, err)
+	assert.Nil(t, violations)
+
+	violations, err = s.ValidateBytes([]byte(`{a: 1, a: 2}`))
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, `minProperties`, violations[0].Keyword)
+
+	violations, err = s.ValidateBytes([]byte(`{a: 1, b: 2, c: 3, c: 4}`))
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, `maxProperties`, violations[0].Keyword)
+}
+
+func TestCompileErrors(t *testing.T) {
+
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{schema: `{type: text}`, err: `line: 1 col: 8 unknown type "text"`},
+		{schema: `{properties: {a: 1}}`, err: `line: 1 col: 18 a schema must be an object or a bool, got number`},
+		{schema: `{pattern: "("}`, err: "line: 1 col: 11 invalid pattern: error parsing regexp: missing closing ): `(`"},
+		{schema: `{minLength: 1.5}`, err: `line: 1 col: 13 expected a non-negative integer`},
+		{schema: `{"$ref": "#/$defs/missing"}`, err: `line: 1 col: 10 reference "#/$defs/missing" not found`},
+		{schema: `{"$ref": "other.json"}`, err: `line: 1 col: 10 reference "other.json" does not point into the schema`},
+		{schema: `{anyOf: []}`, err: `line: 1 col: 9 expected a non-empty array of schemas`},
+		{schema: `{multipleOf: 0}`, err: `line: 1 col: 14 multipleOf must be greater than 0`},
+		{schema: ``, err: `the schema is empty`},
+		{schema: `{"$ref": "#"}`, err: `line: 1 col: 1 schema reference cycle`},
+		{schema: `{"$defs": {a: {"$ref": "#/$defs/b"}, b: {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`, err: `line: 1 col: 15 schema reference cycle`},
+		{schema: `{properties: {a: {allOf: [{type: object}, {"$ref": "#/properties/a"}]}}}`, err: `line: 1 col: 18 schema reference cycle`},
+	}
+
+	for _, ts := range tests {
+		_, err := Compile([]byte(ts.schema))
+		require.Error(t, err, ts.schema)
+		assert.Equal(t, ts.err, err.Error(), ts.schema)
+	}
+}
+
+func TestReferenceCycleGuard(t *testing.T) {
+
+	// a cycle built past Compile ends at the value it started on
+	s := &schema{types: []string{`string`}}
+	s.allOf = []*schema{{ref: s}}
+
+	doc, err := jsonc.Parse([]byte(`[1]`))
+	require.NoError(t, err)
+
+	violations := (&Schema{root: s}).Validate(doc)
+	require.Len(t, violations, 1)
+	assert.Equal(t, `type`, violations[0].Keyword)
+}
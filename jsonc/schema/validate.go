@@ -0,0 +1,428 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+// Violation is a value of a document which does not satisfy the schema.
+type Violation struct {
+	Pos     jsonc.Pos // location of the value or, for members which are not allowed, their key
+	Path    string    // JSON Pointer of the value in the document
+	Keyword string    // the schema keyword which failed
+	Message string
+}
+
+func (v Violation) Error() string {
+
+	if v.Path == `` {
+		return fmt.Sprintf("line: %v col: %v %v", v.Pos.Line, v.Pos.Column, v.Message)
+	}
+	return fmt.Sprintf("line: %v col: %v %v: %v", v.Pos.Line, v.Pos.Column, v.Path, v.Message)
+}
+
+// Validate validates the syntax tree of a jsonc document, as returned by
+// jsonc.Parse, or one of its values. It returns the violations in document
+// order, an empty document violates nothing.
+func (s *Schema) Validate(doc *jsonc.Node) []Violation {
+
+	n := doc
+	if doc.Kind == jsonc.DocumentNode || doc.Kind == jsonc.MemberNode {
+		n = doc.Value()
+	}
+
+	if n == nil {
+		return nil
+	}
+
+	var out []Violation
+	s.root.validate(n, ``, &out, visits{})
+	return out
+}
+
+// ValidateBytes parses the jsonc document data and validates it. Syntax errors
+// are returned as error.
+func (s *Schema) ValidateBytes(data []byte, opts ...jsonc.Option) ([]Violation, error) {
+
+	doc, err := jsonc.Parse(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return s.Validate(doc), nil
+}
+
+func (s *schema) validate(n *jsonc.Node, path string, out *[]Violation, active visits) {
+
+	// Compile rejects cycles without a value in between, this keeps them from
+	// recursing in any case
+	key := visit{s: s, n: n}
+	if active[key] {
+		return
+	}
+	active[key] = true
+	defer delete(active, key)
+
+	report := func(pos jsonc.Pos, keyword, format string, args ...interface{}) {
+		*out = append(*out, Violation{Pos: pos, Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.never {
+		report(n.Start, `false`, "no value is allowed")
+		return
+	}
+
+	if s.ref != nil {
+		s.ref.validate(n, path, out, active)
+	}
+
+	if len(s.types) > 0 && !hasType(s.types, n) {
+		report(n.Start, `type`, "expected %v, got %v", strings.Join(s.types, ` or `), typeName(n))
+		return
+	}
+
+	if s.enum != nil || s.hasConstant {
+
+		value, err := n.Interface()
+		if err != nil {
+			report(n.Start, `enum`, "%v", err)
+			return
+		}
+
+		if s.hasConstant && !equal(value, s.constant) {
+			report(n.Start, `const`, "value must be %v", text(s.constant))
+		}
+
+		if s.enum != nil && !oneOfValues(value, s.enum) {
+			var texts []string
+			for _, e := range s.enum {
+				texts = append(texts, text(e))
+			}
+			report(n.Start, `enum`, "value must be one of %v", strings.Join(texts, `, `))
+		}
+	}
+
+	switch n.Kind {
+	case jsonc.NumberNode:
+		s.validateNumber(n, report)
+	case jsonc.StringNode:
+		s.validateString(n, report)
+	case jsonc.ArrayNode:
+		s.validateArray(n, path, out, active, report)
+	case jsonc.ObjectNode:
+		s.validateObject(n, path, out, active, report)
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(n, path, out, active)
+	}
+
+	if len(s.anyOf) > 0 && matches(s.anyOf, n, path, active) == 0 {
+		report(n.Start, `anyOf`, "value does not match any schema of anyOf")
+	}
+
+	if len(s.oneOf) > 0 {
+		switch c := matches(s.oneOf, n, path, active); {
+		case c == 0:
+			report(n.Start, `oneOf`, "value does not match any schema of oneOf")
+		case c > 1:
+			report(n.Start, `oneOf`, "value matches %v schemas of oneOf, expected exactly one", c)
+		}
+	}
+
+	if s.not != nil && matches([]*schema{s.not}, n, path, active) == 1 {
+		report(n.Start, `not`, "value must not match the schema of not")
+	}
+}
+
+// visit is a schema applied to a value.
+type visit struct {
+	s *schema
+	n *jsonc.Node
+}
+
+// visits are the schemas being applied to values.
+type visits map[visit]bool
+
+type reporter func(pos jsonc.Pos, keyword, format string, args ...interface{})
+
+func (s *schema) validateNumber(n *jsonc.Node, report reporter) {
+
+	v, ok := new(big.Rat).SetString(n.Text)
+	if !ok {
+		return
+	}
+
+	if s.minimum != nil && v.Cmp(s.minimum.value) < 0 {
+		report(n.Start, `minimum`, "%v is less than the minimum %v", n.Text, s.minimum.text)
+	}
+
+	if s.maximum != nil && v.Cmp(s.maximum.value) > 0 {
+		report(n.Start, `maximum`, "%v is greater than the maximum %v", n.Text, s.maximum.text)
+	}
+
+	if s.exclusiveMinimum != nil && v.Cmp(s.exclusiveMinimum.value) <= 0 {
+		report(n.Start, `exclusiveMinimum`, "%v is not greater than %v", n.Text, s.exclusiveMinimum.text)
+	}
+
+	if s.exclusiveMaximum != nil && v.Cmp(s.exclusiveMaximum.value) >= 0 {
+		report(n.Start, `exclusiveMaximum`, "%v is not less than %v", n.Text, s.exclusiveMaximum.text)
+	}
+
+	if s.multipleOf != nil && !new(big.Rat).Quo(v, s.multipleOf.value).IsInt() {
+		report(n.Start, `multipleOf`, "%v is not a multiple of %v", n.Text, s.multipleOf.text)
+	}
+}
+
+func (s *schema) validateString(n *jsonc.Node, report reporter) {
+
+	var str string
+	if n.Decode(&str) != nil {
+		return
+	}
+	length := utf8.RuneCountInString(str)
+
+	if s.minLength != nil && length < *s.minLength {
+		report(n.Start, `minLength`, "string is shorter than %v characters", *s.minLength)
+	}
+
+	if s.maxLength != nil && length > *s.maxLength {
+		report(n.Start, `maxLength`, "string is longer than %v characters", *s.maxLength)
+	}
+
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		report(n.Start, `pattern`, "%q does not match the pattern %q", str, s.pattern.String())
+	}
+}
+
+func (s *schema) validateArray(n *jsonc.Node, path string, out *[]Violation, active visits, report reporter) {
+
+	elements := n.Elements()
+
+	if s.minItems != nil && len(elements) < *s.minItems {
+		report(n.Start, `minItems`, "array has fewer than %v items", *s.minItems)
+	}
+
+	if s.maxItems != nil && len(elements) > *s.maxItems {
+		report(n.Start, `maxItems`, "array has more than %v items", *s.maxItems)
+	}
+
+	for idx, e := range elements {
+
+		sub := s.items
+		if idx < len(s.prefixItems) {
+			sub = s.prefixItems[idx]
+		}
+
+		if sub != nil {
+			sub.validate(e, fmt.Sprintf("%v/%v", path, idx), out, active)
+		}
+	}
+
+	if !s.uniqueItems {
+		return
+	}
+
+	var values []interface{}
+	for idx, e := range elements {
+
+		value, err := e.Interface()
+		if err != nil {
+			continue
+		}
+
+		if oneOfValues(value, values) {
+			*out = append(*out, Violation{
+				Pos:     e.Start,
+				Path:    fmt.Sprintf("%v/%v", path, idx),
+				Keyword: `uniqueItems`,
+				Message: "array items must be unique",
+			})
+		}
+		values = append(values, value)
+	}
+}
+
+func (s *schema) validateObject(n *jsonc.Node, path string, out *[]Violation, active visits, report reporter) {
+
+	// of members with the same name the last one counts
+	members := n.Members()
+	last := map[string]*jsonc.Node{}
+	for _, m := range members {
+		last[m.Name()] = m
+	}
+
+	if s.minProps != nil && len(last) < *s.minProps {
+		report(n.Start, `minProperties`, "object has fewer than %v members", *s.minProps)
+	}
+
+	if s.maxProps != nil && len(last) > *s.maxProps {
+		report(n.Start, `maxProperties`, "object has more than %v members", *s.maxProps)
+	}
+
+	for _, name := range s.required {
+		if last[name] == nil {
+			report(n.Start, `required`, "missing required member %q", name)
+		}
+	}
+
+	for _, m := range members {
+
+		name, value := m.Name(), m.Value()
+		if value == nil || last[name] != m {
+			continue
+		}
+		memberPath := path + `/` + jsonc.EscapePointer(name)
+
+		known := false
+		if sub, ok := s.properties[name]; ok {
+			known = true
+			sub.validate(value, memberPath, out, active)
+		}
+
+		for _, p := range s.patternProperties {
+			if p.pattern.MatchString(name) {
+				known = true
+				p.schema.validate(value, memberPath, out, active)
+			}
+		}
+
+		if known || s.additionalProperties == nil {
+			continue
+		}
+
+		if s.additionalProperties.never {
+			*out = append(*out, Violation{
+				Pos:     m.Key().Start,
+				Path:    memberPath,
+				Keyword: `additionalProperties`,
+				Message: fmt.Sprintf("member %q is not allowed", name),
+			})
+			continue
+		}
+		s.additionalProperties.validate(value, memberPath, out, active)
+	}
+}
+
+// matches returns the number of schemas n is valid against.
+func matches(schemas []*schema, n *jsonc.Node, path string, active visits) int {
+
+	var c int
+	for _, s := range schemas {
+
+		var out []Violation
+		s.validate(n, path, &out, active)
+		if len(out) == 0 {
+			c++
+		}
+	}
+	return c
+}
+
+// typeName returns the JSON Schema type of n.
+func typeName(n *jsonc.Node) string {
+
+	switch n.Kind {
+	case jsonc.ObjectNode:
+		return `object`
+	case jsonc.ArrayNode:
+		return `array`
+	case jsonc.StringNode:
+		return `string`
+	case jsonc.NumberNode:
+		if isInteger(n) {
+			return `integer`
+		}
+		return `number`
+	case jsonc.BoolNode:
+		return `boolean`
+	case jsonc.NullNode:
+		return `null`
+	}
+	return n.Kind.String()
+}
+
+func hasType(types []string, n *jsonc.Node) bool {
+
+	name := typeName(n)
+	for _, t := range types {
+		if t == name || (t == `number` && name == `integer`) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInteger reports whether the number n has no fraction, as 1.0.
+func isInteger(n *jsonc.Node) bool {
+	v, ok := new(big.Rat).SetString(n.Text)
+	return ok && v.IsInt()
+}
+
+func oneOfValues(v interface{}, values []interface{}) bool {
+
+	for _, e := range values {
+		if equal(v, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares two decoded json values, numbers by their value.
+func equal(a, b interface{}) bool {
+
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		x, okx := new(big.Rat).SetString(a.String())
+		y, oky := new(big.Rat).SetString(b.String())
+		return okx && oky && x.Cmp(y) == 0
+
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// text returns a decoded json value as json.
+func text(v interface{}) string {
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
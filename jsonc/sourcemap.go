@@ -0,0 +1,172 @@
+package jsonc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// sourceMap maps offsets of the json written by a Filter to the begin and end
+// locations of the tokens in its jsonc input.
+type sourceMap struct {
+	out []int
+	in  []Pos
+}
+
+func (m *sourceMap) add(out int, in Pos) {
+
+	// tokens without output, like comments, are replaced by the next token
+	if n := len(m.out); n > 0 && m.out[n-1] == out {
+		m.in[n-1] = in
+		return
+	}
+
+	m.out = append(m.out, out)
+	m.in = append(m.in, in)
+}
+
+// trim drops the entries before the output offset.
+func (m *sourceMap) trim(out int) {
+
+	idx := sort.Search(len(m.out), func(i int) bool {
+		return m.out[i] > out
+	})
+
+	if idx <= 1 {
+		return
+	}
+
+	n := copy(m.out, m.out[idx-1:])
+	copy(m.in, m.in[idx-1:])
+	m.out = m.out[:n]
+	m.in = m.in[:n]
+}
+
+// lookup returns the location of the last token boundary at or before the
+// output offset.
+func (m *sourceMap) lookup(out int) (Pos, bool) {
+
+	idx := sort.Search(len(m.out), func(i int) bool {
+		return m.out[i] > out
+	})
+
+	if idx == 0 {
+		return Pos{}, false
+	}
+	return m.in[idx-1], true
+}
+
+// DecodeError is an encoding/json error located in the jsonc source.
+type DecodeError struct {
+	Pos   Pos
+	Value string       // description of the json value, as in json.UnmarshalTypeError
+	Type  reflect.Type // type of the Go value it could not be assigned to
+	Field string       // full path of the field
+	Err   error        // the error returned by encoding/json
+}
+
+func (e *DecodeError) Error() string {
+
+	if e.Type == nil {
+		return fmt.Sprintf("line: %v col: %v %v", e.Pos.Line, e.Pos.Column, e.Err)
+	}
+
+	target := `Go value`
+	if e.Field != `` {
+		target = `field ` + e.Field
+	}
+
+	return fmt.Sprintf("line: %v col: %v cannot unmarshal %v into %v of type %v",
+		e.Pos.Line, e.Pos.Column, e.Value, target, e.Type)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// translate locates the json errors of decoding data, which starts at the
+// output offset base, in the jsonc source. Other errors are returned as is.
+func (m *sourceMap) translate(err error, data []byte, base int) error {
+
+	var terr *json.UnmarshalTypeError
+	if errors.As(err, &terr) {
+
+		pos, ok := m.lookup(base + valueStart(data, int(terr.Offset)))
+		if !ok {
+			return err
+		}
+
+		return &DecodeError{
+			Pos:   pos,
+			Value: terr.Value,
+			Type:  terr.Type,
+			Field: terr.Field,
+			Err:   err,
+		}
+	}
+
+	var serr *json.SyntaxError
+	if errors.As(err, &serr) {
+
+		off := int(serr.Offset) - 1
+		if off < 0 {
+			off = 0
+		}
+
+		pos, ok := m.lookup(base + off)
+		if !ok {
+			return err
+		}
+		return &DecodeError{Pos: pos, Err: err}
+	}
+
+	return err
+}
+
+// valueStart returns the start of the json value at offset off as reported by
+// json.UnmarshalTypeError. The offset points behind the opening brace of
+// objects and arrays and behind all other values.
+func valueStart(data []byte, off int) int {
+
+	if off > len(data) {
+		off = len(data)
+	}
+
+	if off <= 0 {
+		return 0
+	}
+
+	switch data[off-1] {
+	case '{', '[':
+		return off - 1
+
+	case '"':
+		for i := off - 2; i >= 0; i-- {
+			if data[i] != '"' {
+				continue
+			}
+
+			var escapes int
+			for j := i - 1; j >= 0 && data[j] == '\\'; j-- {
+				escapes++
+			}
+
+			if escapes%2 == 0 {
+				return i
+			}
+		}
+		return 0
+	}
+
+	i := off - 1
+	for i > 0 {
+		switch data[i-1] {
+		case ',', ':', '[', '{':
+			return i
+		}
+		i--
+	}
+	return i
+}
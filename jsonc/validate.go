@@ -0,0 +1,169 @@
+package jsonc
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Validate reads a jsonc document from r and returns all syntax errors found
+// in it. Instead of stopping at the first error the Filter skips to the next
+// ',', '}', ']' or line break on the current nesting level and continues. The
+// returned error is only set if reading from r failed.
+func Validate(r io.Reader, opts ...Option) ([]Error, error) {
+
+	f, err := newConfig(opts).filter(runeReader(r).ReadRune, false, ``)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	f.recover = true
+
+	_, err = io.Copy(ioutil.Discard, f)
+	if err != nil {
+		return f.Errors(), err
+	}
+
+	if !f.Done() {
+		f.errs = append(f.errs, f.unexpectedEOF())
+	}
+	return f.Errors(), nil
+}
+
+// Errors returns the syntax errors collected by a validating Filter.
+func (f *Filter) Errors() []Error {
+	return f.errs
+}
+
+func (f *Filter) unexpectedEOF() Error {
+	return newError(f.ring.EndPos(), f.ring.Position()+1, ``, "unexpected end of input")
+}
+
+// resync records err and skips the input up to the next ',', '}', ']' or line
+// break on the current nesting level, the enclosing object or array then
+// continues as if a value was read.
+func (f *Filter) resync(err Error) error {
+
+	// the enclosing state failed on the rune of the last resync, which is
+	// part of the error reported there
+	again := f.ring.Position() == f.lastResync
+	if !again {
+		f.errs = append(f.errs, err)
+	}
+
+	// an error inside a multiline string continues to its closing quote
+	var quote, broken rune
+	switch s := f.peekState().(type) {
+	case *ValueMultilineState:
+		quote = '`'
+	case *ValueState:
+		broken = '"'
+		if s.json5 != nil {
+			broken = s.json5.quote
+		}
+	}
+
+	// drop the state of the broken token
+	for {
+		t := f.peekState().Type()
+		if t == Object || t == Array || t == Root {
+			break
+		}
+		f.popState()
+	}
+
+	switch {
+	case broken != 0 && f.ring.Peek() == '\n':
+		err := f.closeString(broken)
+		if err != nil {
+			return err
+		}
+
+	case quote == 0 && again:
+		err := f.ring.Advance()
+		if err != nil {
+			return err
+		}
+	}
+
+	var depth int
+	for {
+
+		ru := f.ring.Peek()
+
+		switch {
+		case quote != 0:
+			if ru == quote || (ru == '\n' && quote == '"') {
+				quote = 0
+			}
+
+		case ru == '"' || ru == '`':
+			quote = ru
+
+		case ru == '{' || ru == '[':
+			depth++
+
+		case depth > 0 && (ru == '}' || ru == ']'):
+			depth--
+
+		case depth == 0 && (ru == ',' || ru == '}' || ru == ']' || ru == '\n'):
+
+			f.lastResync = f.ring.Position()
+			switch s := f.peekState().(type) {
+			case *ObjectState:
+				s.internalState = ObjInternalValue
+			case *ArrayState:
+				s.internalState = ArrayIntAfterValue
+			}
+			return nil
+		}
+
+		err := f.ring.Advance()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// closeString skips the rest of a string broken by a line break if the next
+// line holds its closing quote, the string was broken in two then. Otherwise
+// the input stays at the line break. The lookahead is limited by the runes
+// the ring keeps to step back.
+func (f *Filter) closeString(quote rune) error {
+
+	var n int
+	var escaped bool
+	for n < f.ring.minSize {
+
+		err := f.ring.Advance()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+		n++
+
+		ru := f.ring.Peek()
+		if ru == '\n' {
+			break
+		}
+
+		if !escaped && ru == quote {
+			return f.ring.Advance()
+		}
+		escaped = !escaped && ru == '\\'
+	}
+
+	for ; n > 0; n-- {
+		err := f.ring.Pop()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package jsonc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		jsonc  string
+		errors []string
+	}{
+		{jsonc: ``},
+		{jsonc: `{x:x, y:[1,2,3]} // fine`},
+		{
+			jsonc: "{\n a: 1x\n b: \"x\n c: -\n d: [1,,2]\n e: {a b}\n f: `\\`\n g: ok\n}",
+			errors: []string{
+				`line: 2 col: 5 invalid identifier`,
+				`line: 3 col: 7 line break in string value`,
+				`line: 4 col: 5 invalid identifier`,
+				`line: 5 col: 8 empty no quote state`,
+				`line: 6 col: 8 error parsing object rune: b`,
+				`line: 7 col: 6 character \ found in multiline string`,
+			},
+		},
+		{
+			jsonc: `[1,,2,,3]`,
+			errors: []string{
+				`line: 1 col: 4 empty no quote state`,
+				`line: 1 col: 7 empty no quote state`,
+			},
+		},
+		{
+			jsonc: "{} x\ny",
+			errors: []string{
+				`line: 1 col: 4 invalid first character: x`,
+				`line: 2 col: 1 invalid first character: y`,
+			},
+		},
+		{
+			jsonc: `{a:1}}`,
+			errors: []string{
+				`line: 1 col: 6 invalid first character: }`,
+			},
+		},
+		{
+			jsonc: "{} x, y",
+			errors: []string{
+				`line: 1 col: 4 invalid first character: x`,
+			},
+		},
+		{
+			jsonc: "{\n a: \"x y\n z\": 1\n b: 1x\n}",
+			errors: []string{
+				`line: 2 col: 9 line break in string value`,
+				`line: 4 col: 5 invalid identifier`,
+			},
+		},
+		{
+			jsonc: "[\"a\n b, 1x]",
+			errors: []string{
+				`line: 1 col: 4 line break in string value`,
+				`line: 2 col: 5 invalid identifier`,
+			},
+		},
+		{
+			jsonc: `{a:[1,,2]`,
+			errors: []string{
+				`line: 1 col: 7 empty no quote state`,
+				`line: 1 col: 10 unexpected end of input`,
+			},
+		},
+	}
+
+	for _, ts := range tests {
+
+		errs, err := Validate(strings.NewReader(ts.jsonc))
+		require.NoError(t, err)
+
+		var msgs []string
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		assert.Equal(t, ts.errors, msgs, ts.jsonc)
+	}
+
+	// the closing quote is searched within the runes the ring keeps
+	errs, err := Validate(strings.NewReader("[\"a\n bbbbbbbbbbbb, 1x, \"c\"]"), RingSize(16, 4))
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	assert.Equal(t, `line: 2 col: 16 invalid identifier`, errs[1].Error())
+}
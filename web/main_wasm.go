@@ -41,30 +41,20 @@ func load() {
 	style := errMsg.Get(`style`)
 	style.Set(`display`, `none`)
 
-	jsonc, errpos, err := process(false)
+	jsonc, jerr, err := process(false)
 	if err != nil {
 
-		var edit string
-		val := Document.Call("getElementById", JsoncArea).Get(`value`)
-		if val.Truthy() {
-			edit = val.String()
-		}
-
-		// get the line of the error
-		errline := strings.Count(edit[:errpos], "\n")
-		fmt.Printf("errline %v\n", errline)
-		idx := strings.Index(edit[errpos:], "\n")
 		style.Set(`display`, `block`)
 
-		line := strconv.Itoa(idx + 1)
-		pos := strconv.Itoa(errpos)
-
-		if idx == -1 {
-			errMsg.Set(`innerHTML`, `error line: 1 pos: `+pos)
-		} else {
-			errMsg.Set(`innerHTML`, `error line: `+line+` pos: `+pos)
+		if jerr.Line() == 0 {
+			errMsg.Set(`innerHTML`, `error: `+err.Error())
+			return
 		}
 
+		line := strconv.Itoa(jerr.Line())
+		col := strconv.Itoa(jerr.Column())
+		errMsg.Set(`innerHTML`, `error line: `+line+` col: `+col)
+
 		return
 	}
 
@@ -87,7 +77,7 @@ func load() {
 	Document.Call("getElementById", JsonArea).Set(`innerHTML`, json)
 }
 
-func process(minimize bool) (json string, errpos int, err error) {
+func process(minimize bool) (json string, jerr jsonc.Error, err error) {
 
 	var edit string
 	val := Document.Call("getElementById", JsoncArea).Get(`value`)
@@ -110,10 +100,7 @@ func process(minimize bool) (json string, errpos int, err error) {
 	if jcr.Err() != nil && !errors.Is(jcr.Err(), io.EOF) {
 		err = jcr.Err()
 
-		var rerr jsonc.Error
-		if ok := errors.As(err, &rerr); ok {
-			errpos = rerr.Position()
-		}
+		errors.As(err, &jerr)
 
 		print("error 2 " + err.Error())
 		return